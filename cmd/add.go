@@ -18,6 +18,10 @@ var addCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if dryRun {
+			fmt.Printf("Would write key pair: %s %s\n", args[0], args[1])
+			return nil
+		}
 		clusterName := config.ClusterName(args[0])
 		keys[clusterName] = config.FilePath(args[1])
 		err = file.SaveKeys(keys)
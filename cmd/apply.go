@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/oberd/ecsy/config"
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+var applyFiles []string
+
+// applyCmd reconciles services with spec files
+var applyCmd = &cobra.Command{
+	Use:   "apply -f [spec.yaml]...",
+	Short: "Make services match declarative spec files",
+	Long: `Compare a service with a YAML (or JSON) spec file, and make the changes needed
+for it to match: register one new task definition revision if the image,
+environment, secrets or sizing differ, update the service, and create, update or
+delete its scheduled and post-deployment tasks.
+
+Settings left out of the spec are not managed. env, secrets, schedules and
+post_deployment are complete when given: whatever is not listed is removed.
+
+    cluster: prod
+    service: api
+    image: example/api:1.4.2
+    memory: 512
+    desired_count: 3
+    env:
+      APP_ENV: production
+    secrets:
+      DATABASE_URL: /prod/api/DATABASE_URL
+    schedules:
+      - name: nightly-report
+        schedule: cron(0 3 * * ? *)
+        command: bin/report --nightly
+    post_deployment:
+      - service: api-migrations
+        command: bin/migrate
+
+Example:
+    ecsy apply -f services/api.yaml --wait
+    ecsy apply -f 'services/*.yaml' --dry-run
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		specs := loadSpecs(append(applyFiles, args...))
+		// compare every service before changing any, so a spec that cannot
+		// be compared does not leave the run half applied
+		diffs := make([]*ecs.SpecDiff, 0, len(specs))
+		for _, spec := range specs {
+			useCluster(spec.Cluster)
+			diff, err := ecs.DiffServiceSpec(spec)
+			failOnError(err, fmt.Sprintf("Error comparing %s/%s with its spec", spec.Cluster, spec.Service))
+			printSpecDiff(diff)
+			diffs = append(diffs, diff)
+		}
+		for _, diff := range diffs {
+			if diff.IsEmpty() {
+				continue
+			}
+			spec := diff.Spec
+			useCluster(spec.Cluster)
+			task, err := ecs.ApplyServiceSpec(diff)
+			failOnError(err, fmt.Sprintf("Error applying the spec of %s/%s", spec.Cluster, spec.Service))
+			if *task.TaskDefinitionArn == *diff.Service.TaskDefinition {
+				ecs.PrintAction("=> Applied %s/%s", "=> Would apply %s/%s", spec.Cluster, spec.Service)
+				continue
+			}
+			if !dryRun {
+				err = config.RecordDeployment(config.HistoryFilePath(), spec.Cluster, spec.Service, *diff.Service.TaskDefinition, *task.TaskDefinitionArn)
+				if err != nil {
+					fmt.Printf("Unable to record deploy history: %v\n", err)
+				}
+			}
+			ecs.PrintAction("=> Applied %s/%s, deploying %s", "=> Would apply %s/%s, deploying %s", spec.Cluster, spec.Service, path.Base(*task.TaskDefinitionArn))
+			failOnError(waitForDeployment(spec.Cluster, spec.Service, task), "Deployment failed")
+		}
+	},
+}
+
+// loadSpecs reads the spec files given on the command line, expanding glob
+// patterns the shell left quoted. Every file is read before anything is
+// done, so a broken spec does not leave a run half applied.
+func loadSpecs(patterns []string) []*ecs.ServiceSpec {
+	if len(patterns) == 0 {
+		failOnError(fmt.Errorf("please give spec files with -f"), "bad arguments")
+	}
+	specs := make([]*ecs.ServiceSpec, 0, len(patterns))
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(pattern)
+		failOnError(err, "Invalid file pattern")
+		if len(files) == 0 {
+			files = []string{pattern}
+		}
+		for _, file := range files {
+			spec, err := ecs.LoadServiceSpec(file)
+			failOnError(err, "Error reading spec")
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+func printSpecDiff(diff *ecs.SpecDiff) {
+	spec := diff.Spec
+	fmt.Printf("==> %s/%s\n", spec.Cluster, spec.Service)
+	if diff.IsEmpty() {
+		fmt.Println("    Up to date")
+		return
+	}
+	if diff.RegistersTaskDefinition() {
+		fmt.Printf("    task definition %s => new revision\n", path.Base(*diff.Current.TaskDefinitionArn))
+		printTaskDefinitionDiff("        ", diff.TaskDefinition)
+	}
+	printFieldChanges("    ", diff.ServiceChanges)
+	for _, rule := range diff.Rules {
+		title := fmt.Sprintf("rule %s (%s)", rule.Name, rule.Kind)
+		switch {
+		case rule.Create:
+			fmt.Println(colorize(colorGreen, "    + "+title))
+		case rule.Delete:
+			fmt.Println(colorize(colorRed, "    - "+title))
+		default:
+			fmt.Println(colorize(colorYellow, "    ~ "+title))
+		}
+		printFieldChanges("        ", rule.Changes)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringArrayVarP(&applyFiles, "file", "f", nil, "Spec file to apply, may be repeated or a glob pattern")
+	addWaitFlags(applyCmd)
+}
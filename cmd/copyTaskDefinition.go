@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/oberd/ecsy/ecs"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -23,7 +21,8 @@ example:
 		if newFamilyName == "" {
 			return errors.New("Please specify a family name for the new definition")
 		}
-		def, err := ecs.LocateTaskDef(args[0], args[1], taskDefinitionSource)
+		cluster, service := ServiceChooser(args)
+		def, err := ecs.LocateTaskDef(cluster, service, taskDefinitionSource)
 		if err != nil {
 			return err
 		}
@@ -37,7 +36,7 @@ example:
 		if err != nil {
 			return err
 		}
-		fmt.Println("configured new task definition: ", newTaskDef)
+		ecs.PrintAction("configured new task definition: %v", "would configure new task definition: %v", newTaskDef)
 		return nil
 	},
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/oberd/ecsy/ecs"
@@ -10,7 +11,8 @@ import (
 var createPostDeploymentTask = &cobra.Command{
 	Use:   "create-post-deployment-task",
 	Short: "Creates an events rule that runs an ecs task with [command] after a service reaches steady state",
-	Long: `Easily create a post-deployment task event
+	Long: `Easily create a post-deployment task event. Both clusters must use the same
+AWS account and region (the same profile, region and role in the config file).
 Example:
     ecsy create-post-deployment-task \
         --source-cluster=mountain-qa \
@@ -31,6 +33,16 @@ Example:
 		if len(args) == 0 {
 			return cmd.Usage()
 		}
+		input.SourceCluster, input.SourceService = ServiceChooser([]string{input.SourceCluster, input.SourceService})
+		input.TargetCluster, input.TargetService = ServiceChooser([]string{input.TargetCluster, input.TargetService})
+		// ECS publishes the steady state event in the source service's
+		// account and region only, and a rule there cannot run a task
+		// elsewhere without an event bus in between
+		if clusterSessionOptions(input.SourceCluster) != clusterSessionOptions(input.TargetCluster) {
+			return fmt.Errorf("%s and %s use different AWS settings, post-deployment tasks need both clusters in the same account and region", input.SourceCluster, input.TargetCluster)
+		}
+		// the event pattern and the rule belong with the source service
+		useCluster(input.SourceCluster)
 		input.Command = strings.Join(args[0:], " ")
 		return ecs.CreatePostDeploymentTask(input)
 	},
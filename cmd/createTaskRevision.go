@@ -15,7 +15,8 @@ var createTaskRevisionCmd = &cobra.Command{
 	Short: "duplicate a task definition into a new revision with a different image",
 	Long:  ``,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		def, err := ecs.LocateTaskDef(args[0], args[1], taskDefinitionSource)
+		cluster, service := ServiceChooser(args)
+		def, err := ecs.LocateTaskDef(cluster, service, taskDefinitionSource)
 		if err != nil {
 			return err
 		}
@@ -22,15 +22,18 @@ var deployNewServiceImageCmd = &cobra.Command{
 		newTask, err := ecs.FindNewestDefinition(*def.Family)
 		if err != nil || ecs.EssentialImage(newTask) != args[2] {
 			newTask, err = ecs.CreateNewTaskWithImage(def, args[2])
-			fmt.Printf("created task definition with image %s\n", args[2])
+			ecs.PrintAction("created task definition with image %s", "would create task definition with image %s", args[2])
 			failOnError(err, "create new task def")
 		}
-		svc, err := ecs.DeployTaskToService(cluster, service, newTask)
+		svc, err := deployTaskDefinition(cluster, service, newTask)
 		failOnError(err, "updating service task")
-		fmt.Printf("updated service %s with task definition %s (deploying to %d containers)", *svc.ServiceArn, *newTask.TaskDefinitionArn, *svc.DesiredCount)
+		printServiceUpdated(svc, newTask)
+		failOnError(waitForDeployment(cluster, service, newTask), "Deployment failed")
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(deployNewServiceImageCmd)
+	addWaitFlags(deployNewServiceImageCmd)
+	deployNewServiceImageCmd.Flags().BoolVar(&rollbackOnFailure, "rollback-on-failure", false, "Roll back to the previous task definition if the deployment fails (implies --wait)")
 }
@@ -22,7 +22,7 @@ var deployNewestTaskCmd = &cobra.Command{
 		if newestTask.TaskDefinitionArn == currentTask.TaskDefinitionArn {
 			return
 		}
-		_, err = ecs.DeployTaskToService(cluster, service, newestTask)
+		_, err = deployTaskDefinition(cluster, service, newestTask)
 		failOnError(err, "deploying to service")
 	},
 }
@@ -1,8 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/aws/aws-sdk-go/aws"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/oberd/ecsy/ecs"
 	"github.com/spf13/cobra"
 )
@@ -13,16 +21,35 @@ var describeCmd = &cobra.Command{
 	Short: "Show current task configuration for service",
 	Long:  `Show current task configuration for service`,
 	Run: func(cmd *cobra.Command, args []string) {
-		def, err := ecs.GetCurrentTaskDefinition(args[0], args[1])
+		cluster, serviceName := ServiceChooser(args)
+		def, err := ecs.GetCurrentTaskDefinition(cluster, serviceName)
 		failOnError(err, "Error finding service")
-		fmt.Printf("%v\n", def)
-		service, err := ecs.FindService(args[0], args[1])
+		service, err := ecs.FindService(cluster, serviceName)
 		failOnError(err, "Error finding service")
+		if structuredOutput() {
+			taskURLs, err := ecs.PrintTaskURLs(cluster, service)
+			failOnError(err, "error getting task urls")
+			// the task definition keeps the shape AWS returns it in
+			body, err := ecs.MarshalShape(def)
+			failOnError(err, "")
+			failOnError(printOutput(describeOutput{
+				Cluster:        cluster,
+				Service:        serviceName,
+				ServiceArn:     aws.StringValue(service.ServiceArn),
+				Status:         aws.StringValue(service.Status),
+				DesiredCount:   aws.Int64Value(service.DesiredCount),
+				RunningCount:   aws.Int64Value(service.RunningCount),
+				TaskDefinition: json.RawMessage(body),
+				TaskURLs:       taskURLs,
+			}), "")
+			return
+		}
+		printTaskDefinitionTable(def)
 		fmt.Println("")
 		fmt.Println("AWS Console URLs")
 		fmt.Println("================")
 		fmt.Println("")
-		taskURLs, err := ecs.PrintTaskURLs(args[0], service)
+		taskURLs, err := ecs.PrintTaskURLs(cluster, service)
 		failOnError(err, "error getting task urls")
 		for _, uri := range taskURLs {
 			fmt.Println(uri)
@@ -37,6 +64,88 @@ var describeCmd = &cobra.Command{
 	},
 }
 
+// printTaskDefinitionTable prints a task definition's sizing and a row per
+// container with its image, sizing, ports and the names of its variables
+func printTaskDefinitionTable(def *awsecs.TaskDefinition) {
+	fmt.Printf("Task definition %s (task cpu %s, memory %s)\n\n", path.Base(aws.StringValue(def.TaskDefinitionArn)), orDash(aws.StringValue(def.Cpu)), orDash(aws.StringValue(def.Memory)))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tIMAGE\tCPU\tMEMORY\tPORTS\tENV")
+	for _, container := range def.ContainerDefinitions {
+		cpu := "-"
+		if container.Cpu != nil {
+			cpu = fmt.Sprintf("%d", *container.Cpu)
+		}
+		memory := "-"
+		if container.Memory != nil {
+			memory = fmt.Sprintf("%d", *container.Memory)
+		} else if container.MemoryReservation != nil {
+			memory = fmt.Sprintf("%d (soft)", *container.MemoryReservation)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			aws.StringValue(container.Name),
+			aws.StringValue(container.Image),
+			cpu,
+			memory,
+			orDash(containerPorts(container)),
+			orDash(containerEnvNames(container)),
+		)
+	}
+	w.Flush()
+}
+
+// containerPorts lists port mappings as host->container/protocol, or
+// container/protocol when the host port is chosen by ECS
+func containerPorts(container *awsecs.ContainerDefinition) string {
+	ports := make([]string, 0, len(container.PortMappings))
+	for _, port := range container.PortMappings {
+		protocol := aws.StringValue(port.Protocol)
+		if protocol == "" {
+			protocol = awsecs.TransportProtocolTcp
+		}
+		mapping := fmt.Sprintf("%d/%s", aws.Int64Value(port.ContainerPort), protocol)
+		if hostPort := aws.Int64Value(port.HostPort); hostPort != 0 && hostPort != aws.Int64Value(port.ContainerPort) {
+			mapping = fmt.Sprintf("%d->%s", hostPort, mapping)
+		}
+		ports = append(ports, mapping)
+	}
+	return strings.Join(ports, ", ")
+}
+
+// containerEnvNames lists the names of a container's variables, marking
+// secrets with *
+func containerEnvNames(container *awsecs.ContainerDefinition) string {
+	names := make([]string, 0)
+	for _, v := range ecs.ContainerEnv(container) {
+		name := v.Name
+		if v.IsSecret() {
+			name += "*"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// describeOutput is the --output json|yaml schema of describe
+type describeOutput struct {
+	Cluster        string          `json:"cluster"`
+	Service        string          `json:"service"`
+	ServiceArn     string          `json:"service_arn"`
+	Status         string          `json:"status"`
+	DesiredCount   int64           `json:"desired_count"`
+	RunningCount   int64           `json:"running_count"`
+	TaskDefinition json.RawMessage `json:"task_definition"`
+	TaskURLs       []string        `json:"task_urls"`
+}
+
 func init() {
 	RootCmd.AddCommand(describeCmd)
+	readOnly(describeCmd)
 }
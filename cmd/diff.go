@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd compares two task definitions of a service
+var diffCmd = &cobra.Command{
+	Use:   "diff [cluster] [service] [from] [to]",
+	Short: "Show what changed between two task definitions of a service",
+	Long: `Compare the container definitions (image, environment, secrets, ports, memory, command)
+of two task definitions related to a service.
+
+[from] and [to] can be "current" (the deployed task definition), "newest" (the
+newest in the service's family), a revision number of the service's family, or
+a full family:revision / arn. They default to "current" and "newest".
+
+Example:
+    ecsy diff my-cluster my-service 41 current
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, service := ServiceChooser(args)
+		fromRef, toRef := "current", "newest"
+		if len(args) > 2 {
+			fromRef = args[2]
+		}
+		if len(args) > 3 {
+			toRef = args[3]
+		}
+		from, err := ecs.ResolveTaskDefinition(cluster, service, fromRef)
+		failOnError(err, fmt.Sprintf("Error finding task definition %s", fromRef))
+		to, err := ecs.ResolveTaskDefinition(cluster, service, toRef)
+		failOnError(err, fmt.Sprintf("Error finding task definition %s", toRef))
+		fmt.Printf("--- %s\n+++ %s\n", path.Base(*from.TaskDefinitionArn), path.Base(*to.TaskDefinitionArn))
+		diff := ecs.DiffTaskDefinitions(from, to)
+		if diff.IsEmpty() {
+			fmt.Println("No differences")
+			return
+		}
+		printTaskDefinitionDiff("", diff)
+	},
+}
+
+func printTaskDefinitionDiff(indent string, diff *ecs.TaskDefinitionDiff) {
+	printFieldChanges(indent, diff.Task)
+	for _, container := range diff.Containers {
+		switch {
+		case container.Added:
+			fmt.Println(colorize(colorGreen, fmt.Sprintf("%s+ container %s", indent, container.Name)))
+		case container.Removed:
+			fmt.Println(colorize(colorRed, fmt.Sprintf("%s- container %s", indent, container.Name)))
+		default:
+			fmt.Printf("%s  container %s\n", indent, container.Name)
+		}
+		printFieldChanges(indent+"    ", container.Changes)
+	}
+}
+
+func printFieldChanges(indent string, changes []ecs.FieldChange) {
+	for _, change := range changes {
+		switch {
+		case change.Added:
+			fmt.Println(colorize(colorGreen, fmt.Sprintf("%s+ %s: %s", indent, change.Field, change.To)))
+		case change.Removed:
+			fmt.Println(colorize(colorRed, fmt.Sprintf("%s- %s: %s", indent, change.Field, change.From)))
+		default:
+			fmt.Println(colorize(colorYellow, fmt.Sprintf("%s~ %s: %s => %s", indent, change.Field, change.From, change.To)))
+		}
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(diffCmd)
+	readOnly(diffCmd)
+}
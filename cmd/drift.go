@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+// driftExitCode is the exit status when a service differs from its spec,
+// errors exit with 1
+const driftExitCode = 2
+
+var driftFiles []string
+var driftShowValues bool
+
+type driftOutput struct {
+	Cluster string             `json:"cluster"`
+	Service string             `json:"service"`
+	Drifted bool               `json:"drifted"`
+	Fields  []driftFieldOutput `json:"fields"`
+}
+
+type driftFieldOutput struct {
+	Field    string `json:"field"`
+	Live     string `json:"live"`
+	Declared string `json:"declared"`
+}
+
+// driftCmd compares services with their spec files
+var driftCmd = &cobra.Command{
+	Use:   "drift -f [spec.yaml]...",
+	Short: "Report where services differ from their spec files",
+	Long: `Compare the live task definition, service and scheduled or post-deployment
+rules of each service with its spec file (see apply), and list the fields that
+differ: image, environment, secrets, memory and cpu, desired count, schedule
+expressions... Nothing is changed.
+
+Plain environment values are masked unless --show-values is given. Exits with
+status 2 when any service drifted, 1 on errors.
+
+Example:
+    ecsy drift -f 'services/*.yaml'
+    ecsy drift -f services/api.yaml --output json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		specs := loadSpecs(append(driftFiles, args...))
+		reports := make([]driftOutput, 0, len(specs))
+		drifted := false
+		for _, spec := range specs {
+			useCluster(spec.Cluster)
+			diff, err := ecs.DiffServiceSpec(spec)
+			failOnError(err, fmt.Sprintf("Error comparing %s/%s with its spec", spec.Cluster, spec.Service))
+			changes := diff.Drift()
+			if !driftShowValues {
+				changes = maskEnvChanges(changes)
+			}
+			report := driftOutput{Cluster: spec.Cluster, Service: spec.Service, Drifted: len(changes) > 0, Fields: make([]driftFieldOutput, len(changes))}
+			for i, change := range changes {
+				report.Fields[i] = driftFieldOutput{Field: change.Field, Live: change.From, Declared: change.To}
+			}
+			reports = append(reports, report)
+			drifted = drifted || report.Drifted
+			if structuredOutput() {
+				continue
+			}
+			if !report.Drifted {
+				fmt.Printf("==> %s/%s matches its spec\n", spec.Cluster, spec.Service)
+				continue
+			}
+			fmt.Println(colorize(colorYellow, fmt.Sprintf("==> %s/%s drifted (live => declared)", spec.Cluster, spec.Service)))
+			printFieldChanges("    ", changes)
+		}
+		if structuredOutput() {
+			failOnError(printOutput(reports), "Error printing drift")
+		}
+		if drifted {
+			os.Exit(driftExitCode)
+		}
+	},
+}
+
+// maskEnvChanges hides plain environment values, keeping whether they were
+// added, removed or changed
+func maskEnvChanges(changes []ecs.FieldChange) []ecs.FieldChange {
+	out := make([]ecs.FieldChange, len(changes))
+	for i, change := range changes {
+		if strings.HasPrefix(change.Field, "container.") && strings.Contains(change.Field, ".env.") {
+			change.From = ecs.EnvVar{Value: change.From}.MaskedValue(false)
+			change.To = ecs.EnvVar{Value: change.To}.MaskedValue(false)
+		}
+		out[i] = change
+	}
+	return out
+}
+
+func init() {
+	RootCmd.AddCommand(driftCmd)
+	readOnly(driftCmd)
+	driftCmd.Flags().StringArrayVarP(&driftFiles, "file", "f", nil, "Spec file to check, may be repeated or a glob pattern")
+	driftCmd.Flags().BoolVar(&driftShowValues, "show-values", false, "Show plain environment values instead of masking them")
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oberd/ecsy/ecs"
+)
+
+// printPlannedRequest shows a write request --dry-run held back: what it
+// changes compared to the current state, then the request itself
+func printPlannedRequest(req *ecs.PlannedRequest) {
+	action := "update"
+	if req.Create {
+		action = "create"
+	}
+	fmt.Println(colorize(colorYellow, fmt.Sprintf("==> [dry run] %s %s (%s)", req.Operation, req.Target, action)))
+	if req.TaskDefinition != nil && !req.TaskDefinition.IsEmpty() {
+		printTaskDefinitionDiff("    ", req.TaskDefinition)
+	}
+	printFieldChanges("    ", req.Changes)
+	body, err := req.RequestJSON()
+	if err != nil {
+		fmt.Printf("    unable to render request: %v\n", err)
+		return
+	}
+	fmt.Println("    request:")
+	fmt.Println("    " + strings.Replace(body, "\n", "\n    ", -1))
+}
@@ -2,14 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
+	"text/tabwriter"
 
-	"github.com/aws/aws-sdk-go/aws"
-
-	awsecs "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/oberd/ecsy/ecs"
+	"github.com/oberd/ecsy/picker"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 // envCmd represents the env command
@@ -25,38 +26,154 @@ ecsy env set or ecsy env edit`,
 var getCmd = &cobra.Command{
 	Use:   "get [cluster-name] [service-name]",
 	Short: "List environment variables for an ECS service's deployed task definition",
-	Long:  `List environment variables for an ECS service's deployed task definition`,
+	Long: `List environment variables for an ECS service's deployed task definition
+
+--format dotenv (the default) prints a .env block that env import and env edit
+read back, json prints an object and shell-export prints export statements:
+
+eval "$(ecsy env get my-cluster api --format shell-export)"
+
+The global --output json|yaml takes precedence over --format, and prints the
+container name and a list of variables, with the store of secrets.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		container, err := ecs.GetDeployedEssentialContainer(args[0], args[1])
+		cluster, service := ServiceChooser(args)
+		container, err := ecs.GetDeployedEssentialContainer(cluster, service)
 		failOnError(err, "")
-		fmt.Print(ecs.KeyPairsToString(container.Environment))
+		if structuredOutput() {
+			out := envOutput{Cluster: cluster, Service: service, Container: *container.Name, Variables: make([]envVarOutput, 0)}
+			for _, v := range ecs.ContainerEnv(container) {
+				out.Variables = append(out.Variables, envVarOutput{Name: v.Name, Value: v.Value, SecretStore: v.Store})
+			}
+			failOnError(printOutput(out), "")
+			return
+		}
+		out, err := ecs.FormatEnvVars(ecs.ContainerEnv(container), envGetFormat)
+		failOnError(err, "")
+		fmt.Print(out)
 	},
 	PreRunE: Validate2ArgumentsCount,
 }
 
+// envOutput is the --output json|yaml schema of env get
+type envOutput struct {
+	Cluster   string         `json:"cluster"`
+	Service   string         `json:"service"`
+	Container string         `json:"container"`
+	Variables []envVarOutput `json:"variables"`
+}
+
+// envVarOutput is a plain variable, or a secret whose value is its
+// reference in secret_store (ssm or secretsmanager)
+type envVarOutput struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	SecretStore string `json:"secret_store,omitempty"`
+}
+
+var envFindImage string
+var envFindRegexp bool
+var envFindConcurrency int
+var envGetFormat string
+var envImportFile string
+var envImportMode string
+var envSecretStore string
+var envSecretName string
+
 // setCmd sets a variable
 var setCmd = &cobra.Command{
-	Use:   "set [cluster-name] [service-name] [env_var_name] [env_var_value]",
+	Use:   "set [cluster-name] [service-name] [env_var_name] [env_var_value|-]",
 	Short: "Set an environment variable for an ECS service's deployed task definition",
-	Long:  `Set an environment variable for an ECS service's deployed task definition`,
+	Long: `Set an environment variable for an ECS service's deployed task definition
+
+A value of ssm:/path or secretsmanager:arn references an existing secret.
+With --secret ssm|secretsmanager, the value is stored in SSM Parameter Store
+(as a SecureString) or Secrets Manager instead, and the variable references
+it. The secret is named by --secret-name, defaulting to the secret the
+variable already references, or /cluster/service/NAME. To keep the secret out
+of shell history, give - as the value, or leave it out, to read it from stdin
+(or type it at a prompt, without echo, in a terminal).
+
+The task definition needs an execution role allowed to read the secret.
+
+Examples:
+
+ecsy env set my-cluster api DB_HOST db.internal
+ecsy env set my-cluster api DB_PASSWORD ssm:/shared/db/password
+ecsy env set my-cluster api DB_PASSWORD --secret ssm < password.txt
+`,
 	Run: func(cmd *cobra.Command, args []string) {
-		container, err := ecs.GetDeployedEssentialContainer(args[0], args[1])
+		cluster, service := ServiceChooser(args)
+		container, err := ecs.GetDeployedEssentialContainer(cluster, service)
 		failOnError(err, "")
-		varName := args[2]
-		varValue := args[3]
-		var found bool
-		for _, val := range container.Environment {
-			if *val.Name == varName {
-				val.SetValue(varValue)
-				found = true
-			}
+		vars := ecs.ContainerEnv(container)
+		name := args[2]
+		var value string
+		if len(args) > 3 {
+			value = args[3]
+		}
+		if envSecretStore != "" && (len(args) == 3 || value == "-") {
+			value, err = readSecretValue(name)
+			failOnError(err, "Problem reading secret value")
 		}
-		if !found {
-			container.Environment = append(container.Environment, &awsecs.KeyValuePair{Name: aws.String(varName), Value: aws.String(varValue)})
+		v := ecs.ParseEnvVar(name, value)
+		if envSecretStore != "" {
+			v, err = putEnvSecret(cluster, service, vars, name, value)
+			failOnError(err, "Problem storing secret")
+			ecs.PrintAction("Stored %s in %s:%s", "Would store %s in %s:%s", v.Name, v.Store, v.Value)
 		}
-		deployEnv(args[0], args[1], container.Environment)
+		deployEnv(cluster, service, ecs.SetEnvVar(vars, v))
 	},
-	PreRunE: Validate4ArgumentsCount,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if envSecretStore != "" && len(args) == 3 {
+			// the secret is read from stdin
+			return nil
+		}
+		return Validate4ArgumentsCount(cmd, args)
+	},
+}
+
+// readSecretValue reads a secret value from stdin, without the trailing
+// newline, or prompts for it without echo when stdin is a terminal
+func readSecretValue(name string) (string, error) {
+	if picker.IsTerminal(os.Stdin) {
+		fmt.Fprintf(os.Stderr, "Value of %s: ", name)
+		value, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(value), nil
+	}
+	content, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimRight(string(content), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("no value for %s on stdin", name)
+	}
+	return value, nil
+}
+
+// putEnvSecret stores a value as a secret, reusing the secret the variable
+// already references in the same store if no name was given
+func putEnvSecret(cluster, service string, vars []ecs.EnvVar, name, value string) (ecs.EnvVar, error) {
+	secretName := envSecretName
+	if secretName == "" {
+		for _, existing := range vars {
+			if existing.Name == name && existing.Store == envSecretStore {
+				secretName = existing.Value
+			}
+		}
+	}
+	if secretName == "" {
+		secretName = ecs.DefaultSecretName(envSecretStore, cluster, service, name)
+	}
+	valueFrom, err := ecs.PutSecret(envSecretStore, secretName, value)
+	if err != nil {
+		return ecs.EnvVar{}, err
+	}
+	return ecs.EnvVar{Name: name, Value: valueFrom, Store: envSecretStore}, nil
 }
 
 // editCmd allows you to edit env vars of an active service's
@@ -64,16 +181,18 @@ var setCmd = &cobra.Command{
 var editCmd = &cobra.Command{
 	Use:   "edit [cluster-name] [service-name]",
 	Short: "Interactively define environment for a task, and deploy it to the service",
-	Long:  `Interactively define environment for a task, and deploy it to the service`,
+	Long: `Interactively define environment for a task, and deploy it to the service
+
+Secrets appear as KEY=ssm:/path or KEY=secretsmanager:arn lines, and can be
+added, removed or repointed the same way.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cluster := args[0]
-		service := args[1]
+		cluster, service := ServiceChooser(args)
 		primary, err := ecs.GetDeployedEssentialContainer(cluster, service)
 		if err != nil {
 			fmt.Printf("Error finding essential container:\n%v\n", err)
 			os.Exit(1)
 		}
-		original := ecs.KeyPairsToString(primary.Environment)
+		original := ecs.EnvVarsToString(ecs.ContainerEnv(primary))
 		original = strings.TrimSpace(original)
 		edited, err := EditStringBlock(original)
 		edited = strings.TrimSpace(edited)
@@ -84,7 +203,7 @@ var editCmd = &cobra.Command{
 		if original == edited {
 			fmt.Println("No changes made to environment.  Nothing to do!")
 		} else {
-			newKeyPairs, err := ecs.StringToKeyPairs(edited)
+			newVars, err := ecs.StringToEnvVars(edited)
 			if err != nil {
 				fmt.Printf("Problem parsing new environment: %v\n", err)
 				os.Exit(1)
@@ -95,66 +214,195 @@ Do you want to save the following environment to the task?
 %s
 
 This will also update service "%s" in "%s" to a new task definition.`
-			if !AskForConfirmation(fmt.Sprintf(confirm, ecs.KeyPairsToString(newKeyPairs), cluster, service)) {
+			if !AskForConfirmation(fmt.Sprintf(confirm, ecs.EnvVarsToString(newVars), cluster, service)) {
 				return
 			}
-			deployEnv(args[0], args[1], newKeyPairs)
+			deployEnv(cluster, service, newVars)
 		}
 	},
 	PreRunE: Validate2ArgumentsCount,
 }
 
+// unsetCmd removes variables
+var unsetCmd = &cobra.Command{
+	Use:   "unset [cluster-name] [service-name] [env_var_name]...",
+	Short: "Remove environment variables (or secrets) from an ECS service's task definition",
+	Long: `Remove one or more environment variables or secrets from the essential
+container, and deploy the result to the service as a single new revision`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, service := ServiceChooser(args)
+		container, err := ecs.GetDeployedEssentialContainer(cluster, service)
+		failOnError(err, "")
+		vars, missing := ecs.RemoveEnvVars(ecs.ContainerEnv(container), args[2:])
+		for _, name := range missing {
+			fmt.Printf("%s is not set\n", name)
+		}
+		deployEnv(cluster, service, vars)
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 3 {
+			return fmt.Errorf("Incorrect number of arguments supplied! (%d / at least 3)", len(args))
+		}
+		return nil
+	},
+}
+
+// importCmd loads variables from a .env file
+var importCmd = &cobra.Command{
+	Use:   "import [cluster-name] [service-name] --file .env",
+	Short: "Load environment variables from a .env file into an ECS service's task definition",
+	Long: `Load environment variables from a .env file and deploy them to the service
+as a single new revision.
+
+With --mode merge (the default) the file's variables are added to, or replace,
+the existing ones. With --mode replace the file becomes the whole environment,
+removing anything it does not list (secrets included). Values may reference
+secrets as ssm:/path or secretsmanager:arn. Use --file - to read stdin.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, service := ServiceChooser(args)
+		var content []byte
+		var err error
+		if envImportFile == "-" {
+			content, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			content, err = ioutil.ReadFile(envImportFile)
+		}
+		failOnError(err, "Problem reading environment file")
+		imported, err := ecs.StringToEnvVars(string(content))
+		failOnError(err, "Problem parsing environment file")
+		vars := imported
+		if envImportMode == "merge" {
+			container, err := ecs.GetDeployedEssentialContainer(cluster, service)
+			failOnError(err, "")
+			vars = ecs.MergeEnvVars(ecs.ContainerEnv(container), imported)
+		}
+		deployEnv(cluster, service, vars)
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := Validate2ArgumentsCount(cmd, args); err != nil {
+			return err
+		}
+		if envImportFile == "" {
+			return fmt.Errorf("--file is required")
+		}
+		if envImportMode != "merge" && envImportMode != "replace" {
+			return fmt.Errorf("unknown mode %q, expected merge or replace", envImportMode)
+		}
+		return nil
+	},
+}
+
 var findCmd = &cobra.Command{
 	Use:   "find [env_var_name] [env_var_value]",
 	Short: "Find all services that have an environment variable with the given name and value",
-	Long:  `Find all services that have an environment variable with the given name and value`,
+	Long: `Find all services, in every cluster, whose containers have an environment
+variable or secret with the given name, and a value containing [env_var_value]
+(any value if omitted). Secret values are matched by their ssm: or
+secretsmanager: reference.
+
+With --regex, the name and value are regular expressions. --image also (or,
+without a name, only) finds containers whose image contains a string.
+
+Services that cannot be searched are reported, without stopping the search.
+
+Examples:
+
+ecsy env find DATABASE_URL prod-db.internal
+ecsy env find --regex '^(REDIS|CACHE)_URL$' 'redis-(1|2)'
+ecsy env find --image nginx:1.1
+`,
 	Run: func(cmd *cobra.Command, args []string) {
-		varName := args[0]
-		varValue := args[1]
-		services, err := ecs.FindServicesWithEnvVar(varName, varValue)
+		search := ecs.EnvSearch{
+			Image:       envFindImage,
+			Regexp:      envFindRegexp,
+			Concurrency: envFindConcurrency,
+		}
+		if len(args) > 0 {
+			search.Name = args[0]
+		}
+		if len(args) > 1 {
+			search.Value = args[1]
+		}
+		result, err := ecs.SearchEnv(search)
 		if err != nil {
 			fmt.Printf("Problem finding services: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Found %d services with %s=%s:\n", len(services), varName, varValue)
-		for _, service := range services {
-			fmt.Printf("%s %s\n", *service.ClusterArn, *service.ServiceName)
+		for _, searchErr := range result.Errors {
+			fmt.Fprintf(os.Stderr, "=> Unable to search %v\n", searchErr)
+		}
+		fmt.Printf("Found %d matches:\n", len(result.Matches))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, match := range result.Matches {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s=%s\n", match.Cluster, match.Service, match.TaskDefinition, match.Container, match.Name, match.Value)
+		}
+		w.Flush()
+		if len(result.Errors) > 0 {
+			os.Exit(1)
+		}
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 2 || (len(args) == 0 && envFindImage == "") {
+			return fmt.Errorf("expected [env_var_name] [env_var_value], or --image")
 		}
+		return nil
 	},
-	PreRunE: Validate2ArgumentsCount,
 }
 
 func init() {
 	RootCmd.AddCommand(envCmd)
 	envCmd.AddCommand(getCmd)
+	readOnly(getCmd)
 	envCmd.AddCommand(editCmd)
 	envCmd.AddCommand(setCmd)
 	envCmd.AddCommand(findCmd)
+	readOnly(findCmd)
+	envCmd.AddCommand(unsetCmd)
+	envCmd.AddCommand(importCmd)
+	getCmd.Flags().StringVar(&envGetFormat, "format", ecs.EnvFormatDotenv, "Output format (dotenv|json|shell-export)")
+	importCmd.Flags().StringVarP(&envImportFile, "file", "f", "", "The .env file to import, - for stdin")
+	importCmd.Flags().StringVar(&envImportMode, "mode", "merge", "merge into or replace the existing environment (merge|replace)")
+	findCmd.Flags().StringVar(&envFindImage, "image", "", "Also find containers whose image contains this string")
+	findCmd.Flags().BoolVar(&envFindRegexp, "regex", false, "Treat the name, value and image as regular expressions")
+	findCmd.Flags().IntVar(&envFindConcurrency, "concurrency", ecs.DefaultSearchConcurrency, "Maximum parallel AWS calls")
+	addWaitFlags(unsetCmd)
+	addWaitFlags(importCmd)
+	addWaitFlags(setCmd)
+	setCmd.Flags().StringVar(&envSecretStore, "secret", "", "Store the value as a secret in ssm or secretsmanager, and reference it")
+	setCmd.Flags().StringVar(&envSecretName, "secret-name", "", "Name of the SSM parameter or Secrets Manager secret (with --secret)")
+	addWaitFlags(editCmd)
 }
 
-func deployEnv(cluster, service string, newKeyPairs []*awsecs.KeyValuePair) {
+func deployEnv(cluster, service string, vars []ecs.EnvVar) {
 	fmt.Println("Getting current task definition...")
 	task, err := ecs.GetCurrentTaskDefinition(cluster, service)
 	if err != nil {
 		fmt.Printf("%v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("Creating new task based on %s:%d, with new environment\n", *task.Family, *task.Revision)
-	newTask, err := ecs.CreateNewTaskWithEnvironment(task, newKeyPairs)
+	essential, err := ecs.GetEssentialContainer(task)
+	failOnError(err, "")
+	if ecs.EnvVarsEqual(ecs.ContainerEnv(essential), vars) {
+		fmt.Println("No changes made to environment.  Nothing to do!")
+		return
+	}
+	ecs.PrintAction("Creating new task based on %s:%d, with new environment", "Would create new task based on %s:%d, with new environment", *task.Family, *task.Revision)
+	newTask, err := ecs.CreateNewTaskWithEnv(task, vars)
 	if err != nil {
 		fmt.Printf("Problem creating new task: %v\n", err)
 		os.Exit(1)
 	}
-	serviceStruct, err := ecs.DeployTaskToService(cluster, service, newTask)
+	serviceStruct, err := deployTaskDefinition(cluster, service, newTask)
 	if err != nil {
 		fmt.Printf("Problem deploying task: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("\nSuccessfully deployed new task definition")
+	ecs.PrintAction("\nSuccessfully deployed new task definition", "\nWould deploy new task definition")
 	fmt.Println("=========================================")
 	fmt.Printf("Cluster: %s\n", cluster)
 	fmt.Printf("Service: %s\n", service)
 	fmt.Printf("Task Definition: %s:%d\n", *newTask.Family, *newTask.Revision)
 	fmt.Printf("Desired Count: %d\n", *serviceStruct.DesiredCount)
 	fmt.Printf("To view deployment status, you can visit:\n%s\n", ecs.BuildConsoleURLForService(cluster, service)+"/deployments")
+	failOnError(waitForDeployment(cluster, service, newTask), "Deployment failed")
 }
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+var envDiffShowValues bool
+
+// envDiffCmd compares the environment of two services
+var envDiffCmd = &cobra.Command{
+	Use:   "diff [cluster/service] [cluster/service]",
+	Short: "Compare the environment of two services, possibly in different clusters",
+	Long: `Show the variables added, removed and changed between the essential
+containers of two services. Plain values are masked unless --show-values is
+given, secret references (ssm:, secretsmanager:) are always shown.
+
+Example:
+
+ecsy env diff qa/api prod/api
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("Incorrect number of arguments supplied! (%d / 2)", len(args))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fromCluster, fromService := resolveServiceRef(args[0])
+		from, err := serviceEnv(fromCluster, fromService)
+		failOnError(err, "")
+		toCluster, toService := resolveServiceRef(args[1])
+		to, err := serviceEnv(toCluster, toService)
+		failOnError(err, "")
+		fmt.Printf("--- %s/%s\n+++ %s/%s\n", fromCluster, fromService, toCluster, toService)
+		changes := ecs.DiffEnvVars(from, to)
+		if len(changes) == 0 {
+			fmt.Println("No differences")
+			return
+		}
+		printEnvChanges(changes, envDiffShowValues)
+	},
+}
+
+// envCopyCmd promotes variables from one service to another
+var envCopyCmd = &cobra.Command{
+	Use:   "copy [cluster/service] [cluster/service] [env_var_name]...",
+	Short: "Copy selected environment variables from one service to another",
+	Long: `Copy the named variables (plain values or secret references) from the
+essential container of the first service to that of the second, and deploy the
+second service with them as a single new revision.
+
+Example:
+
+ecsy env copy qa/api prod/api FEATURE_X_ENABLED SEARCH_URL
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 3 {
+			return fmt.Errorf("Incorrect number of arguments supplied! (%d / at least 3)", len(args))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		sourceCluster, sourceService := resolveServiceRef(args[0])
+		source, err := serviceEnv(sourceCluster, sourceService)
+		failOnError(err, "")
+		selected, err := ecs.SelectEnvVars(source, args[2:])
+		failOnError(err, fmt.Sprintf("Problem reading %s/%s", sourceCluster, sourceService))
+		cluster, service := resolveServiceRef(args[1])
+		target, err := serviceEnv(cluster, service)
+		failOnError(err, "")
+		vars := ecs.MergeEnvVars(target, selected)
+		changes := ecs.DiffEnvVars(target, vars)
+		if len(changes) == 0 {
+			fmt.Println("No changes made to environment.  Nothing to do!")
+			return
+		}
+		printEnvChanges(changes, envDiffShowValues)
+		confirm := fmt.Sprintf("\nThis will update service %q in %q to a new task definition with the changes above.", service, cluster)
+		if !AskForConfirmation(confirm) {
+			return
+		}
+		deployEnv(cluster, service, vars)
+	},
+}
+
+// parseServiceRef splits a cluster/service argument
+func parseServiceRef(ref string) (string, string, error) {
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("expected cluster/service, got %q", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// resolveServiceRef resolves a cluster/service argument, either part of
+// which may be a partial name, and applies the cluster's configured AWS
+// settings
+func resolveServiceRef(ref string) (string, string) {
+	cluster, service, err := parseServiceRef(ref)
+	failOnError(err, "")
+	return ServiceChooser([]string{cluster, service})
+}
+
+// serviceEnv reads the environment of a service's essential container.
+// The cluster's AWS settings must already be applied.
+func serviceEnv(cluster, service string) ([]ecs.EnvVar, error) {
+	container, err := ecs.GetDeployedEssentialContainer(cluster, service)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %v", cluster, service, err)
+	}
+	return ecs.ContainerEnv(container), nil
+}
+
+func printEnvChanges(changes []ecs.EnvChange, reveal bool) {
+	for _, change := range changes {
+		switch {
+		case change.From == nil:
+			fmt.Println(colorize(colorGreen, fmt.Sprintf("+ %s=%s", change.Name, change.To.MaskedValue(reveal))))
+		case change.To == nil:
+			fmt.Println(colorize(colorRed, fmt.Sprintf("- %s=%s", change.Name, change.From.MaskedValue(reveal))))
+		default:
+			fmt.Println(colorize(colorYellow, fmt.Sprintf("~ %s: %s => %s", change.Name, change.From.MaskedValue(reveal), change.To.MaskedValue(reveal))))
+		}
+	}
+}
+
+func init() {
+	envCmd.AddCommand(envDiffCmd)
+	readOnly(envDiffCmd)
+	envCmd.AddCommand(envCopyCmd)
+	envDiffCmd.Flags().BoolVar(&envDiffShowValues, "show-values", false, "Show plain values instead of masking them")
+	envCopyCmd.Flags().BoolVar(&envDiffShowValues, "show-values", false, "Show plain values instead of masking them")
+	addWaitFlags(envCopyCmd)
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/oberd/ecsy/ecs"
 	"github.com/spf13/cobra"
 )
@@ -13,8 +15,22 @@ var eventsCmd = &cobra.Command{
 	Short: "Show recent events for a service in a cluster",
 	Long:  `Show recent events for a service in a cluster`,
 	Run: func(cmd *cobra.Command, args []string) {
-		svc, err := ecs.FindService(args[0], args[1])
+		cluster, service := ServiceChooser(args)
+		svc, err := ecs.FindService(cluster, service)
 		failOnError(err, "Error finding service")
+		if structuredOutput() {
+			// oldest first, like the table
+			events := make([]eventOutput, len(svc.Events))
+			for i, event := range svc.Events {
+				events[len(events)-1-i] = eventOutput{
+					ID:        aws.StringValue(event.Id),
+					CreatedAt: aws.TimeValue(event.CreatedAt),
+					Message:   aws.StringValue(event.Message),
+				}
+			}
+			failOnError(printOutput(events), "")
+			return
+		}
 		out := ""
 		for _, event := range svc.Events {
 			out = fmt.Sprintf("[%v] %s\n", *event.CreatedAt, *event.Message) + out
@@ -29,6 +45,14 @@ var eventsCmd = &cobra.Command{
 	},
 }
 
+// eventOutput is the --output json|yaml schema of events
+type eventOutput struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Message   string    `json:"message"`
+}
+
 func init() {
 	RootCmd.AddCommand(eventsCmd)
+	readOnly(eventsCmd)
 }
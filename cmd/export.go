@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var exportRules bool
+var exportTaskDefinition bool
+var exportSpec bool
+
+type exportOutput struct {
+	TaskDefinition json.RawMessage    `json:"task_definition"`
+	Service        json.RawMessage    `json:"service"`
+	Rules          []exportRuleOutput `json:"rules,omitempty"`
+}
+
+type exportRuleOutput struct {
+	Kind    string          `json:"kind"`
+	Rule    json.RawMessage `json:"rule"`
+	Targets json.RawMessage `json:"targets"`
+}
+
+// exportCmd dumps a service as the requests that would recreate it
+var exportCmd = &cobra.Command{
+	Use:   "export [cluster] [service]",
+	Short: "Dump a service as portable task definition and service JSON, or as a spec",
+	Long: `Print the deployed task definition of a service as a RegisterTaskDefinition
+request (read-only fields such as revision, status and requiresAttributes
+removed, tags kept) and the service settings as a CreateService request. With
+--rules, the scheduled and post-deployment tasks ecsy created for the service
+are included as PutRule and PutTargets requests.
+
+--task-definition prints only the task definition, ready for
+"aws ecs register-task-definition --cli-input-json" or "ecsy register".
+--spec prints the service as a spec file for "ecsy apply" instead.
+
+Example:
+    ecsy export my-cluster my-service --rules > my-service.json
+    ecsy export my-cluster my-service --spec --rules > services/my-service.yaml
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if exportTaskDefinition && (exportSpec || exportRules) {
+			return fmt.Errorf("--task-definition can not be combined with --spec or --rules")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, service := ServiceChooser(args)
+		export, err := ecs.ExportService(cluster, service, exportRules)
+		failOnError(err, "Error reading service")
+		switch {
+		case exportTaskDefinition:
+			body, err := ecs.MarshalShape(export.TaskDefinition)
+			failOnError(err, "")
+			out, err := formatOutput(json.RawMessage(body), outputJSON, true)
+			failOnError(err, "")
+			fmt.Print(out)
+		case exportSpec && outputFormat == outputJSON:
+			out, err := json.MarshalIndent(export.Spec, "", "  ")
+			failOnError(err, "")
+			fmt.Println(string(out))
+		case exportSpec:
+			out, err := yaml.Marshal(export.Spec)
+			failOnError(err, "")
+			fmt.Print(string(out))
+		default:
+			failOnError(printExport(export), "Error printing export")
+		}
+	},
+}
+
+// printExport prints the requests of an export, each in the shape AWS
+// expects it
+func printExport(export *ecs.ServiceExport) error {
+	taskDefinition, err := ecs.MarshalShape(export.TaskDefinition)
+	if err != nil {
+		return err
+	}
+	service, err := ecs.MarshalShape(export.Service)
+	if err != nil {
+		return err
+	}
+	out := exportOutput{TaskDefinition: taskDefinition, Service: service}
+	for _, rule := range export.Rules {
+		body, err := ecs.MarshalShape(rule.Rule)
+		if err != nil {
+			return err
+		}
+		targets, err := ecs.MarshalShape(rule.Targets)
+		if err != nil {
+			return err
+		}
+		out.Rules = append(out.Rules, exportRuleOutput{Kind: rule.Kind, Rule: body, Targets: targets})
+	}
+	return printOutput(out)
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+	readOnly(exportCmd)
+	exportCmd.Flags().BoolVar(&exportRules, "rules", false, "Include the service's scheduled and post-deployment tasks")
+	exportCmd.Flags().BoolVar(&exportTaskDefinition, "task-definition", false, "Print only the task definition, as a RegisterTaskDefinition request")
+	exportCmd.Flags().BoolVar(&exportSpec, "spec", false, "Print the service as a spec file for apply (YAML, or JSON with --output json)")
+}
@@ -8,55 +8,157 @@ import (
 	"os"
 	"os/exec"
 	"sort"
-	"strconv"
 	"strings"
 
+	"github.com/oberd/ecsy/config"
 	"github.com/oberd/ecsy/ecs"
+	"github.com/oberd/ecsy/picker"
 	"github.com/spf13/cobra"
 )
 
-// ServiceChooser enforces an arguments array have a cluster and service
-// if it does not, it will search and prompt
+// ServiceChooser resolves the [cluster] [service] arguments of a command,
+// which may be partial names ("prod api" for prod-us/api-gateway), and
+// applies the cluster's configured AWS settings. Missing or ambiguous names
+// are chosen interactively on a terminal.
 func ServiceChooser(args []string) (string, string) {
-	var cluster, service string
-	if len(args) > 0 {
-		cluster = args[0]
-	}
+	var service string
 	if len(args) > 1 {
 		service = args[1]
 	}
-	if cluster == "" {
-		names, err := ecs.GetClusterNames()
-		failOnError(err, "Error finding clusters")
-		cluster = StringChooser(names, "Please choose a cluster")
+	cluster := ClusterChooser(args)
+	return cluster, resolveService(cluster, service)
+}
+
+// ClusterChooser resolves the [cluster] argument of a command like
+// ServiceChooser does, and applies the cluster's configured AWS settings
+func ClusterChooser(args []string) string {
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	}
+	cluster := resolveCluster(name)
+	useCluster(cluster)
+	return cluster
+}
+
+func resolveCluster(name string) string {
+	configured := config.ClusterNames()
+	for _, c := range configured {
+		if c == name {
+			return name
+		}
 	}
-	if service == "" {
-		services, err := ecs.ListServices(cluster)
-		service = StringChooser(services, "Please choose a service")
-		failOnError(err, "Error finding services")
+	// list with the settings from the flags, not those of a cluster
+	// resolved earlier in the same command
+	failOnError(configureClient(flagSessionOptions()), "Error configuring AWS session")
+	names, err := ecs.GetClusterNames()
+	if err != nil && name != "" {
+		// resolving is a convenience, the name may still be right
+		return name
 	}
-	return cluster, service
+	failOnError(err, "Error finding clusters")
+	for _, c := range configured {
+		if !containsName(names, c) {
+			names = append(names, c)
+		}
+	}
+	return resolveName("cluster", name, names)
 }
 
-// StringChooser asks a user to pick from a series of strings
+func resolveService(cluster, name string) string {
+	services, err := ecs.ListServices(cluster)
+	if err != nil && name != "" {
+		return name
+	}
+	failOnError(err, "Error finding services")
+	return resolveName("service", name, services)
+}
+
+// annotationReadOnly marks commands that change nothing
+const annotationReadOnly = "ecsy/read-only"
+
+// looseNames lets a name resolve to a single candidate that merely contains
+// it, which only read-only commands do without asking
+var looseNames bool
+
+// readOnly marks a command as changing nothing, so partial names given to
+// it resolve without confirmation
+func readOnly(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[annotationReadOnly] = "true"
+}
+
+// resolveName finds the candidate a name refers to. An exact match, or a
+// partial name matching a single candidate, resolves directly; for commands
+// that make changes, a match that is not by prefix must be confirmed.
+// Otherwise the user picks among the closest matches (or all candidates,
+// for an empty name), or the command fails listing them when there is no
+// terminal.
+func resolveName(kind, name string, candidates []string) string {
+	if name == "" {
+		return StringChooser(candidates, fmt.Sprintf("Please choose a %s", kind))
+	}
+	matches := picker.Match(candidates, name)
+	switch len(matches) {
+	case 0:
+		failOnError(fmt.Errorf("no %s matches %q, expected one of:%s", kind, name, candidateList(candidates)), "Unable to find "+kind)
+	case 1:
+		if matches[0] == name {
+			return name
+		}
+		if !looseNames && !picker.IsPrefix(matches[0], name) {
+			confirmName(kind, name, matches[0])
+		}
+		fmt.Fprintf(os.Stderr, "=> Using %s %s\n", kind, matches[0])
+		return matches[0]
+	}
+	return StringChooser(matches, fmt.Sprintf("%q matches several %ss, please choose one", name, kind))
+}
+
+// confirmName asks before a command that makes changes acts on a candidate
+// a name only loosely matches, failing without a terminal to ask on
+func confirmName(kind, name, match string) {
+	if !picker.IsTerminal(os.Stdin) {
+		failOnError(fmt.Errorf("%q only partly matches %s %s, give its full name or a prefix of it", name, kind, match), "Unable to find "+kind)
+	}
+	if !AskForConfirmation(fmt.Sprintf("%q partly matches %s %s, use it?", name, kind, match)) {
+		failOnError(fmt.Errorf("%s %s not confirmed", kind, match), "Aborted")
+	}
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func candidateList(candidates []string) string {
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+	return "\n  " + strings.Join(sorted, "\n  ")
+}
+
+// StringChooser asks a user to pick from a series of strings, with a
+// type-to-filter picker. Without a terminal to ask on, it fails listing the
+// options rather than waiting for input that will not come.
 func StringChooser(options []string, title string) string {
 	if len(options) == 1 {
 		return options[0]
 	}
-	for {
-		fmt.Printf("%s:\n", title)
-		reader := bufio.NewReader(os.Stdin)
-		sort.Strings(options)
-		for choice, disp := range options {
-			fmt.Printf("[%d]: %s\n", choice+1, disp)
-		}
-		text, _ := reader.ReadString('\n')
-		choice, err := strconv.Atoi(text[:len(text)-1])
-		choice--
-		if err == nil && choice >= 0 && choice < len(options) {
-			return options[choice]
-		}
+	if len(options) == 0 {
+		failOnError(fmt.Errorf("nothing to choose from"), title)
+	}
+	if !picker.IsTerminal(os.Stdin) || !picker.IsTerminal(os.Stderr) {
+		failOnError(fmt.Errorf("not a terminal, pass one of:%s", candidateList(options)), title)
 	}
+	choice, err := picker.Choose(os.Stdin, os.Stderr, title, options, "")
+	failOnError(err, title)
+	return choice
 }
 
 // AskForConfirmation asks the user for confirmation. A user must type in "yes" or "no" and
@@ -132,6 +234,21 @@ func EditStringBlock(input string) (output string, err error) {
 	return string(edited), nil
 }
 
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+// colorize wraps text in an ANSI color when stdout is a terminal
+func colorize(color, text string) string {
+	if noColor || !picker.IsTerminal(os.Stdout) {
+		return text
+	}
+	return color + text + colorReset
+}
+
 func failOnError(err error, message string) {
 	if err != nil {
 		fmt.Printf("%s: %v\n", message, err)
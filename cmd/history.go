@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+var historyMax int
+
+// historyCmd lists the revisions of a service's task family
+var historyCmd = &cobra.Command{
+	Use:   "history [cluster] [service]",
+	Short: "List task definition revisions of a service's family",
+	Long: `List the most recent task definition revisions of the family a service runs,
+with the essential image, sizing and registration time. The revision currently
+deployed to the service is marked with *`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if historyMax < 1 {
+			return fmt.Errorf("--max must be at least 1, got %d", historyMax)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, service := ServiceChooser(args)
+		current, err := ecs.GetCurrentTaskDefinition(cluster, service)
+		failOnError(err, "Error finding current task definition")
+		revisions, err := ecs.ListFamilyRevisions(*current.Family, historyMax)
+		failOnError(err, "Error listing revisions")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tREVISION\tIMAGE\tCPU\tMEMORY\tREGISTERED")
+		for _, def := range revisions {
+			marker := ""
+			if *def.TaskDefinitionArn == *current.TaskDefinitionArn {
+				marker = "*"
+			}
+			registered := ""
+			if def.RegisteredAt != nil {
+				registered = def.RegisteredAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				marker,
+				path.Base(*def.TaskDefinitionArn),
+				ecs.EssentialImage(def),
+				taskCPU(def),
+				taskMemory(def),
+				registered,
+			)
+		}
+		w.Flush()
+	},
+}
+
+// taskCPU returns the task level cpu, or the essential container's
+func taskCPU(def *awsecs.TaskDefinition) string {
+	if def.Cpu != nil {
+		return *def.Cpu
+	}
+	if essential, err := ecs.GetEssentialContainer(def); err == nil && essential.Cpu != nil {
+		return fmt.Sprintf("%d", *essential.Cpu)
+	}
+	return "-"
+}
+
+// taskMemory returns the task level memory, or the essential container's
+// memory (and reservation)
+func taskMemory(def *awsecs.TaskDefinition) string {
+	if def.Memory != nil {
+		return *def.Memory
+	}
+	essential, err := ecs.GetEssentialContainer(def)
+	if err != nil {
+		return "-"
+	}
+	if essential.Memory != nil {
+		return fmt.Sprintf("%d", *essential.Memory)
+	}
+	if essential.MemoryReservation != nil {
+		return fmt.Sprintf("%d (soft)", *essential.MemoryReservation)
+	}
+	return "-"
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+	readOnly(historyCmd)
+	historyCmd.Flags().IntVarP(&historyMax, "max", "n", 10, "number of revisions to show")
+}
@@ -17,6 +17,13 @@ var listClustersCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if structuredOutput() {
+			out := make([]clusterOutput, len(list))
+			for i, name := range list {
+				out[i] = clusterOutput{Name: name}
+			}
+			return printOutput(out)
+		}
 		for _, svc := range list {
 			fmt.Println(svc)
 		}
@@ -24,6 +31,12 @@ var listClustersCmd = &cobra.Command{
 	},
 }
 
+// clusterOutput is the --output json|yaml schema of list-clusters
+type clusterOutput struct {
+	Name string `json:"name"`
+}
+
 func init() {
 	RootCmd.AddCommand(listClustersCmd)
+	readOnly(listClustersCmd)
 }
@@ -9,17 +9,22 @@ import (
 
 // listServicesCmd represents the listServices command
 var listServicesCmd = &cobra.Command{
-	Use:   "list-services",
+	Use:   "list-services [cluster]",
 	Short: "list services in a cluster",
 	Long:  `list services in a cluster`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 0 {
-			return fmt.Errorf("please provide an argument for [cluster]")
-		}
-		list, err := ecs.ListServices(args[0])
+		cluster := ClusterChooser(args)
+		list, err := ecs.ListServices(cluster)
 		if err != nil {
 			return err
 		}
+		if structuredOutput() {
+			out := make([]serviceOutput, len(list))
+			for i, name := range list {
+				out[i] = serviceOutput{Cluster: cluster, Name: name}
+			}
+			return printOutput(out)
+		}
 		for _, svc := range list {
 			fmt.Println(svc)
 		}
@@ -27,6 +32,13 @@ var listServicesCmd = &cobra.Command{
 	},
 }
 
+// serviceOutput is the --output json|yaml schema of list-services
+type serviceOutput struct {
+	Cluster string `json:"cluster"`
+	Name    string `json:"name"`
+}
+
 func init() {
 	RootCmd.AddCommand(listServicesCmd)
+	readOnly(listServicesCmd)
 }
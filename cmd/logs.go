@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
+	"time"
 
 	"github.com/oberd/ecsy/ecs"
 	"github.com/spf13/cobra"
@@ -9,25 +13,239 @@ import (
 
 // Can be "all", "running", "stopped"
 var logsStatusFilter = "all"
+var logsFollow bool
+var logsSince string
+var logsUntil string
+var logsFilterPattern string
+var logsTaskIDs []string
+var logsContainers []string
+var logsJSON bool
+var logsWhere []string
+var logsFields []string
+var logsPretty bool
+var logsNDJSON bool
+var logsConditions []ecs.LogCondition
+
+// logsShowContainer prefixes events with their container when logs of more
+// than one container are shown
+var logsShowContainer bool
 
 // logsCmd represents the logs command
 var logsCmd = &cobra.Command{
 	Use:   "logs [cluster] [service]",
 	Short: "Show recent logs for a service in a cluster (must be cloudwatch based)",
-	Long:  `Show recent logs for a service in a cluster`,
+	Long: `Show recent logs for a service in a cluster, interleaved across tasks
+by event time.
+
+Examples:
+
+ecsy logs my-cluster api --since 15m --filter-pattern ERROR
+ecsy logs my-cluster api -f
+ecsy logs my-cluster api --task 0123abcd --since 2021-06-01T10:00:00Z --until 2021-06-01T11:00:00Z
+ecsy logs my-cluster api --container nginx --container app
+ecsy logs my-cluster api --where level=error --fields ts,level,msg
+ecsy logs my-cluster api --since 1h --ndjson | jq .msg
+
+Containers using the awslogs driver, or FireLens (awsfirelens) with a
+cloudwatch / cloudwatch_logs output, are supported. Containers whose logs
+cannot be retrieved are listed and skipped.
+
+With --json, messages that are JSON objects are parsed. --where keeps only
+events whose field matches (field=value or field!=value, nested fields as
+a.b, repeat for several conditions), --fields projects fields, --pretty
+indents them and --ndjson prints one JSON object per event, with the task
+id and container name attached. --where, --fields, --pretty and --ndjson
+imply --json.
+
+The global --output json prints one JSON object per event (timestamp,
+task_id, container, message, and the parsed fields with --json), --output
+yaml one YAML document per event.
+`,
 	Run: func(cmd *cobra.Command, args []string) {
-		err := ecs.GetLogs(args[0], args[1], logsStatusFilter)
+		cluster, service := ServiceChooser(args)
+		query, err := logsQuery(time.Now())
+		failOnError(err, "")
+		failOnError(checkLogContainers(cluster, service), "")
+		if logsFollow {
+			stop := make(chan struct{})
+			interrupt := make(chan os.Signal, 1)
+			signal.Notify(interrupt, os.Interrupt)
+			go func() {
+				<-interrupt
+				close(stop)
+			}()
+			err = ecs.FollowServiceLogs(cluster, service, query, stop, printLogEvent)
+			failOnError(err, "")
+			return
+		}
+		events, err := ecs.GetServiceLogs(cluster, service, query)
 		failOnError(err, "")
+		for _, event := range events {
+			printLogEvent(event)
+		}
 	},
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) != 2 {
 			return fmt.Errorf("Incorrect number of arguments supplied! (%d / 2)", len(args))
 		}
+		if logsFollow && logsUntil != "" {
+			return fmt.Errorf("--until cannot be used with --follow")
+		}
+		if logsNDJSON && structuredOutput() {
+			return fmt.Errorf("--ndjson cannot be used with --output %s", outputFormat)
+		}
+		logsConditions = make([]ecs.LogCondition, 0, len(logsWhere))
+		for _, expr := range logsWhere {
+			cond, err := ecs.ParseLogCondition(expr)
+			if err != nil {
+				return err
+			}
+			logsConditions = append(logsConditions, cond)
+		}
+		if len(logsWhere) > 0 || len(logsFields) > 0 || logsPretty || logsNDJSON {
+			logsJSON = true
+		}
 		return nil
 	},
 }
 
+// logsQuery builds the log query from the flags
+func logsQuery(now time.Time) (ecs.LogsQuery, error) {
+	query := ecs.LogsQuery{
+		Status:        logsStatusFilter,
+		TaskIDs:       logsTaskIDs,
+		Containers:    logsContainers,
+		FilterPattern: logsFilterPattern,
+	}
+	var err error
+	if query.Since, err = ecs.ParseLogTime(logsSince, now); err != nil {
+		return query, err
+	}
+	if query.Until, err = ecs.ParseLogTime(logsUntil, now); err != nil {
+		return query, err
+	}
+	if logsFollow && logsStatusFilter == "all" {
+		// only running tasks write new events
+		query.Status = "running"
+	}
+	return query, nil
+}
+
+// checkLogContainers reports the containers of the service whose logs
+// cannot be retrieved, failing only if there are no logs to show at all
+func checkLogContainers(cluster, service string) error {
+	def, err := ecs.GetCurrentTaskDefinition(cluster, service)
+	if err != nil {
+		return err
+	}
+	containers, unavailable, err := ecs.LogContainers(def, logsContainers)
+	if err != nil {
+		return err
+	}
+	for _, u := range unavailable {
+		fmt.Fprintf(os.Stderr, "=> No logs for container %s: %v\n", u.Container, u.Reason)
+	}
+	if len(containers) == 0 {
+		return fmt.Errorf("no container of %s has retrievable logs", service)
+	}
+	logsShowContainer = len(containers) > 1
+	return nil
+}
+
+func printLogEvent(event *ecs.LogEvent) {
+	if line, ok := formatLogEvent(event); ok {
+		fmt.Println(line)
+	}
+}
+
+// formatLogEvent renders an event according to the output flags, reporting
+// false for events the --where conditions exclude
+func formatLogEvent(event *ecs.LogEvent) (string, bool) {
+	prefix := ""
+	if len(logsTaskIDs) != 1 {
+		prefix += event.TaskID + " "
+	}
+	if logsShowContainer {
+		prefix += event.Container + " "
+	}
+	var fields ecs.LogFields
+	isJSON := false
+	if logsJSON {
+		fields, isJSON = ecs.ParseLogFields(event.Message)
+		for _, cond := range logsConditions {
+			if !isJSON || !cond.Match(fields) {
+				return "", false
+			}
+		}
+		if isJSON && len(logsFields) > 0 {
+			fields = fields.Project(logsFields)
+		}
+	}
+	if structuredOutput() {
+		record := logEventOutput{
+			Timestamp: event.Timestamp.UTC(),
+			TaskID:    event.TaskID,
+			Container: event.Container,
+			Message:   event.Message,
+		}
+		if isJSON {
+			record.Fields = fields
+		}
+		line, err := formatOutputRecord(record)
+		if err != nil {
+			return prefix + event.String(), true
+		}
+		return line, true
+	}
+	if !logsJSON {
+		return prefix + event.String(), true
+	}
+	if logsNDJSON {
+		line, err := event.NDJSON(fields)
+		if err != nil {
+			return prefix + event.String(), true
+		}
+		return string(line), true
+	}
+	if !isJSON {
+		return prefix + event.String(), true
+	}
+	formatted := *event
+	if logsPretty {
+		indented, err := json.MarshalIndent(fields, "", "  ")
+		if err != nil {
+			return prefix + event.String(), true
+		}
+		formatted.Message = string(indented)
+	} else {
+		formatted.Message = fields.Format(logsFields)
+	}
+	return prefix + formatted.String(), true
+}
+
+// logEventOutput is the --output json|yaml schema of logs, one JSON line
+// or YAML document per event. Fields holds the parsed message with --json.
+type logEventOutput struct {
+	Timestamp time.Time     `json:"timestamp"`
+	TaskID    string        `json:"task_id"`
+	Container string        `json:"container"`
+	Message   string        `json:"message"`
+	Fields    ecs.LogFields `json:"fields,omitempty"`
+}
+
 func init() {
 	RootCmd.AddCommand(logsCmd)
+	readOnly(logsCmd)
 	logsCmd.Flags().StringVarP(&logsStatusFilter, "status", "s", "all", "Limit to only tasks of [status] (stopped|running|all)")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow new log events of the service's running tasks")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Only show events newer than a duration (15m, 2h) or RFC3339 time")
+	logsCmd.Flags().StringVar(&logsUntil, "until", "", "Only show events older than a duration (15m, 2h) or RFC3339 time")
+	logsCmd.Flags().StringVar(&logsFilterPattern, "filter-pattern", "", "CloudWatch Logs filter pattern events must match")
+	logsCmd.Flags().StringSliceVar(&logsContainers, "container", nil, "Limit to the given container(s), may be repeated (defaults to all containers)")
+	logsCmd.Flags().BoolVar(&logsJSON, "json", false, "Parse JSON object messages into fields")
+	logsCmd.Flags().StringArrayVar(&logsWhere, "where", nil, "Only show JSON events whose field matches, field=value or field!=value, may be repeated")
+	logsCmd.Flags().StringSliceVar(&logsFields, "fields", nil, "Only show these JSON fields, comma separated")
+	logsCmd.Flags().BoolVar(&logsPretty, "pretty", false, "Indent JSON events")
+	logsCmd.Flags().BoolVar(&logsNDJSON, "ndjson", false, "Print one JSON object per event, with task id and container name attached")
+	logsCmd.Flags().StringSliceVar(&logsTaskIDs, "task", nil, "Limit to the given task id(s), may be repeated")
 }
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+var logsExportSince string
+var logsExportUntil string
+var logsExportOut string
+var logsExportStatus string
+var logsExportContainers []string
+var logsExportTaskIDs []string
+
+// logsExportCmd downloads a service's logs into a directory or tarball
+var logsExportCmd = &cobra.Command{
+	Use:   "export [cluster] [service]",
+	Short: "Download a service's logs into files, for attaching to tickets",
+	Long: `Download the log stream of every container of every task of a service
+within a time window, one task-id/container.log file per stream, along with a
+manifest.json describing the export.
+
+When --out ends in .tar.gz or .tgz, the files are bundled into a gzipped
+tarball instead of a directory.
+
+Examples:
+
+ecsy logs export my-cluster api --since 24h --out api-logs/
+ecsy logs export my-cluster api --since 2h --out api-logs.tar.gz
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("Incorrect number of arguments supplied! (%d / 2)", len(args))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, service := ServiceChooser(args)
+		now := time.Now()
+		since, err := ecs.ParseLogTime(logsExportSince, now)
+		failOnError(err, "")
+		until, err := ecs.ParseLogTime(logsExportUntil, now)
+		failOnError(err, "")
+		query := ecs.LogsQuery{
+			Status:     logsExportStatus,
+			Since:      since,
+			Until:      until,
+			Containers: logsExportContainers,
+			TaskIDs:    logsExportTaskIDs,
+		}
+		failOnError(exportLogs(cluster, service, query, logsExportOut), "Error exporting logs")
+	},
+}
+
+// exportLogs exports into out, through a temporary directory when out is
+// a tarball
+func exportLogs(cluster, service string, query ecs.LogsQuery, out string) error {
+	archive := strings.HasSuffix(out, ".tar.gz") || strings.HasSuffix(out, ".tgz")
+	dir := out
+	if archive {
+		tmp, err := ioutil.TempDir("", "ecsy-logs")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	manifest, err := ecs.ExportServiceLogs(cluster, service, query, dir)
+	if err != nil {
+		return err
+	}
+	events := 0
+	for _, file := range manifest.Files {
+		events += file.Events
+		if file.Error != "" {
+			fmt.Printf("=> %s: %s\n", file.File, file.Error)
+		}
+	}
+	if archive {
+		if err := ecs.WriteTarGz(dir, out); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("=> Exported %d events from %d streams to %s\n", events, len(manifest.Files), out)
+	return nil
+}
+
+func init() {
+	logsCmd.AddCommand(logsExportCmd)
+	readOnly(logsExportCmd)
+	logsExportCmd.Flags().StringVar(&logsExportSince, "since", "24h", "Start of the export window, a duration (15m, 2h) or RFC3339 time")
+	logsExportCmd.Flags().StringVar(&logsExportUntil, "until", "", "End of the export window, a duration (15m, 2h) or RFC3339 time (defaults to now)")
+	logsExportCmd.Flags().StringVarP(&logsExportOut, "out", "o", "logs", "Output directory, or a .tar.gz / .tgz file")
+	logsExportCmd.Flags().StringVarP(&logsExportStatus, "status", "s", "all", "Limit to only tasks of [status] (stopped|running|all)")
+	logsExportCmd.Flags().StringSliceVar(&logsExportContainers, "container", nil, "Limit to the given container(s), may be repeated (defaults to all containers)")
+	logsExportCmd.Flags().StringSliceVar(&logsExportTaskIDs, "task", nil, "Limit to the given task id(s), may be repeated")
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+var logsQuerySince string
+var logsQueryUntil string
+var logsQueryLimit int64
+var logsQueryContainers []string
+var logsQueryFormat string
+
+// logsQueryCmd runs a Logs Insights query over a service's log groups
+var logsQueryCmd = &cobra.Command{
+	Use:   "query [cluster] [service] '[insights query]'",
+	Short: "Run a CloudWatch Logs Insights query over a service's logs",
+	Long: `Run a CloudWatch Logs Insights query over the log groups the service's
+containers write to (resolved from the current task definition), and print
+the results as a table, JSON or YAML.
+
+Example:
+
+ecsy logs query my-cluster api --since 30m 'fields @timestamp, @message | filter @message like /ERROR/ | sort @timestamp desc'
+`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 3 {
+			return fmt.Errorf("Incorrect number of arguments supplied! (%d / 3)", len(args))
+		}
+		if logsQueryFormat == "" {
+			logsQueryFormat = outputFormat
+		}
+		return checkOutputFormat(logsQueryFormat)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, service := ServiceChooser(args)
+		queryString := args[2]
+		now := time.Now()
+		since, err := ecs.ParseLogTime(logsQuerySince, now)
+		failOnError(err, "")
+		until, err := ecs.ParseLogTime(logsQueryUntil, now)
+		failOnError(err, "")
+		def, err := ecs.GetCurrentTaskDefinition(cluster, service)
+		failOnError(err, "Error finding current task definition")
+		groups, err := ecs.ServiceLogGroups(def, logsQueryContainers)
+		failOnError(err, "")
+		fmt.Fprintf(os.Stderr, "==> Querying %s...\n", strings.Join(groups, ", "))
+		result, err := ecs.RunInsightsQuery(groups, ecs.InsightsQuery{
+			Query: queryString,
+			Since: since,
+			Until: until,
+			Limit: logsQueryLimit,
+		})
+		failOnError(err, "Error running query")
+		if logsQueryFormat != outputTable {
+			out, err := formatOutput(result.Rows, logsQueryFormat, true)
+			failOnError(err, "")
+			fmt.Print(out)
+			return
+		}
+		printInsightsTable(result)
+	},
+}
+
+func printInsightsTable(result *ecs.InsightsResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(result.Fields, "\t"))
+	for _, row := range result.Rows {
+		values := make([]string, len(result.Fields))
+		for i, field := range result.Fields {
+			values[i] = strings.Replace(row[field], "\n", " ", -1)
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	w.Flush()
+	fmt.Fprintf(os.Stderr, "=> %d rows, %.0f records matched, %.0f scanned\n", len(result.Rows), result.RecordsMatched, result.RecordsScanned)
+}
+
+func init() {
+	logsCmd.AddCommand(logsQueryCmd)
+	readOnly(logsQueryCmd)
+	logsQueryCmd.Flags().StringVar(&logsQuerySince, "since", "1h", "Start of the query window, a duration (15m, 2h) or RFC3339 time")
+	logsQueryCmd.Flags().StringVar(&logsQueryUntil, "until", "", "End of the query window, a duration (15m, 2h) or RFC3339 time (defaults to now)")
+	logsQueryCmd.Flags().Int64Var(&logsQueryLimit, "limit", 0, "Maximum number of rows (defaults to the query's limit)")
+	logsQueryCmd.Flags().StringSliceVar(&logsQueryContainers, "container", nil, "Only query the log groups of the given container(s)")
+	logsQueryCmd.Flags().StringVar(&logsQueryFormat, "format", "", "Output format (table|json|yaml, defaults to --output)")
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Output formats of the read commands, chosen with the global --output flag
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var outputFormat string
+
+func checkOutputFormat(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, expected %s, %s or %s", format, outputTable, outputJSON, outputYAML)
+}
+
+// structuredOutput reports whether --output asks for json or yaml rather
+// than the human readable table
+func structuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// printOutput writes a command's result as indented JSON, or as YAML with
+// the same field names, according to --output
+func printOutput(v interface{}) error {
+	out, err := formatOutput(v, outputFormat, true)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+	return nil
+}
+
+// formatOutputRecord renders one record of a stream (log events, polled
+// statuses): a single JSON line, or a YAML document
+func formatOutputRecord(v interface{}) (string, error) {
+	out, err := formatOutput(v, outputFormat, false)
+	if err != nil {
+		return "", err
+	}
+	if outputFormat == outputYAML {
+		return "---\n" + strings.TrimSuffix(out, "\n"), nil
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+func formatOutput(v interface{}, format string, indent bool) (string, error) {
+	var encoded []byte
+	var err error
+	if indent {
+		encoded, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		encoded, err = json.Marshal(v)
+	}
+	if err != nil {
+		return "", err
+	}
+	if format != outputYAML {
+		return string(encoded) + "\n", nil
+	}
+	// go through JSON so YAML uses the json field names, decoding into a
+	// MapSlice to keep fields in their declared order
+	var doc yaml.MapSlice
+	if err = yaml.Unmarshal([]byte(`{"v": `+string(encoded)+`}`), &doc); err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(doc[0].Value)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
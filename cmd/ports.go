@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"path"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/oberd/ecsy/ecs"
 	"github.com/spf13/cobra"
@@ -14,26 +16,60 @@ var portsCmd = &cobra.Command{
 	Short: "List out exposed service ports for creating new services",
 	Long:  `When you are creating a new exposed port on the servers, nice to see what is already taken!`,
 	Run: func(cmd *cobra.Command, args []string) {
-		services, err := ecs.ListServices(args[0])
+		cluster := ClusterChooser(args)
+		services, err := ecs.ListServices(cluster)
 		if err != nil {
 			log.Errorf("error fetching services: %v", err)
 			return
 		}
+		out := make([]servicePortsOutput, 0, len(services))
 		for _, service := range services {
 			ports := make([]int64, 0)
-			taskDef, err := ecs.GetCurrentTaskDefinition(args[0], service)
+			taskDef, err := ecs.GetCurrentTaskDefinition(cluster, service)
 			if err == nil {
+				entry := servicePortsOutput{
+					Service:        service,
+					TaskDefinition: path.Base(aws.StringValue(taskDef.TaskDefinitionArn)),
+					Ports:          make([]portOutput, 0),
+				}
 				for _, container := range taskDef.ContainerDefinitions {
 					for _, port := range container.PortMappings {
-						ports = append(ports, *port.HostPort)
+						ports = append(ports, aws.Int64Value(port.HostPort))
+						entry.Ports = append(entry.Ports, portOutput{
+							Container:     aws.StringValue(container.Name),
+							ContainerPort: aws.Int64Value(port.ContainerPort),
+							HostPort:      aws.Int64Value(port.HostPort),
+							Protocol:      aws.StringValue(port.Protocol),
+						})
 					}
 				}
-				fmt.Printf("%s %v\n", service, ports)
+				out = append(out, entry)
+				if !structuredOutput() {
+					fmt.Printf("%s %v\n", service, ports)
+				}
 			}
 		}
+		if structuredOutput() {
+			failOnError(printOutput(out), "")
+		}
 	},
 }
 
+// servicePortsOutput is the --output json|yaml schema of ports
+type servicePortsOutput struct {
+	Service        string       `json:"service"`
+	TaskDefinition string       `json:"task_definition"`
+	Ports          []portOutput `json:"ports"`
+}
+
+type portOutput struct {
+	Container     string `json:"container"`
+	ContainerPort int64  `json:"container_port"`
+	HostPort      int64  `json:"host_port"`
+	Protocol      string `json:"protocol"`
+}
+
 func init() {
 	RootCmd.AddCommand(portsCmd)
+	readOnly(portsCmd)
 }
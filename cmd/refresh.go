@@ -17,7 +17,7 @@ var refreshCmd = &cobra.Command{
 		if len(args) < 2 {
 			return fmt.Errorf("Not enough arguments")
 		}
-		fmt.Printf("forcing new deployment for %s/%s", cluster, service)
+		ecs.PrintAction("forcing new deployment for %s/%s", "would force new deployment for %s/%s", cluster, service)
 		return ecs.CreateRefreshDeployment(cluster, service)
 	},
 }
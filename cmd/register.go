@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+var registerFile string
+var registerVars []string
+
+// registerCmd registers a task definition from a local file
+var registerCmd = &cobra.Command{
+	Use:   "register -f [taskdef.json] [cluster] [service]",
+	Short: "Register a task definition from a local JSON file, optionally deploying it",
+	Long: `Render a task definition file with --var values, check it, and register it as
+a new revision. Given a cluster and service, the revision is then deployed to
+the service.
+
+The file is a RegisterTaskDefinition request (as "ecsy export --task-definition"
+or "aws ecs describe-task-definition" print it). Values are filled in as a Go
+template ({{ .image }}) or by substitution (${image}); a variable with no value
+is an error, and $${NAME} leaves a literal ${NAME}. A field the request does
+not take is an error too, except for the read-only ones describe-task-definition
+adds (revision, status...).
+
+Before registering, the task definition must have an essential container,
+memory for every container (or the task), and no two containers on the same
+host port.
+
+Example:
+    ecsy register -f taskdef.json --var image=example/api:1.4.2 --var env=prod
+    ecsy register -f taskdef.json --var image=example/api:1.4.2 my-cluster api --wait
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if registerFile == "" {
+			failOnError(fmt.Errorf("please give a task definition file with -f"), "bad arguments")
+		}
+		vars := make(map[string]string, len(registerVars))
+		for _, v := range registerVars {
+			parts := strings.SplitN(v, "=", 2)
+			if len(parts) != 2 {
+				failOnError(fmt.Errorf("expected name=value, got %q", v), "bad arguments")
+			}
+			vars[parts[0]] = parts[1]
+		}
+		text, err := ioutil.ReadFile(registerFile)
+		failOnError(err, "Error reading task definition")
+		rendered, err := ecs.RenderTaskDefinition(string(text), vars)
+		failOnError(err, "Error rendering task definition")
+		input, err := ecs.ParseTaskDefinitionJSON([]byte(rendered))
+		failOnError(err, "Error parsing task definition")
+		failOnError(ecs.ValidateTaskDefinition(input), registerFile)
+		var cluster, service string
+		if len(args) > 0 {
+			cluster, service = ServiceChooser(args)
+		}
+		task, err := ecs.RegisterTaskDefinition(input)
+		failOnError(err, "Error registering task definition")
+		ecs.PrintAction("Registered task definition %s", "Would register task definition %s", path.Base(*task.TaskDefinitionArn))
+		if service == "" {
+			return
+		}
+		svc, err := deployTaskDefinition(cluster, service, task)
+		failOnError(err, "updating service task")
+		printServiceUpdated(svc, task)
+		failOnError(waitForDeployment(cluster, service, task), "Deployment failed")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(registerCmd)
+	registerCmd.Flags().StringVarP(&registerFile, "file", "f", "", "Task definition JSON file")
+	registerCmd.Flags().StringArrayVar(&registerVars, "var", nil, "Template variable as name=value, may be repeated")
+	addWaitFlags(registerCmd)
+	registerCmd.Flags().BoolVar(&rollbackOnFailure, "rollback-on-failure", false, "Roll back to the previous task definition if the deployment fails (implies --wait)")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/oberd/ecsy/config"
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+var rollbackRevision int64
+
+// rollbackCmd redeploys the previous task definition of a service
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [cluster] [service]",
+	Short: "Redeploy the previously deployed task definition of a service",
+	Long: `Finds the task definition that was deployed before the current one and
+deploys it again. The previous revision is taken from an in-progress deployment
+if there is one, otherwise from the local deploy history (~/.ecsy_history.yaml)
+recorded by ecsy deploy, env set/edit and set-memory.
+
+Use --revision to roll back to a specific revision of the service's family instead.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, service := ServiceChooser(args)
+		svc, err := ecs.FindService(cluster, service)
+		failOnError(err, "Error finding service")
+		var previous string
+		if rollbackRevision > 0 {
+			def, err := ecs.GetTaskDefinition(*svc.TaskDefinition)
+			failOnError(err, "Error finding current task definition")
+			previous = fmt.Sprintf("%s:%d", *def.Family, rollbackRevision)
+		} else {
+			previous = ecs.FindPreviousDeployment(svc)
+		}
+		if previous == "" {
+			history, err := config.ReadDeployHistory(config.HistoryFilePath())
+			failOnError(err, "Error reading deploy history")
+			previous, _ = history.PreviousDeployment(cluster, service, *svc.TaskDefinition)
+		}
+		if previous == "" {
+			failOnError(fmt.Errorf("no previous task definition found for %s, try --revision", service), "Unable to roll back")
+		}
+		failOnError(rollbackService(cluster, service, previous), "Rollback failed")
+	},
+}
+
+// rollbackService deploys a previous task definition (by arn or family:revision)
+func rollbackService(cluster, service, previous string) error {
+	def, err := ecs.GetTaskDefinition(previous)
+	if err != nil {
+		return err
+	}
+	ecs.PrintAction("==> Rolling back %s to %s", "==> Would roll back %s to %s", service, path.Base(*def.TaskDefinitionArn))
+	svc, err := deployAndRecord(cluster, service, def, config.RecordRollback)
+	if err != nil {
+		return err
+	}
+	printServiceUpdated(svc, def)
+	return waitForDeployment(cluster, service, def)
+}
+
+func init() {
+	RootCmd.AddCommand(rollbackCmd)
+	rollbackCmd.Flags().Int64VarP(&rollbackRevision, "revision", "r", 0, "revision of the service's task family to roll back to")
+	addWaitFlags(rollbackCmd)
+}
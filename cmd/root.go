@@ -4,11 +4,21 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/oberd/ecsy/config"
+	"github.com/oberd/ecsy/ecs"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
+var awsProfile string
+var awsRegion string
+var awsRoleArn string
+var noColor bool
+var dryRun bool
+
+// activeSessionOptions are the options the default ecs client was built with
+var activeSessionOptions ecs.SessionOptions
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
@@ -18,6 +28,13 @@ var RootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(outputFormat); err != nil {
+			return err
+		}
+		looseNames = cmd.Annotations[annotationReadOnly] == "true"
+		return configureClient(flagSessionOptions())
+	},
 }
 
 // Execute adds all child commands to the root command sets flags appropriately.
@@ -37,6 +54,12 @@ func init() {
 	// will be global for your application.
 
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.ecsy.yaml)")
+	RootCmd.PersistentFlags().StringVar(&awsProfile, "profile", "", "AWS shared config profile to use (default is $AWS_PROFILE)")
+	RootCmd.PersistentFlags().StringVar(&awsRegion, "region", "", "AWS region to use (default is $AWS_REGION, the profile region, or us-west-2)")
+	RootCmd.PersistentFlags().StringVar(&awsRoleArn, "role-arn", "", "IAM role to assume for all AWS calls")
+	RootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output")
+	RootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputTable, "output format of read commands (table|json|yaml)")
+	RootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "show the AWS write requests a command would make, as a diff against the current state, without making them")
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 }
@@ -56,3 +79,54 @@ func initConfig() {
 		// fmt.Println("Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// configureClient points the default ecs client at a new set of
+// session options, if they differ from the active ones (or the client
+// does not plan writes yet under --dry-run)
+func configureClient(opts ecs.SessionOptions) error {
+	if opts == activeSessionOptions && ecs.DryRun() == dryRun {
+		return nil
+	}
+	client, err := ecs.NewClientWithOptions(opts)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		client.EnableDryRun(printPlannedRequest)
+	}
+	ecs.SetDefaultClient(client)
+	activeSessionOptions = opts
+	return nil
+}
+
+// useCluster applies the profile, region and role configured for a cluster
+// in the config file. Flags given on the command line take precedence.
+func useCluster(cluster string) {
+	failOnError(configureClient(clusterSessionOptions(cluster)), "Error configuring AWS session")
+}
+
+// clusterSessionOptions are the session options of a cluster: its settings
+// in the config file, overridden by the flags given on the command line
+func clusterSessionOptions(cluster string) ecs.SessionOptions {
+	settings := config.GetClusterSettings(cluster)
+	// start from the flags rather than the active options, so commands
+	// spanning several clusters do not carry one cluster's settings over
+	// to the next
+	opts := flagSessionOptions()
+	flags := RootCmd.PersistentFlags()
+	if settings.Profile != "" && !flags.Changed("profile") {
+		opts.Profile = settings.Profile
+	}
+	if settings.Region != "" && !flags.Changed("region") {
+		opts.Region = settings.Region
+	}
+	if settings.RoleArn != "" && !flags.Changed("role-arn") {
+		opts.RoleArn = settings.RoleArn
+	}
+	return opts
+}
+
+// flagSessionOptions are the session options given on the command line
+func flagSessionOptions() ecs.SessionOptions {
+	return ecs.SessionOptions{Profile: awsProfile, Region: awsRegion, RoleArn: awsRoleArn}
+}
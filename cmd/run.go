@@ -29,9 +29,11 @@ import (
 var runCmd = &cobra.Command{
 	Use:   "run [cluster]",
 	Short: "Run an ssh command on all the servers in a cluster",
-	Long:  `Run an ssh command on all the servers in a cluster`,
+	Long: `Run an ssh command on all the servers in a cluster
+
+With --dry-run, the servers are listed but the command is not run.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cluster := args[0]
+		cluster := ClusterChooser(args)
 		clusterKey := config.GetClusterKey(cluster)
 		commandParts := args[1:]
 		command := strings.Join(commandParts, " ")
@@ -40,6 +42,10 @@ var runCmd = &cobra.Command{
 			log.Fatalf("Problem retrieving servers: %v", err)
 		}
 		for _, instance := range instances {
+			if dryRun {
+				fmt.Printf("Would run %q on %s\n", command, instance)
+				continue
+			}
 			client := ssh.NewClient(ssh.ClientConfiguration{
 				Host:           instance,
 				User:           "ec2-user",
@@ -16,16 +16,36 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/oberd/ecsy/ecs"
 	"github.com/spf13/cobra"
 )
 
 var runTaskWait = false
 var taskDefinitionSource = "newest"
+var runTaskLaunchType string
+var runTaskCapacityProviders []string
+var runTaskPlatformVersion string
+var runTaskSubnets []string
+var runTaskSecurityGroups []string
+var runTaskAssignPublicIP string
+var runTaskContainer string
+var runTaskEnv []string
+var runTaskEnvFile string
+var runTaskCPU int64
+var runTaskMemory int64
+var runTaskTaskCPU string
+var runTaskTaskMemory string
+var runTaskRole string
+var runTaskCount int64
 
 // runTaskCmd represents the runTask command
 var runTaskCmd = &cobra.Command{
@@ -41,6 +61,20 @@ For example, to run an artisan or cron command
 Example:
 
 ecsy run-task medamine indexer-worker 'bin/snapshot --configuration assets/medamine_configurations/knee_replacement_revision_medamine.json'
+
+The task runs the same way the service runs its tasks: with its launch type
+or capacity provider strategy, and (for awsvpc / Fargate services) in its
+subnets and security groups. Each of these can be overridden with flags.
+
+Environment, sizing and the task role can be overridden too, for example to run
+a migration with more memory in three parallel tasks:
+
+ecsy run-task my-cluster api --container api -e DRY_RUN=false --memory 2048 --count 3 'bin/migrate'
+
+--cpu and --memory size the container only. Task level sizing (which Fargate
+tasks have) is left as is unless --task-cpu or --task-memory is given:
+
+ecsy run-task my-cluster api --memory 2048 --task-memory 2048 'bin/migrate'
 `,
 	FParseErrWhitelist: cobra.FParseErrWhitelist{
 		UnknownFlags: true,
@@ -52,8 +86,16 @@ ecsy run-task medamine indexer-worker 'bin/snapshot --configuration assets/medam
 		}
 		command := args[2]
 		def, err := ecs.LocateTaskDef(cluster, service, taskDefinitionSource)
-		output, err := ecs.RunTaskWithCommand(cluster, def, command)
+		failOnError(err, "Error finding task definition")
+		opts, err := runTaskOptions(cluster, service)
+		failOnError(err, "Error configuring task")
+		opts.Command = command
+		failOnError(applyRunTaskOverrides(&opts), "Error configuring task overrides")
+		output, err := ecs.RunTask(cluster, def, opts)
 		if err != nil {
+			if output != nil {
+				printCreatedTasks(cluster, output.Tasks)
+			}
 			log.Fatalf("%v\n", err)
 			return
 		}
@@ -63,46 +105,195 @@ ecsy run-task medamine indexer-worker 'bin/snapshot --configuration assets/medam
 			}
 			return
 		}
-		taskID := ecs.GetTaskIDFromArn(*output.Tasks[0].TaskArn)
-		detailsLink := fmt.Sprintf(
-			"https://us-west-2.console.aws.amazon.com/ecs/home?region=us-west-2#/clusters/%s/tasks/%s/details",
-			cluster,
-			taskID,
-		)
-		fmt.Printf("=> Created Task: %s\n", detailsLink)
-		if runTaskWait {
-			var exitCode *int64
-			fmt.Printf("==> Waiting for task to complete...\n")
-			for exitCode == nil {
-				task, err := ecs.GetTask(cluster, *output.Tasks[0].TaskArn)
-				if err != nil {
-					log.Fatalln("could not find task", err)
-				}
-				time.Sleep(time.Second * 2)
-				exitCode, err = ecs.TaskExitCode(task)
+		printCreatedTasks(cluster, output.Tasks)
+		if runTaskWait && !dryRun {
+			taskArns := make([]string, len(output.Tasks))
+			for i, task := range output.Tasks {
+				taskArns[i] = *task.TaskArn
 			}
+			exitCode, err := waitForTasks(cluster, def, runTaskContainer, taskArns)
 			if err != nil {
 				log.Fatalf("%v\n", err)
-				return
-			}
-			fmt.Printf("==> Retrieving Container Log Output\n")
-			err = ecs.GetTaskLogs(def, taskID)
-			if err != nil {
-				log.Printf("Unable to read logs: %v\n", err)
 			}
-			fmt.Printf("==> End Container Log Output\n")
-			if *exitCode > 0 {
-				log.Printf("==> Received error code from container: %v", *exitCode)
+			if exitCode > 0 {
+				log.Printf("==> Received error code from container: %v", exitCode)
 			} else {
 				fmt.Printf("=> Tasks Completed Successfully\n")
 			}
-			os.Exit(int(*exitCode))
+			os.Exit(int(exitCode))
 		}
 	},
 }
 
+func printCreatedTasks(cluster string, tasks []*awsecs.Task) {
+	for _, task := range tasks {
+		detailsLink := ecs.BuildConsoleURLForTask(cluster, ecs.GetTaskIDFromArn(*task.TaskArn))
+		fmt.Printf("=> Created Task: %s\n", detailsLink)
+	}
+}
+
+// waitForTasks streams the logs of a container of each task while polling
+// the tasks until they stop, and returns the highest exit code
+func waitForTasks(cluster string, def *awsecs.TaskDefinition, containerName string, taskArns []string) (int64, error) {
+	container, err := ecs.FindContainer(def, containerName)
+	if err != nil {
+		return 1, err
+	}
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	for _, taskArn := range taskArns {
+		taskID := ecs.GetTaskIDFromArn(taskArn)
+		stream, err := ecs.ContainerLogStream(container, taskID)
+		if err != nil {
+			log.Printf("Unable to stream logs: %v\n", err)
+			break
+		}
+		prefix := ""
+		if len(taskArns) > 1 {
+			prefix = taskID + " "
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := ecs.TailLogStream(stream, stop, func(event *cloudwatchlogs.OutputLogEvent) {
+				fmt.Println(prefix + ecs.FormatLogEvent(event))
+			})
+			if err != nil {
+				log.Printf("Unable to read logs of task %s: %v\n", stream.TaskID, err)
+			}
+		}()
+	}
+	fmt.Printf("==> Waiting for tasks to complete, streaming container log output...\n")
+	var worstExitCode int64
+	for _, taskArn := range taskArns {
+		exitCode, err := waitForTaskExit(cluster, taskArn)
+		if err != nil {
+			close(stop)
+			wg.Wait()
+			return 1, err
+		}
+		if exitCode > worstExitCode {
+			worstExitCode = exitCode
+		}
+	}
+	close(stop)
+	wg.Wait()
+	fmt.Printf("==> End Container Log Output\n")
+	return worstExitCode, nil
+}
+
+// waitForTaskExit polls a task until it stops, returning its exit code
+func waitForTaskExit(cluster, taskArn string) (int64, error) {
+	var exitCode *int64
+	for exitCode == nil {
+		task, err := ecs.GetTask(cluster, taskArn)
+		if err != nil {
+			return 1, fmt.Errorf("could not find task: %v", err)
+		}
+		time.Sleep(time.Second * 2)
+		exitCode, err = ecs.TaskExitCode(task)
+		if err != nil {
+			return 1, err
+		}
+	}
+	return *exitCode, nil
+}
+
+// runTaskOptions inherits the placement and networking of a service,
+// overridden by any flags given
+func runTaskOptions(cluster, service string) (ecs.RunTaskOptions, error) {
+	svc, err := ecs.FindService(cluster, service)
+	if err != nil {
+		return ecs.RunTaskOptions{}, err
+	}
+	opts := ecs.RunTaskOptionsFromService(svc)
+	if runTaskLaunchType != "" {
+		opts.LaunchType = runTaskLaunchType
+		opts.CapacityProviderStrategy = nil
+	}
+	if len(runTaskCapacityProviders) > 0 {
+		opts.LaunchType = ""
+		opts.CapacityProviderStrategy = nil
+		for _, value := range runTaskCapacityProviders {
+			item, err := ecs.ParseCapacityProvider(value)
+			if err != nil {
+				return opts, err
+			}
+			opts.CapacityProviderStrategy = append(opts.CapacityProviderStrategy, item)
+		}
+	}
+	if runTaskPlatformVersion != "" {
+		opts.PlatformVersion = runTaskPlatformVersion
+	}
+	if len(runTaskSubnets) > 0 {
+		opts.Subnets = runTaskSubnets
+	}
+	if len(runTaskSecurityGroups) > 0 {
+		opts.SecurityGroups = runTaskSecurityGroups
+	}
+	if runTaskAssignPublicIP != "" {
+		opts.AssignPublicIP = strings.ToUpper(runTaskAssignPublicIP)
+	}
+	return opts, nil
+}
+
+// applyRunTaskOverrides adds the container, environment, sizing, role and
+// count flags to the task options
+func applyRunTaskOverrides(opts *ecs.RunTaskOptions) error {
+	opts.Container = runTaskContainer
+	opts.Cpu = runTaskCPU
+	opts.Memory = runTaskMemory
+	opts.TaskCpu = runTaskTaskCPU
+	opts.TaskMemory = runTaskTaskMemory
+	opts.Count = runTaskCount
+	if runTaskEnvFile != "" {
+		content, err := ioutil.ReadFile(runTaskEnvFile)
+		if err != nil {
+			return err
+		}
+		pairs, err := ecs.StringToKeyPairs(string(content))
+		if err != nil {
+			return fmt.Errorf("problem parsing %s: %v", runTaskEnvFile, err)
+		}
+		opts.Environment = append(opts.Environment, pairs...)
+	}
+	if len(runTaskEnv) > 0 {
+		pairs, err := ecs.AssignmentsToKeyPairs(runTaskEnv)
+		if err != nil {
+			return err
+		}
+		opts.Environment = append(opts.Environment, pairs...)
+	}
+	if runTaskRole != "" {
+		opts.TaskRoleArn = runTaskRole
+		if !strings.HasPrefix(runTaskRole, "arn:") {
+			role, err := ecs.FindRoleByName(runTaskRole)
+			if err != nil {
+				return err
+			}
+			opts.TaskRoleArn = *role.Arn
+		}
+	}
+	return nil
+}
+
 func init() {
 	RootCmd.AddCommand(runTaskCmd)
 	runTaskCmd.Flags().StringVarP(&taskDefinitionSource, "task-definition-source", "s", "newest", `locator for task definition ("newest" will use the newest in the family of the service, "current" will use the currently deployed)`)
 	runTaskCmd.Flags().BoolVarP(&runTaskWait, "wait", "w", false, "Wait for completion of task")
+	runTaskCmd.Flags().StringVar(&runTaskLaunchType, "launch-type", "", "launch type (EC2|FARGATE|EXTERNAL), defaults to the service's")
+	runTaskCmd.Flags().StringSliceVar(&runTaskCapacityProviders, "capacity-provider", nil, "capacity provider strategy item as provider[:weight[:base]], may be repeated (defaults to the service's)")
+	runTaskCmd.Flags().StringVar(&runTaskPlatformVersion, "platform-version", "", "Fargate platform version, defaults to the service's")
+	runTaskCmd.Flags().StringSliceVar(&runTaskSubnets, "subnets", nil, "subnets for awsvpc networking, defaults to the service's")
+	runTaskCmd.Flags().StringSliceVar(&runTaskSecurityGroups, "security-groups", nil, "security groups for awsvpc networking, defaults to the service's")
+	runTaskCmd.Flags().StringVar(&runTaskContainer, "container", "", "name of the container to override (defaults to the first container)")
+	runTaskCmd.Flags().StringArrayVarP(&runTaskEnv, "env", "e", nil, "environment variable KEY=VAL to add or override, may be repeated")
+	runTaskCmd.Flags().StringVar(&runTaskEnvFile, "env-file", "", "file of KEY=VAL lines to add to or override the environment")
+	runTaskCmd.Flags().Int64Var(&runTaskCPU, "cpu", 0, "cpu units override of the container")
+	runTaskCmd.Flags().Int64Var(&runTaskMemory, "memory", 0, "memory (MiB) override of the container")
+	runTaskCmd.Flags().StringVar(&runTaskTaskCPU, "task-cpu", "", "task level cpu override (for Fargate, raise it with --cpu)")
+	runTaskCmd.Flags().StringVar(&runTaskTaskMemory, "task-memory", "", "task level memory (MiB) override (for Fargate, raise it with --memory)")
+	runTaskCmd.Flags().StringVar(&runTaskRole, "task-role", "", "task role override, by name or arn")
+	runTaskCmd.Flags().Int64Var(&runTaskCount, "count", 1, "number of tasks to run in parallel")
+	runTaskCmd.Flags().StringVar(&runTaskAssignPublicIP, "assign-public-ip", "", "assign a public ip for awsvpc networking (ENABLED|DISABLED), defaults to the service's")
 }
@@ -29,7 +29,7 @@ var scaleCmd = &cobra.Command{
 		}
 		_, err = ecs.ScaleService(cluster, service, desiredCount)
 		failOnError(err, "Unable to set service scale")
-		fmt.Println("Successfully scaled service")
+		ecs.PrintAction("Successfully scaled service to %d", "Would scale service to %d", desiredCount)
 		printServiceStatus(cluster, service)
 	},
 }
@@ -25,8 +25,7 @@ in there through this command using a menu system rather than figuring it out
 yourself! Yay. I guess.
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		cluster := args[0]
-		service := args[1]
+		cluster, service := ServiceChooser(args)
 		err := ecs.ValidateCluster(cluster)
 		if err != nil {
 			fmt.Printf("%v\n", err)
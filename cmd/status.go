@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/oberd/ecsy/ecs"
 	"github.com/spf13/cobra"
 )
@@ -13,28 +14,74 @@ import (
 var prevCount int
 var poll bool
 
+// serviceStatusOutput is the --output json|yaml schema of status
+type serviceStatusOutput struct {
+	Cluster        string             `json:"cluster"`
+	Service        string             `json:"service"`
+	TaskDefinition string             `json:"task_definition"`
+	Status         string             `json:"status"`
+	DesiredCount   int64              `json:"desired_count"`
+	PendingCount   int64              `json:"pending_count"`
+	RunningCount   int64              `json:"running_count"`
+	CreatedAt      time.Time          `json:"created_at"`
+	Instances      []string           `json:"instances"`
+	Deployments    []deploymentOutput `json:"deployments"`
+}
+
+type deploymentOutput struct {
+	ID             string    `json:"id"`
+	TaskDefinition string    `json:"task_definition"`
+	Status         string    `json:"status"`
+	RolloutState   string    `json:"rollout_state"`
+	DesiredCount   int64     `json:"desired_count"`
+	PendingCount   int64     `json:"pending_count"`
+	RunningCount   int64     `json:"running_count"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 func printServiceStatus(cluster, service string) bool {
 	serviceObj, err := ecs.FindService(cluster, service)
-	fmt.Printf("Cluster:\t\t%s\n", cluster)
-	fmt.Printf("Service:\t\t%s\n", service)
-	fmt.Printf("Task Definition:\t%s\n", path.Base(*serviceObj.TaskDefinition))
-	if *serviceObj.RunningCount == 0 {
-		fmt.Printf("%s %s (%d Desired, %d Pending, %d Running) %v\n",
-			path.Base(*serviceObj.TaskDefinition), *serviceObj.Status,
-			*serviceObj.DesiredCount, *serviceObj.PendingCount,
-			*serviceObj.RunningCount, *serviceObj.CreatedAt)
-		return false
-	}
 	failOnError(err, fmt.Sprintf("problem finding service: %s %s\n", cluster, service))
-	instances, err := ecs.GetContainerInstances(cluster, service)
-	failOnError(err, "")
-	fmt.Printf("Cluster:\t\t%s\n", cluster)
-	fmt.Printf("Service:\t\t%s\n", service)
-	fmt.Printf("Task Definition:\t%s\n", path.Base(*serviceObj.TaskDefinition))
-	fmt.Printf("Instances:\n\t%s\n", strings.Join(instances, "\n\t"))
-	fmt.Println("Deployments:")
+	status := serviceStatusOutput{
+		Cluster:        cluster,
+		Service:        service,
+		TaskDefinition: path.Base(*serviceObj.TaskDefinition),
+		Status:         aws.StringValue(serviceObj.Status),
+		DesiredCount:   aws.Int64Value(serviceObj.DesiredCount),
+		PendingCount:   aws.Int64Value(serviceObj.PendingCount),
+		RunningCount:   aws.Int64Value(serviceObj.RunningCount),
+		CreatedAt:      aws.TimeValue(serviceObj.CreatedAt),
+		Instances:      make([]string, 0),
+		Deployments:    make([]deploymentOutput, 0, len(serviceObj.Deployments)),
+	}
 	for _, d := range serviceObj.Deployments {
-		fmt.Printf("%s %s (%d Desired, %d Pending, %d Running) %v\n", path.Base(*d.TaskDefinition), *d.Status, *d.DesiredCount, *d.PendingCount, *d.RunningCount, *d.CreatedAt)
+		status.Deployments = append(status.Deployments, deploymentOutput{
+			ID:             aws.StringValue(d.Id),
+			TaskDefinition: path.Base(aws.StringValue(d.TaskDefinition)),
+			Status:         aws.StringValue(d.Status),
+			RolloutState:   aws.StringValue(d.RolloutState),
+			DesiredCount:   aws.Int64Value(d.DesiredCount),
+			PendingCount:   aws.Int64Value(d.PendingCount),
+			RunningCount:   aws.Int64Value(d.RunningCount),
+			CreatedAt:      aws.TimeValue(d.CreatedAt),
+		})
+	}
+	if status.RunningCount > 0 {
+		status.Instances, err = ecs.GetContainerInstances(cluster, service)
+		failOnError(err, "")
+	}
+	switch {
+	case structuredOutput() && poll:
+		record, err := formatOutputRecord(status)
+		failOnError(err, "")
+		fmt.Println(record)
+	case structuredOutput():
+		failOnError(printOutput(status), "")
+	default:
+		printServiceStatusTable(status)
+	}
+	if status.RunningCount == 0 {
+		return false
 	}
 	currCount := len(serviceObj.Deployments)
 	hasChanged := prevCount != 1000 && currCount != prevCount
@@ -42,6 +89,24 @@ func printServiceStatus(cluster, service string) bool {
 	return !hasChanged
 }
 
+func printServiceStatusTable(status serviceStatusOutput) {
+	fmt.Printf("Cluster:\t\t%s\n", status.Cluster)
+	fmt.Printf("Service:\t\t%s\n", status.Service)
+	fmt.Printf("Task Definition:\t%s\n", status.TaskDefinition)
+	if status.RunningCount == 0 {
+		fmt.Printf("%s %s (%d Desired, %d Pending, %d Running) %v\n",
+			status.TaskDefinition, status.Status,
+			status.DesiredCount, status.PendingCount,
+			status.RunningCount, status.CreatedAt)
+		return
+	}
+	fmt.Printf("Instances:\n\t%s\n", strings.Join(status.Instances, "\n\t"))
+	fmt.Println("Deployments:")
+	for _, d := range status.Deployments {
+		fmt.Printf("%s %s (%d Desired, %d Pending, %d Running) %v\n", d.TaskDefinition, d.Status, d.DesiredCount, d.PendingCount, d.RunningCount, d.CreatedAt)
+	}
+}
+
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
 	Use:   "status",
@@ -60,6 +125,7 @@ var statusCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(statusCmd)
+	readOnly(statusCmd)
 
 	// Here you will define your flags and configuration settings.
 
@@ -48,6 +48,10 @@ var updateCmd = &cobra.Command{
 			version = args[1]
 		}
 		url := fmt.Sprintf("https://github.com/oberd/ecsy/releases/download/%s/ecsy-%s-%s", version, version, suffix)
+		if dryRun {
+			fmt.Printf("Would replace %s with version %s from %s\n", executable, version, url)
+			return
+		}
 		tmp, err := ioutil.TempFile("", "ecsy")
 		defer os.Remove(tmp.Name())
 		failOnError(err, "Problem allocating temp file")
@@ -29,10 +29,10 @@ var updateAgentCmd = &cobra.Command{
 	Short: "Update the Container Instance Agents",
 	Long:  `This will grab a list of the container instances and upgrade their agents (performs a cluster wide upgrade)`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) != 1 {
-			return fmt.Errorf("Cluster not specified")
+		if len(args) > 1 {
+			return fmt.Errorf("Too many arguments, expected [cluster-name]")
 		}
-		cluster := args[0]
+		cluster := ClusterChooser(args)
 		instances, err := ecs.GetClusterInstances(cluster)
 		if err != nil {
 			log.Fatalf("error retrieving instances: %v", err)
@@ -20,7 +20,8 @@ set memory properties on the essential container of a service
     for more information about memory reservations
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		def, err := ecs.LocateTaskDef(args[0], args[1], "newest")
+		cluster, serviceName := ServiceChooser(args)
+		def, err := ecs.LocateTaskDef(cluster, serviceName, "newest")
 		if err != nil {
 			return err
 		}
@@ -37,12 +38,12 @@ set memory properties on the essential container of a service
 			return err
 		}
 		fmt.Println("configured new task definition: ", newTaskDef)
-		service, err := ecs.DeployTaskToService(args[0], args[1], newTaskDef)
+		service, err := deployTaskDefinition(cluster, serviceName, newTaskDef)
 		if err != nil {
 			return err
 		}
 		fmt.Printf("deployed new memory to %s %s\n", *service.ClusterArn, *service.ServiceName)
-		return nil
+		return waitForDeployment(cluster, serviceName, newTaskDef)
 	},
 }
 
@@ -50,4 +51,5 @@ func init() {
 	RootCmd.AddCommand(setMemoryCmd)
 	setMemoryCmd.Flags().Int64VarP(&memoryFlag, "memory", "m", -1, `"memory" for a task`)
 	setMemoryCmd.Flags().Int64VarP(&memoryReservationFlag, "memory-reservation", "r", -1, `"memoryReservation" for a task`)
+	addWaitFlags(setMemoryCmd)
 }
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/oberd/ecsy/config"
+	"github.com/oberd/ecsy/ecs"
+	"github.com/spf13/cobra"
+)
+
+var deployWait bool
+var deployWaitTimeout time.Duration
+var deployWaitMaxStopped int
+var rollbackOnFailure bool
+
+// addWaitFlags adds the --wait family of flags to a deploying command
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&deployWait, "wait", "w", false, "Wait for the deployment to reach steady state, exiting non-zero if it fails")
+	cmd.Flags().DurationVar(&deployWaitTimeout, "timeout", ecs.DefaultWaitOptions.Timeout, "Maximum time to wait for steady state (with --wait)")
+	cmd.Flags().IntVar(&deployWaitMaxStopped, "max-stopped-tasks", ecs.DefaultWaitOptions.MaxStoppedTasks, "Number of stopped tasks after which the deployment is considered failed (with --wait)")
+}
+
+// deployTaskDefinition deploys a task definition to a service, remembering
+// the previously deployed one in the local deploy history
+func deployTaskDefinition(cluster, service string, task *awsecs.TaskDefinition) (*awsecs.Service, error) {
+	return deployAndRecord(cluster, service, task, config.RecordDeployment)
+}
+
+// deployAndRecord deploys a task definition to a service, and records the
+// change in the local deploy history with record
+func deployAndRecord(cluster, service string, task *awsecs.TaskDefinition, record func(path, cluster, service, from, to string) error) (*awsecs.Service, error) {
+	current, err := ecs.FindService(cluster, service)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := ecs.DeployTaskToService(cluster, service, task)
+	if err != nil {
+		return nil, err
+	}
+	if *current.TaskDefinition != *task.TaskDefinitionArn && !dryRun {
+		err = record(config.HistoryFilePath(), cluster, service, *current.TaskDefinition, *task.TaskDefinitionArn)
+		if err != nil {
+			fmt.Printf("Unable to record deploy history: %v\n", err)
+		}
+	}
+	return svc, nil
+}
+
+// printServiceUpdated reports a deployment started by deployTaskDefinition
+func printServiceUpdated(svc *awsecs.Service, task *awsecs.TaskDefinition) {
+	ecs.PrintAction("updated service %s with task definition %s (deploying to %d containers)",
+		"would update service %s with task definition %s (deploying to %d containers)",
+		*svc.ServiceArn, *task.TaskDefinitionArn, *svc.DesiredCount)
+}
+
+// waitForDeployment follows a deployment if --wait (or --rollback-on-failure)
+// was given, rolling back to the previous task definition on failure if asked to
+func waitForDeployment(cluster, service string, task *awsecs.TaskDefinition) error {
+	if !deployWait && !rollbackOnFailure {
+		return nil
+	}
+	if dryRun {
+		fmt.Println("==> Dry run, not waiting for the deployment")
+		return nil
+	}
+	fmt.Printf("==> Waiting for %s:%d to reach steady state...\n", *task.Family, *task.Revision)
+	err := ecs.WaitForDeployment(cluster, service, *task.TaskDefinitionArn, ecs.WaitOptions{
+		Timeout:         deployWaitTimeout,
+		MaxStoppedTasks: deployWaitMaxStopped,
+	})
+	if err == nil {
+		fmt.Printf("=> Service %s reached steady state\n", service)
+		return nil
+	}
+	if !rollbackOnFailure {
+		return err
+	}
+	fmt.Printf("==> Deployment failed: %v\n", err)
+	history, historyErr := config.ReadDeployHistory(config.HistoryFilePath())
+	if historyErr != nil {
+		return fmt.Errorf("%v (unable to read deploy history for rollback: %v)", err, historyErr)
+	}
+	previous, ok := history.PreviousDeployment(cluster, service, *task.TaskDefinitionArn)
+	if !ok {
+		return fmt.Errorf("%v (no previous task definition found to roll back to)", err)
+	}
+	rollbackOnFailure = false
+	deployWait = true
+	if rollbackErr := rollbackService(cluster, service, previous); rollbackErr != nil {
+		return fmt.Errorf("%v (rollback failed: %v)", err, rollbackErr)
+	}
+	return fmt.Errorf("%v (rolled back to %s)", err, path.Base(previous))
+}
@@ -20,9 +20,17 @@ type FilePath string
 // Keys is a map of cluster names
 type Keys map[ClusterName]FilePath
 
+// ClusterSettings holds per cluster AWS connection settings
+type ClusterSettings struct {
+	Profile string `yaml:"profile,omitempty"`
+	Region  string `yaml:"region,omitempty"`
+	RoleArn string `yaml:"role_arn,omitempty"`
+}
+
 // Config represents global application configuration
 type Config struct {
-	Keys Keys `yaml:"keys"`
+	Keys     Keys                            `yaml:"keys"`
+	Clusters map[ClusterName]ClusterSettings `yaml:"clusters,omitempty"`
 }
 
 // Storage is a mechanism for storing ECS Commander Config!
@@ -126,3 +134,39 @@ func GetClusterKey(cluster string) string {
 	}
 	return out
 }
+
+// ReadClusterSettings implements a cluster settings reader for yaml files
+func (yamlFile *YAMLFile) ReadClusterSettings() (map[ClusterName]ClusterSettings, error) {
+	config := &Config{}
+	err := yaml.Unmarshal(yamlFile.content, &config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Clusters == nil {
+		config.Clusters = make(map[ClusterName]ClusterSettings)
+	}
+	return config.Clusters, nil
+}
+
+// GetClusterSettings returns the configured AWS settings for a cluster,
+// which are empty if the cluster has none
+func GetClusterSettings(cluster string) ClusterSettings {
+	all, err := GetYAMLConfig().ReadClusterSettings()
+	if err != nil {
+		return ClusterSettings{}
+	}
+	return all[ClusterName(cluster)]
+}
+
+// ClusterNames lists the clusters that have settings in the config file
+func ClusterNames() []string {
+	all, err := GetYAMLConfig().ReadClusterSettings()
+	if err != nil {
+		return nil
+	}
+	out := make([]string, 0, len(all))
+	for name := range all {
+		out = append(out, string(name))
+	}
+	return out
+}
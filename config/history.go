@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// maxHistoryEntries is the number of deployments kept per service
+const maxHistoryEntries = 20
+
+// Deployment records a task definition change made by ecsy. Rollback marks
+// changes made by rolling back, which are not themselves rolled back to.
+type Deployment struct {
+	From     string    `yaml:"from"`
+	To       string    `yaml:"to"`
+	At       time.Time `yaml:"at"`
+	Rollback bool      `yaml:"rollback,omitempty"`
+}
+
+// DeployHistory is a map of "cluster/service" to deployments, oldest first
+type DeployHistory map[string][]Deployment
+
+func historyKey(cluster, service string) string {
+	return fmt.Sprintf("%s/%s", cluster, service)
+}
+
+// HistoryFilePath returns the location of the local deploy history
+func HistoryFilePath() string {
+	usr, err := user.Current()
+	if err != nil {
+		return ".ecsy_history.yaml"
+	}
+	return fmt.Sprintf("%s/.ecsy_history.yaml", usr.HomeDir)
+}
+
+// ReadDeployHistory reads the local deploy history, which is empty
+// if nothing has been deployed yet
+func ReadDeployHistory(path string) (DeployHistory, error) {
+	history := make(DeployHistory)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(content, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// RecordDeployment appends a deployment to the local deploy history
+func RecordDeployment(path, cluster, service, from, to string) error {
+	return recordDeployment(path, cluster, service, Deployment{From: from, To: to, At: time.Now()})
+}
+
+// RecordRollback appends a rollback to the local deploy history
+func RecordRollback(path, cluster, service, from, to string) error {
+	return recordDeployment(path, cluster, service, Deployment{From: from, To: to, At: time.Now(), Rollback: true})
+}
+
+func recordDeployment(path, cluster, service string, deployment Deployment) error {
+	history, err := ReadDeployHistory(path)
+	if err != nil {
+		return err
+	}
+	key := historyKey(cluster, service)
+	entries := append(history[key], deployment)
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+	}
+	history[key] = entries
+	bytes, err := yaml.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, bytes, 0644)
+}
+
+// PreviousDeployment returns the task definition that was deployed before
+// current, according to the local deploy history. Rollbacks are skipped, so
+// rolling back repeatedly keeps going further back rather than returning
+// to the task definition just rolled back from.
+func (history DeployHistory) PreviousDeployment(cluster, service, current string) (string, bool) {
+	entries := history[historyKey(cluster, service)]
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Rollback {
+			continue
+		}
+		if entries[i].To == current && entries[i].From != "" && entries[i].From != current {
+			return entries[i].From, true
+		}
+	}
+	return "", false
+}
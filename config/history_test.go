@@ -0,0 +1,82 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeployHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecsy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.yaml")
+
+	records := [][2]string{
+		{"api:1", "api:2"},
+		{"api:2", "api:3"},
+		{"api:3", "api:2"},
+	}
+	for _, r := range records {
+		if err := RecordDeployment(path, "qa", "api", r[0], r[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, err := ReadDeployHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		current string
+		want    string
+		found   bool
+	}{
+		{"api:2", "api:3", true},
+		{"api:3", "api:2", true},
+		{"api:1", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.current, func(t *testing.T) {
+			got, found := history.PreviousDeployment("qa", "api", tt.current)
+			if got != tt.want || found != tt.found {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.want, tt.found, got, found)
+			}
+		})
+	}
+	if _, found := history.PreviousDeployment("prod", "api", "api:2"); found {
+		t.Errorf("expected no history for another cluster")
+	}
+}
+
+func TestPreviousDeploymentSkipsRollbacks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecsy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.yaml")
+
+	for _, r := range [][2]string{{"api:1", "api:2"}, {"api:2", "api:3"}, {"api:3", "api:4"}} {
+		if err := RecordDeployment(path, "qa", "api", r[0], r[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current := "api:4"
+	for _, want := range []string{"api:3", "api:2", "api:1"} {
+		history, err := ReadDeployHistory(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous, found := history.PreviousDeployment("qa", "api", current)
+		if !found || previous != want {
+			t.Fatalf("rolling back from %s: expected %s, got (%q, %v)", current, want, previous, found)
+		}
+		if err = RecordRollback(path, "qa", "api", current, previous); err != nil {
+			t.Fatal(err)
+		}
+		current = previous
+	}
+}
@@ -0,0 +1,135 @@
+package ecs
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// Client holds the AWS service clients used by ecsy. Every field is an
+// interface, so tests (or other programs embedding ecsy) can swap in fakes.
+type Client struct {
+	ECS              ecsiface.ECSAPI
+	EC2              ec2iface.EC2API
+	IAM              iamiface.IAMAPI
+	CloudWatchLogs   cloudwatchlogsiface.CloudWatchLogsAPI
+	CloudWatchEvents cloudwatcheventsiface.CloudWatchEventsAPI
+	SSM              ssmiface.SSMAPI
+	SecretsManager   secretsmanageriface.SecretsManagerAPI
+	// Region is the region the service clients talk to, used for
+	// building console urls
+	Region string
+
+	mu          sync.Mutex
+	clusterArns map[string]string
+	planner     *planner
+}
+
+// NewClient creates a client with real AWS service clients built
+// from a session (or any other client.ConfigProvider)
+func NewClient(p client.ConfigProvider, cfgs ...*aws.Config) *Client {
+	return &Client{
+		Region:           aws.StringValue(p.ClientConfig(ecs.EndpointsID, cfgs...).Config.Region),
+		ECS:              ecs.New(p, cfgs...),
+		EC2:              ec2.New(p, cfgs...),
+		IAM:              iam.New(p, cfgs...),
+		CloudWatchLogs:   cloudwatchlogs.New(p, cfgs...),
+		CloudWatchEvents: cloudwatchevents.New(p, cfgs...),
+		SSM:              ssm.New(p, cfgs...),
+		SecretsManager:   secretsmanager.New(p, cfgs...),
+	}
+}
+
+// defaultRegion is used when neither flags, config nor the environment
+// specify a region
+const defaultRegion = "us-west-2"
+
+var defaultClientMu sync.Mutex
+var _defaultClient *Client
+
+// SessionOptions selects the credentials and region used to talk to AWS
+type SessionOptions struct {
+	// Profile is a named profile from the shared config/credentials files
+	Profile string
+	// Region overrides the region from the environment or profile
+	Region string
+	// RoleArn, when set, is assumed via STS on top of the base credentials
+	RoleArn string
+}
+
+// NewSession creates an AWS session honoring shared config profiles,
+// an optional region override and an optional role to assume
+func NewSession(opts SessionOptions) (*session.Session, error) {
+	cfg := aws.Config{}
+	if opts.Region != "" {
+		cfg.Region = aws.String(opts.Region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:                  cfg,
+		Profile:                 opts.Profile,
+		SharedConfigState:       session.SharedConfigEnable,
+		AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create aws session: %v", err)
+	}
+	if aws.StringValue(sess.Config.Region) == "" {
+		sess.Config.Region = aws.String(defaultRegion)
+	}
+	if opts.RoleArn != "" {
+		sess = sess.Copy(&aws.Config{
+			Credentials: stscreds.NewCredentials(sess, opts.RoleArn),
+		})
+	}
+	return sess, nil
+}
+
+// NewClientWithOptions creates a client from session options
+func NewClientWithOptions(opts SessionOptions) (*Client, error) {
+	sess, err := NewSession(opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(sess), nil
+}
+
+// DefaultClient returns the client used by the package level functions,
+// creating it from the environment on first use
+func DefaultClient() *Client {
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	if _defaultClient == nil {
+		client, err := NewClientWithOptions(SessionOptions{})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		_defaultClient = client
+	}
+	return _defaultClient
+}
+
+// SetDefaultClient replaces the client used by the package level functions
+func SetDefaultClient(c *Client) {
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	_defaultClient = c
+}
@@ -0,0 +1,294 @@
+package ecs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+// fakeECS implements the handful of ECS calls the tests need, and
+// panics (via the nil embedded interface) on anything else
+type fakeECS struct {
+	ecsiface.ECSAPI
+	clusters   []string
+	services   map[string]*ecs.Service
+	taskDefs   map[string]*ecs.TaskDefinition
+	tasks      map[string]*ecs.Task
+	tags       map[string][]*ecs.Tag
+	registered []*ecs.RegisterTaskDefinitionInput
+	runs       []*ecs.RunTaskInput
+	updates    []*ecs.UpdateServiceInput
+}
+
+func newFakeECS() *fakeECS {
+	return &fakeECS{
+		services: make(map[string]*ecs.Service),
+		taskDefs: make(map[string]*ecs.TaskDefinition),
+		tasks:    make(map[string]*ecs.Task),
+		tags:     make(map[string][]*ecs.Tag),
+	}
+}
+
+func (f *fakeECS) addService(cluster, service string, def *ecs.TaskDefinition) {
+	f.taskDefs[*def.TaskDefinitionArn] = def
+	f.services[cluster+"/"+service] = &ecs.Service{
+		ClusterArn:     aws.String("arn:aws:ecs:us-west-2:1:cluster/" + cluster),
+		ServiceArn:     aws.String("arn:aws:ecs:us-west-2:1:service/" + cluster + "/" + service),
+		ServiceName:    aws.String(service),
+		TaskDefinition: def.TaskDefinitionArn,
+		DesiredCount:   aws.Int64(1),
+	}
+}
+
+func (f *fakeECS) ListClusters(*ecs.ListClustersInput) (*ecs.ListClustersOutput, error) {
+	out := &ecs.ListClustersOutput{}
+	for _, c := range f.clusters {
+		out.ClusterArns = append(out.ClusterArns, aws.String("arn:aws:ecs:us-west-2:1:cluster/"+c))
+	}
+	return out, nil
+}
+
+func (f *fakeECS) DescribeServices(input *ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error) {
+	out := &ecs.DescribeServicesOutput{}
+	if len(input.Services) > 10 {
+		return nil, fmt.Errorf("InvalidParameterException: at most 10 services can be described")
+	}
+	for _, name := range input.Services {
+		if s, ok := f.services[*input.Cluster+"/"+path.Base(*name)]; ok {
+			out.Services = append(out.Services, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeECS) DescribeTaskDefinition(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+	def, ok := f.taskDefs[*input.TaskDefinition]
+	if !ok {
+		return nil, fmt.Errorf("task definition %s not found", *input.TaskDefinition)
+	}
+	out := &ecs.DescribeTaskDefinitionOutput{TaskDefinition: def}
+	for _, include := range input.Include {
+		if *include == ecs.TaskDefinitionFieldTags {
+			out.Tags = f.tags[*input.TaskDefinition]
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeECS) RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error) {
+	f.registered = append(f.registered, input)
+	revision := int64(len(f.registered) + 100)
+	arn := fmt.Sprintf("arn:aws:ecs:us-west-2:1:task-definition/%s:%d", *input.Family, revision)
+	def, err := taskDefinitionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+	def.TaskDefinitionArn = aws.String(arn)
+	def.Revision = aws.Int64(revision)
+	f.taskDefs[arn] = def
+	return &ecs.RegisterTaskDefinitionOutput{TaskDefinition: def}, nil
+}
+
+func (f *fakeECS) UpdateService(input *ecs.UpdateServiceInput) (*ecs.UpdateServiceOutput, error) {
+	f.updates = append(f.updates, input)
+	s, ok := f.services[*input.Cluster+"/"+*input.Service]
+	if !ok {
+		return nil, fmt.Errorf("service %s not found", *input.Service)
+	}
+	if input.TaskDefinition != nil {
+		s.TaskDefinition = input.TaskDefinition
+	}
+	if input.DesiredCount != nil {
+		s.DesiredCount = input.DesiredCount
+	}
+	return &ecs.UpdateServiceOutput{Service: s}, nil
+}
+
+func (f *fakeECS) ListTasks(input *ecs.ListTasksInput) (*ecs.ListTasksOutput, error) {
+	out := &ecs.ListTasksOutput{}
+	for arn, task := range f.tasks {
+		if input.DesiredStatus != nil && aws.StringValue(task.DesiredStatus) != *input.DesiredStatus {
+			continue
+		}
+		if input.ServiceName != nil && aws.StringValue(task.Group) != "service:"+*input.ServiceName {
+			continue
+		}
+		if input.Family != nil && !strings.Contains(aws.StringValue(task.TaskDefinitionArn), "/"+*input.Family+":") {
+			continue
+		}
+		out.TaskArns = append(out.TaskArns, aws.String(arn))
+	}
+	return out, nil
+}
+
+func (f *fakeECS) ListTasksPages(input *ecs.ListTasksInput, fn func(*ecs.ListTasksOutput, bool) bool) error {
+	out, err := f.ListTasks(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeECS) DescribeTasks(input *ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error) {
+	out := &ecs.DescribeTasksOutput{}
+	for _, arn := range input.Tasks {
+		if task, ok := f.tasks[*arn]; ok {
+			out.Tasks = append(out.Tasks, task)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeECS) ListTaskDefinitionsPages(input *ecs.ListTaskDefinitionsInput, fn func(*ecs.ListTaskDefinitionsOutput, bool) bool) error {
+	defs := make([]*ecs.TaskDefinition, 0)
+	for _, def := range f.taskDefs {
+		if strings.HasPrefix(*def.Family, aws.StringValue(input.FamilyPrefix)) {
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return *defs[i].Revision > *defs[j].Revision
+	})
+	out := &ecs.ListTaskDefinitionsOutput{}
+	for _, def := range defs {
+		out.TaskDefinitionArns = append(out.TaskDefinitionArns, def.TaskDefinitionArn)
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeECS) RunTask(input *ecs.RunTaskInput) (*ecs.RunTaskOutput, error) {
+	copied := *input
+	f.runs = append(f.runs, &copied)
+	out := &ecs.RunTaskOutput{}
+	for i := int64(0); i < aws.Int64Value(input.Count); i++ {
+		arn := fmt.Sprintf("arn:aws:ecs:us-west-2:1:task/%s/%d-%d", *input.Cluster, len(f.runs), i)
+		task := &ecs.Task{TaskArn: aws.String(arn), TaskDefinitionArn: input.TaskDefinition, LastStatus: aws.String("PROVISIONING")}
+		f.tasks[arn] = task
+		out.Tasks = append(out.Tasks, task)
+	}
+	return out, nil
+}
+
+func testTaskDef(family string, revision int64, image string) *ecs.TaskDefinition {
+	return &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String(fmt.Sprintf("arn:aws:ecs:us-west-2:1:task-definition/%s:%d", family, revision)),
+		Family:            aws.String(family),
+		Revision:          aws.Int64(revision),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name:      aws.String(family),
+				Image:     aws.String(image),
+				Essential: aws.Bool(true),
+				Environment: []*ecs.KeyValuePair{
+					{Name: aws.String("APP_ENV"), Value: aws.String("qa")},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateCluster(t *testing.T) {
+	fake := newFakeECS()
+	fake.clusters = []string{"qa", "prod"}
+	client := &Client{ECS: fake}
+	tests := []struct {
+		cluster string
+		wantErr bool
+	}{
+		{"qa", false},
+		{"prod", false},
+		{"staging", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cluster, func(t *testing.T) {
+			err := client.ValidateCluster(tt.cluster)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCluster(%s) error = %v, wantErr %v", tt.cluster, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateNewTaskWithImageDeploys(t *testing.T) {
+	fake := newFakeECS()
+	fake.clusters = []string{"qa"}
+	fake.addService("qa", "api", testTaskDef("api", 1, "api:1"))
+	client := &Client{ECS: fake}
+
+	current, err := client.GetCurrentTaskDefinition("qa", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTask, err := client.CreateNewTaskWithImage(current, "api:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DeployTaskToService("qa", "api", newTask); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.registered) != 1 {
+		t.Fatalf("expected 1 registration, got %d", len(fake.registered))
+	}
+	if got := EssentialImage(newTask); got != "api:2" {
+		t.Errorf("expected image api:2, got %s", got)
+	}
+	deployed, err := client.GetCurrentTaskDefinition("qa", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployed.TaskDefinitionArn != *newTask.TaskDefinitionArn {
+		t.Errorf("expected service to run %s, got %s", *newTask.TaskDefinitionArn, *deployed.TaskDefinitionArn)
+	}
+}
+
+func TestNewClientWithOptionsRegion(t *testing.T) {
+	setenv(t, "AWS_REGION", "")
+	setenv(t, "AWS_DEFAULT_REGION", "")
+	setenv(t, "AWS_PROFILE", "")
+	setenv(t, "AWS_CONFIG_FILE", "testdata/none")
+	setenv(t, "AWS_SHARED_CREDENTIALS_FILE", "testdata/none")
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{"eu-west-1", "eu-west-1"},
+		{"", defaultRegion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			client, err := NewClientWithOptions(SessionOptions{Region: tt.region})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.Region != tt.want {
+				t.Errorf("expected region %s, got %s", tt.want, client.Region)
+			}
+			url := client.BuildConsoleURLForTask("qa", "abc")
+			want := "https://" + tt.want + ".console.aws.amazon.com/ecs/home?region=" + tt.want + "#/clusters/qa/tasks/abc/details"
+			if url != want {
+				t.Errorf("expected url %s, got %s", want, url)
+			}
+		})
+	}
+}
+
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
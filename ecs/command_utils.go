@@ -14,3 +14,19 @@ func parseCommandOverride(command string) ([]string, error) {
 	}
 	return commands, nil
 }
+
+// formatCommandOverride is the inverse of parseCommandOverride, quoting
+// arguments that contain spaces or quotes
+func formatCommandOverride(commands []string) (string, error) {
+	var out strings.Builder
+	writer := csv.NewWriter(&out)
+	writer.Comma = ' '
+	if err := writer.Write(commands); err != nil {
+		return "", err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
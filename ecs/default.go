@@ -0,0 +1,307 @@
+package ecs
+
+import (
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+// The functions below operate on DefaultClient, and are kept so existing
+// callers do not need to construct a Client themselves.
+
+// GetClusterNames calls DefaultClient().GetClusterNames
+func GetClusterNames() ([]string, error) {
+	return DefaultClient().GetClusterNames()
+}
+
+// ValidateCluster calls DefaultClient().ValidateCluster
+func ValidateCluster(cluster string) error {
+	return DefaultClient().ValidateCluster(cluster)
+}
+
+// GetDeployedEssentialContainer calls DefaultClient().GetDeployedEssentialContainer
+func GetDeployedEssentialContainer(cluster, service string) (*ecs.ContainerDefinition, error) {
+	return DefaultClient().GetDeployedEssentialContainer(cluster, service)
+}
+
+// FindServicesWithEnvVar calls DefaultClient().FindServicesWithEnvVar
+//
+// Deprecated: use SearchEnv.
+func FindServicesWithEnvVar(envVar, envVarValue string) ([]ecs.Service, error) {
+	return DefaultClient().FindServicesWithEnvVar(envVar, envVarValue)
+}
+
+// GetCurrentTaskDefinition calls DefaultClient().GetCurrentTaskDefinition
+func GetCurrentTaskDefinition(cluster, service string) (*ecs.TaskDefinition, error) {
+	return DefaultClient().GetCurrentTaskDefinition(cluster, service)
+}
+
+// GetNewestTaskDefinition calls DefaultClient().GetNewestTaskDefinition
+func GetNewestTaskDefinition(cluster, service string) (*ecs.TaskDefinition, error) {
+	return DefaultClient().GetNewestTaskDefinition(cluster, service)
+}
+
+// GetTaskDefinition calls DefaultClient().GetTaskDefinition
+func GetTaskDefinition(arn string) (*ecs.TaskDefinition, error) {
+	return DefaultClient().GetTaskDefinition(arn)
+}
+
+// GetClusterInstances calls DefaultClient().GetClusterInstances
+func GetClusterInstances(cluster string) ([]string, error) {
+	return DefaultClient().GetClusterInstances(cluster)
+}
+
+// UpdateAgent calls DefaultClient().UpdateAgent
+func UpdateAgent(cluster, containerInstanceARN string) error {
+	return DefaultClient().UpdateAgent(cluster, containerInstanceARN)
+}
+
+// GetContainerInstances calls DefaultClient().GetContainerInstances
+func GetContainerInstances(cluster string, service string) ([]string, error) {
+	return DefaultClient().GetContainerInstances(cluster, service)
+}
+
+// CreateNewTaskWithEnvironment calls DefaultClient().CreateNewTaskWithEnvironment
+func CreateNewTaskWithEnvironment(existingTask *ecs.TaskDefinition, env []*ecs.KeyValuePair) (*ecs.TaskDefinition, error) {
+	return DefaultClient().CreateNewTaskWithEnvironment(existingTask, env)
+}
+
+// CreateNewTaskWithMemory calls DefaultClient().CreateNewTaskWithMemory
+func CreateNewTaskWithMemory(existingTask *ecs.TaskDefinition, memory *int64, memoryReservation *int64) (*ecs.TaskDefinition, error) {
+	return DefaultClient().CreateNewTaskWithMemory(existingTask, memory, memoryReservation)
+}
+
+// CreateNewTaskWithImage calls DefaultClient().CreateNewTaskWithImage
+func CreateNewTaskWithImage(existingTask *ecs.TaskDefinition, imageURL string) (*ecs.TaskDefinition, error) {
+	return DefaultClient().CreateNewTaskWithImage(existingTask, imageURL)
+}
+
+// CopyTaskDefinition calls DefaultClient().CopyTaskDefinition
+func CopyTaskDefinition(existingTask *ecs.TaskDefinition, toFamilyName string, modifiers ...func(*ecs.ContainerDefinition)) (*ecs.TaskDefinition, error) {
+	return DefaultClient().CopyTaskDefinition(existingTask, toFamilyName, modifiers...)
+}
+
+// FindService calls DefaultClient().FindService
+func FindService(cluster, service string) (*ecs.Service, error) {
+	return DefaultClient().FindService(cluster, service)
+}
+
+// ListClusters calls DefaultClient().ListClusters
+func ListClusters() ([]string, error) {
+	return DefaultClient().ListClusters()
+}
+
+// ListServices calls DefaultClient().ListServices
+func ListServices(cluster string) ([]string, error) {
+	return DefaultClient().ListServices(cluster)
+}
+
+// FindNewestDefinition calls DefaultClient().FindNewestDefinition
+func FindNewestDefinition(familyPrefix string) (*ecs.TaskDefinition, error) {
+	return DefaultClient().FindNewestDefinition(familyPrefix)
+}
+
+// ListFamilyRevisions calls DefaultClient().ListFamilyRevisions
+func ListFamilyRevisions(family string, max int) ([]*ecs.TaskDefinition, error) {
+	return DefaultClient().ListFamilyRevisions(family, max)
+}
+
+// ResolveTaskDefinition calls DefaultClient().ResolveTaskDefinition
+func ResolveTaskDefinition(cluster, service, ref string) (*ecs.TaskDefinition, error) {
+	return DefaultClient().ResolveTaskDefinition(cluster, service, ref)
+}
+
+// DeployTaskToService calls DefaultClient().DeployTaskToService
+func DeployTaskToService(cluster, service string, task *ecs.TaskDefinition) (*ecs.Service, error) {
+	return DefaultClient().DeployTaskToService(cluster, service, task)
+}
+
+// ScaleService calls DefaultClient().ScaleService
+func ScaleService(cluster, service string, desiredCount int) (*ecs.Service, error) {
+	return DefaultClient().ScaleService(cluster, service, desiredCount)
+}
+
+// GetAllTasksByDefinition calls DefaultClient().GetAllTasksByDefinition
+func GetAllTasksByDefinition(cluster string, def *ecs.TaskDefinition, status string) ([]*ecs.Task, error) {
+	return DefaultClient().GetAllTasksByDefinition(cluster, def, status)
+}
+
+// GetAllTasksByDefinitionStatus calls DefaultClient().GetAllTasksByDefinitionStatus
+func GetAllTasksByDefinitionStatus(cluster string, def *ecs.TaskDefinition, status *string) ([]*ecs.Task, error) {
+	return DefaultClient().GetAllTasksByDefinitionStatus(cluster, def, status)
+}
+
+// GetLogs calls DefaultClient().GetLogs
+func GetLogs(cluster, service, status string) error {
+	return DefaultClient().GetLogs(cluster, service, status)
+}
+
+// GetAllLogs calls DefaultClient().GetAllLogs
+func GetAllLogs(region string, params *cloudwatchlogs.GetLogEventsInput) ([]string, error) {
+	return DefaultClient().GetAllLogs(region, params)
+}
+
+// GetTaskLogs calls DefaultClient().GetTaskLogs
+func GetTaskLogs(def *ecs.TaskDefinition, taskID string) error {
+	return DefaultClient().GetTaskLogs(def, taskID)
+}
+
+// ListLogEvents calls DefaultClient().ListLogEvents
+func ListLogEvents(group, name, region string) error {
+	return DefaultClient().ListLogEvents(group, name, region)
+}
+
+// LocateTaskDef calls DefaultClient().LocateTaskDef
+func LocateTaskDef(cluster, service, source string) (*ecs.TaskDefinition, error) {
+	return DefaultClient().LocateTaskDef(cluster, service, source)
+}
+
+// RunTaskWithCommand calls DefaultClient().RunTaskWithCommand
+func RunTaskWithCommand(cluster string, task *ecs.TaskDefinition, command string) (*ecs.RunTaskOutput, error) {
+	return DefaultClient().RunTaskWithCommand(cluster, task, command)
+}
+
+// FindRoleByName calls DefaultClient().FindRoleByName
+func FindRoleByName(name string) (*iam.Role, error) {
+	return DefaultClient().FindRoleByName(name)
+}
+
+// CreateScheduledTask calls DefaultClient().CreateScheduledTask
+func CreateScheduledTask(cluster, service, taskSuffix, scheduleExpression, command string) error {
+	return DefaultClient().CreateScheduledTask(cluster, service, taskSuffix, scheduleExpression, command)
+}
+
+// CreatePostDeploymentTask calls DefaultClient().CreatePostDeploymentTask
+func CreatePostDeploymentTask(input *CreatePostDeploymentTaskInput) error {
+	return DefaultClient().CreatePostDeploymentTask(input)
+}
+
+// GetTask calls DefaultClient().GetTask
+func GetTask(cluster, arn string) (*ecs.Task, error) {
+	return DefaultClient().GetTask(cluster, arn)
+}
+
+// TaskExitCode calls DefaultClient().TaskExitCode
+func TaskExitCode(task *ecs.Task) (*int64, error) {
+	return DefaultClient().TaskExitCode(task)
+}
+
+// CreateRefreshDeployment calls DefaultClient().CreateRefreshDeployment
+func CreateRefreshDeployment(cluster, service string) error {
+	return DefaultClient().CreateRefreshDeployment(cluster, service)
+}
+
+// PrintTaskURLs calls DefaultClient().PrintTaskURLs
+func PrintTaskURLs(clusterName string, service *ecs.Service) ([]string, error) {
+	return DefaultClient().PrintTaskURLs(clusterName, service)
+}
+
+// BuildConsoleURLForService calls DefaultClient().BuildConsoleURLForService
+func BuildConsoleURLForService(cluster, service string) string {
+	return DefaultClient().BuildConsoleURLForService(cluster, service)
+}
+
+// BuildConsoleURLForTask calls DefaultClient().BuildConsoleURLForTask
+func BuildConsoleURLForTask(cluster, taskID string) string {
+	return DefaultClient().BuildConsoleURLForTask(cluster, taskID)
+}
+
+// WaitForDeployment calls DefaultClient().WaitForDeployment
+func WaitForDeployment(cluster, service, taskDefinitionArn string, opts WaitOptions) error {
+	return DefaultClient().WaitForDeployment(cluster, service, taskDefinitionArn, opts)
+}
+
+// CloneTaskDefinition calls DefaultClient().CloneTaskDefinition
+func CloneTaskDefinition(existingTask *ecs.TaskDefinition, configure func(*ecs.RegisterTaskDefinitionInput) error) (*ecs.TaskDefinition, error) {
+	return DefaultClient().CloneTaskDefinition(existingTask, configure)
+}
+
+// RegisterTaskDefinition calls DefaultClient().RegisterTaskDefinition
+func RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (*ecs.TaskDefinition, error) {
+	return DefaultClient().RegisterTaskDefinition(input)
+}
+
+// RunTask calls DefaultClient().RunTask
+func RunTask(cluster string, task *ecs.TaskDefinition, opts RunTaskOptions) (*ecs.RunTaskOutput, error) {
+	return DefaultClient().RunTask(cluster, task, opts)
+}
+
+// TailLogStream calls DefaultClient().TailLogStream
+func TailLogStream(stream *LogStream, stop <-chan struct{}, print func(event *cloudwatchlogs.OutputLogEvent)) error {
+	return DefaultClient().TailLogStream(stream, stop, print)
+}
+
+// ServiceLogStreams calls DefaultClient().ServiceLogStreams
+func ServiceLogStreams(cluster, service string, query LogsQuery) ([]*LogStream, error) {
+	return DefaultClient().ServiceLogStreams(cluster, service, query)
+}
+
+// GetServiceLogs calls DefaultClient().GetServiceLogs
+func GetServiceLogs(cluster, service string, query LogsQuery) ([]*LogEvent, error) {
+	return DefaultClient().GetServiceLogs(cluster, service, query)
+}
+
+// FilterLogEvents calls DefaultClient().FilterLogEvents
+func FilterLogEvents(streams []*LogStream, query LogsQuery) ([]*LogEvent, error) {
+	return DefaultClient().FilterLogEvents(streams, query)
+}
+
+// FollowServiceLogs calls DefaultClient().FollowServiceLogs
+func FollowServiceLogs(cluster, service string, query LogsQuery, stop <-chan struct{}, print func(event *LogEvent)) error {
+	return DefaultClient().FollowServiceLogs(cluster, service, query, stop, print)
+}
+
+// RunInsightsQuery calls DefaultClient().RunInsightsQuery
+func RunInsightsQuery(groups []string, query InsightsQuery) (*InsightsResult, error) {
+	return DefaultClient().RunInsightsQuery(groups, query)
+}
+
+// EachLogEvent calls DefaultClient().EachLogEvent
+func EachLogEvent(params *cloudwatchlogs.GetLogEventsInput, fn func(event *cloudwatchlogs.OutputLogEvent) error) error {
+	return DefaultClient().EachLogEvent(params, fn)
+}
+
+// ExportServiceLogs calls DefaultClient().ExportServiceLogs
+func ExportServiceLogs(cluster, service string, query LogsQuery, dir string) (*LogExportManifest, error) {
+	return DefaultClient().ExportServiceLogs(cluster, service, query, dir)
+}
+
+// CreateNewTaskWithEnv calls DefaultClient().CreateNewTaskWithEnv
+func CreateNewTaskWithEnv(existingTask *ecs.TaskDefinition, vars []EnvVar) (*ecs.TaskDefinition, error) {
+	return DefaultClient().CreateNewTaskWithEnv(existingTask, vars)
+}
+
+// PutSecret calls DefaultClient().PutSecret
+func PutSecret(store, name, value string) (string, error) {
+	return DefaultClient().PutSecret(store, name, value)
+}
+
+// SearchEnv calls DefaultClient().SearchEnv
+func SearchEnv(search EnvSearch) (*EnvSearchResult, error) {
+	return DefaultClient().SearchEnv(search)
+}
+
+// DryRun calls DefaultClient().DryRun
+func DryRun() bool {
+	return DefaultClient().DryRun()
+}
+
+// PrintAction calls DefaultClient().PrintAction
+func PrintAction(doing, planned string, args ...interface{}) {
+	DefaultClient().PrintAction(doing, planned, args...)
+}
+
+// DiffServiceSpec calls DefaultClient().DiffServiceSpec
+func DiffServiceSpec(spec *ServiceSpec) (*SpecDiff, error) {
+	return DefaultClient().DiffServiceSpec(spec)
+}
+
+// ApplyServiceSpec calls DefaultClient().ApplyServiceSpec
+func ApplyServiceSpec(diff *SpecDiff) (*ecs.TaskDefinition, error) {
+	return DefaultClient().ApplyServiceSpec(diff)
+}
+
+// ExportService calls DefaultClient().ExportService
+func ExportService(cluster, service string, withRules bool) (*ServiceExport, error) {
+	return DefaultClient().ExportService(cluster, service, withRules)
+}
@@ -0,0 +1,197 @@
+package ecs
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// WaitOptions controls how long, and how patiently, WaitForDeployment
+// follows a rollout
+type WaitOptions struct {
+	// Timeout is the maximum time to wait for steady state
+	Timeout time.Duration
+	// PollInterval is the time between service checks
+	PollInterval time.Duration
+	// MaxStoppedTasks is the number of stopped tasks of the new task
+	// definition after which the rollout is considered failed
+	MaxStoppedTasks int
+}
+
+// DefaultWaitOptions are used for any zero values in WaitOptions
+var DefaultWaitOptions = WaitOptions{
+	Timeout:         10 * time.Minute,
+	PollInterval:    10 * time.Second,
+	MaxStoppedTasks: 3,
+}
+
+// WaitForDeployment follows the rollout of a task definition to a service,
+// printing service events and stopped task reasons as they happen, until
+// the service reaches steady state. It returns an error if the rollout
+// fails, keeps stopping tasks, or does not finish within the timeout.
+func (c *Client) WaitForDeployment(cluster, service, taskDefinitionArn string, opts WaitOptions) error {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultWaitOptions.Timeout
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultWaitOptions.PollInterval
+	}
+	if opts.MaxStoppedTasks == 0 {
+		opts.MaxStoppedTasks = DefaultWaitOptions.MaxStoppedTasks
+	}
+	started := time.Now()
+	deadline := started.Add(opts.Timeout)
+	seenEvents := make(map[string]bool)
+	seenStopped := make(map[string]bool)
+	for {
+		svc, err := c.FindService(cluster, service)
+		if err != nil {
+			return err
+		}
+		printNewServiceEvents(svc, started, seenEvents)
+		deployment := findDeployment(svc, taskDefinitionArn)
+		if deployment == nil {
+			return fmt.Errorf("deployment of %s not found in service %s, was it replaced by another deployment?", path.Base(taskDefinitionArn), service)
+		}
+		if aws.StringValue(deployment.RolloutState) == ecs.DeploymentRolloutStateFailed {
+			return fmt.Errorf("deployment of %s failed: %s", path.Base(taskDefinitionArn), aws.StringValue(deployment.RolloutStateReason))
+		}
+		stopped, err := c.stoppedServiceTasks(cluster, service, taskDefinitionArn, started)
+		if err != nil {
+			return err
+		}
+		for _, task := range stopped {
+			if !seenStopped[*task.TaskArn] {
+				seenStopped[*task.TaskArn] = true
+				fmt.Printf("stopped task %s: %s\n", GetTaskIDFromArn(*task.TaskArn), DescribeStoppedTask(task))
+			}
+		}
+		if len(seenStopped) >= opts.MaxStoppedTasks {
+			return fmt.Errorf("deployment of %s failed: %d tasks stopped", path.Base(taskDefinitionArn), len(seenStopped))
+		}
+		fmt.Printf("%s %s (%d Desired, %d Pending, %d Running)\n",
+			path.Base(taskDefinitionArn), aws.StringValue(deployment.RolloutState),
+			aws.Int64Value(deployment.DesiredCount), aws.Int64Value(deployment.PendingCount), aws.Int64Value(deployment.RunningCount))
+		if isSteadyState(svc, taskDefinitionArn) {
+			return nil
+		}
+		if time.Now().Add(opts.PollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %v waiting for %s to reach steady state", opts.Timeout, service)
+		}
+		time.Sleep(opts.PollInterval)
+	}
+}
+
+// DescribeStoppedTask summarizes why a task stopped, including any
+// container exit codes and reasons
+func DescribeStoppedTask(task *ecs.Task) string {
+	out := aws.StringValue(task.StoppedReason)
+	for _, container := range task.Containers {
+		if container.ExitCode == nil && container.Reason == nil {
+			continue
+		}
+		out += fmt.Sprintf(" [%s", aws.StringValue(container.Name))
+		if container.ExitCode != nil {
+			out += fmt.Sprintf(" exit %d", *container.ExitCode)
+		}
+		if container.Reason != nil {
+			out += fmt.Sprintf(": %s", *container.Reason)
+		}
+		out += "]"
+	}
+	return out
+}
+
+func (c *Client) stoppedServiceTasks(cluster, service, taskDefinitionArn string, since time.Time) ([]*ecs.Task, error) {
+	svc := c.ECS
+	list, err := svc.ListTasks(&ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		ServiceName:   aws.String(service),
+		DesiredStatus: aws.String(ecs.DesiredStatusStopped),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(list.TaskArns) == 0 {
+		return nil, nil
+	}
+	if len(list.TaskArns) > 100 {
+		list.TaskArns = list.TaskArns[:100]
+	}
+	result, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
+		Cluster: aws.String(cluster),
+		Tasks:   list.TaskArns,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*ecs.Task, 0)
+	for _, task := range result.Tasks {
+		if aws.StringValue(task.TaskDefinitionArn) != taskDefinitionArn {
+			continue
+		}
+		if task.StoppedAt != nil && task.StoppedAt.Before(since) {
+			continue
+		}
+		out = append(out, task)
+	}
+	return out, nil
+}
+
+func findDeployment(svc *ecs.Service, taskDefinitionArn string) *ecs.Deployment {
+	for _, d := range svc.Deployments {
+		if aws.StringValue(d.TaskDefinition) == taskDefinitionArn {
+			return d
+		}
+	}
+	return nil
+}
+
+func isSteadyState(svc *ecs.Service, taskDefinitionArn string) bool {
+	if len(svc.Deployments) != 1 {
+		return false
+	}
+	d := svc.Deployments[0]
+	if aws.StringValue(d.TaskDefinition) != taskDefinitionArn {
+		return false
+	}
+	if d.RolloutState != nil && *d.RolloutState != ecs.DeploymentRolloutStateCompleted {
+		return false
+	}
+	return aws.Int64Value(d.RunningCount) == aws.Int64Value(d.DesiredCount)
+}
+
+func printNewServiceEvents(svc *ecs.Service, since time.Time, seen map[string]bool) {
+	// events are returned newest first
+	for i := len(svc.Events) - 1; i >= 0; i-- {
+		event := svc.Events[i]
+		if seen[*event.Id] || event.CreatedAt.Before(since) {
+			continue
+		}
+		seen[*event.Id] = true
+		fmt.Printf("[%v] %s\n", *event.CreatedAt, *event.Message)
+	}
+}
+
+// FindPreviousDeployment returns the task definition arn of the most recent
+// non-primary deployment of a service that runs a different task definition
+// than the primary one. It returns an empty string if there is none, which
+// is the case once a deployment has finished.
+func FindPreviousDeployment(svc *ecs.Service) string {
+	var previous *ecs.Deployment
+	for _, d := range svc.Deployments {
+		if aws.StringValue(d.Status) == "PRIMARY" || aws.StringValue(d.TaskDefinition) == aws.StringValue(svc.TaskDefinition) {
+			continue
+		}
+		if previous == nil || (d.CreatedAt != nil && previous.CreatedAt != nil && d.CreatedAt.After(*previous.CreatedAt)) {
+			previous = d
+		}
+	}
+	if previous == nil {
+		return ""
+	}
+	return aws.StringValue(previous.TaskDefinition)
+}
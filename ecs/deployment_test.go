@@ -0,0 +1,119 @@
+package ecs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestWaitForDeployment(t *testing.T) {
+	def := testTaskDef("api", 2, "api:2")
+	tests := []struct {
+		name       string
+		deployment *ecs.Deployment
+		extra      []*ecs.Deployment
+		stopped    int
+		wantErr    string
+	}{
+		{
+			name:       "steady",
+			deployment: &ecs.Deployment{RolloutState: aws.String("COMPLETED"), DesiredCount: aws.Int64(2), RunningCount: aws.Int64(2)},
+		},
+		{
+			name:       "failed rollout",
+			deployment: &ecs.Deployment{RolloutState: aws.String("FAILED"), RolloutStateReason: aws.String("circuit breaker"), DesiredCount: aws.Int64(2)},
+			wantErr:    "circuit breaker",
+		},
+		{
+			name:       "tasks keep stopping",
+			deployment: &ecs.Deployment{RolloutState: aws.String("IN_PROGRESS"), DesiredCount: aws.Int64(2), RunningCount: aws.Int64(0)},
+			stopped:    3,
+			wantErr:    "3 tasks stopped",
+		},
+		{
+			name:       "timeout",
+			deployment: &ecs.Deployment{RolloutState: aws.String("IN_PROGRESS"), DesiredCount: aws.Int64(2), RunningCount: aws.Int64(1)},
+			extra:      []*ecs.Deployment{{TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1:task-definition/api:1")}},
+			wantErr:    "timed out",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeECS()
+			fake.addService("qa", "api", def)
+			tt.deployment.TaskDefinition = def.TaskDefinitionArn
+			tt.deployment.PendingCount = aws.Int64(0)
+			fake.services["qa/api"].Deployments = append([]*ecs.Deployment{tt.deployment}, tt.extra...)
+			for i := 0; i < tt.stopped; i++ {
+				arn := "arn:aws:ecs:us-west-2:1:task/qa/" + string(rune('a'+i))
+				fake.tasks[arn] = &ecs.Task{
+					TaskArn:           aws.String(arn),
+					TaskDefinitionArn: def.TaskDefinitionArn,
+					Group:             aws.String("service:api"),
+					DesiredStatus:     aws.String("STOPPED"),
+					StoppedReason:     aws.String("Essential container in task exited"),
+				}
+			}
+			client := &Client{ECS: fake}
+			err := client.WaitForDeployment("qa", "api", *def.TaskDefinitionArn, WaitOptions{
+				Timeout:      30 * time.Millisecond,
+				PollInterval: 10 * time.Millisecond,
+			})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDescribeStoppedTask(t *testing.T) {
+	task := &ecs.Task{
+		StoppedReason: aws.String("Essential container in task exited"),
+		Containers: []*ecs.Container{
+			{Name: aws.String("api"), ExitCode: aws.Int64(137), Reason: aws.String("OutOfMemoryError")},
+			{Name: aws.String("sidecar")},
+		},
+	}
+	want := "Essential container in task exited [api exit 137: OutOfMemoryError]"
+	if got := DescribeStoppedTask(task); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestFindPreviousDeployment(t *testing.T) {
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+	tests := []struct {
+		name        string
+		deployments []*ecs.Deployment
+		want        string
+	}{
+		{"steady", []*ecs.Deployment{{Status: aws.String("PRIMARY"), TaskDefinition: aws.String("api:3")}}, ""},
+		{
+			"rolling",
+			[]*ecs.Deployment{
+				{Status: aws.String("PRIMARY"), TaskDefinition: aws.String("api:3"), CreatedAt: &newer},
+				{Status: aws.String("ACTIVE"), TaskDefinition: aws.String("api:1"), CreatedAt: &older},
+				{Status: aws.String("ACTIVE"), TaskDefinition: aws.String("api:2"), CreatedAt: &newer},
+			},
+			"api:2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &ecs.Service{TaskDefinition: aws.String("api:3"), Deployments: tt.deployments}
+			if got := FindPreviousDeployment(svc); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
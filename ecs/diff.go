@@ -0,0 +1,161 @@
+package ecs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// FieldChange is a single changed value between two task definitions.
+// Added fields have no From, removed ones no To; a field set to an empty
+// value is neither.
+type FieldChange struct {
+	Field   string
+	From    string
+	To      string
+	Added   bool
+	Removed bool
+}
+
+// ContainerDiff lists the changes to one container definition
+type ContainerDiff struct {
+	Name    string
+	Added   bool
+	Removed bool
+	Changes []FieldChange
+}
+
+// TaskDefinitionDiff is the structured difference between two task definitions
+type TaskDefinitionDiff struct {
+	Task       []FieldChange
+	Containers []ContainerDiff
+}
+
+// IsEmpty reports whether the two task definitions are equivalent
+func (d *TaskDefinitionDiff) IsEmpty() bool {
+	if len(d.Task) > 0 {
+		return false
+	}
+	for _, c := range d.Containers {
+		if c.Added || c.Removed || len(c.Changes) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffTaskDefinitions compares the task level sizing and the container
+// definitions (image, environment, secrets, ports, memory, command) of two task
+// definitions, matching containers by name
+func DiffTaskDefinitions(from, to *ecs.TaskDefinition) *TaskDefinitionDiff {
+	out := &TaskDefinitionDiff{}
+	out.Task = diffFields(taskFields(from), taskFields(to))
+	fromContainers := make(map[string]*ecs.ContainerDefinition)
+	names := make([]string, 0)
+	for _, c := range from.ContainerDefinitions {
+		fromContainers[aws.StringValue(c.Name)] = c
+		names = append(names, aws.StringValue(c.Name))
+	}
+	toContainers := make(map[string]*ecs.ContainerDefinition)
+	for _, c := range to.ContainerDefinitions {
+		name := aws.StringValue(c.Name)
+		toContainers[name] = c
+		if _, ok := fromContainers[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		a, inFrom := fromContainers[name]
+		b, inTo := toContainers[name]
+		diff := ContainerDiff{Name: name, Added: !inFrom, Removed: !inTo}
+		diff.Changes = diffFields(containerFields(a), containerFields(b))
+		if diff.Added || diff.Removed || len(diff.Changes) > 0 {
+			out.Containers = append(out.Containers, diff)
+		}
+	}
+	return out
+}
+
+func diffFields(from, to map[string]string) []FieldChange {
+	keys := make([]string, 0, len(from)+len(to))
+	for k := range from {
+		keys = append(keys, k)
+	}
+	for k := range to {
+		if _, ok := from[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	changes := make([]FieldChange, 0)
+	for _, k := range keys {
+		a, inFrom := from[k]
+		b, inTo := to[k]
+		if inFrom && inTo && a == b {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: k, From: a, To: b, Added: !inFrom, Removed: !inTo})
+	}
+	return changes
+}
+
+func taskFields(task *ecs.TaskDefinition) map[string]string {
+	out := make(map[string]string)
+	setField(out, "cpu", task.Cpu)
+	setField(out, "memory", task.Memory)
+	return out
+}
+
+func containerFields(c *ecs.ContainerDefinition) map[string]string {
+	out := make(map[string]string)
+	if c == nil {
+		return out
+	}
+	setField(out, "image", c.Image)
+	setField(out, "cpu", formatInt64(c.Cpu))
+	setField(out, "memory", formatInt64(c.Memory))
+	setField(out, "memoryReservation", formatInt64(c.MemoryReservation))
+	setField(out, "command", joinStrings(c.Command))
+	setField(out, "entryPoint", joinStrings(c.EntryPoint))
+	for _, env := range c.Environment {
+		out["env."+aws.StringValue(env.Name)] = aws.StringValue(env.Value)
+	}
+	for _, secret := range c.Secrets {
+		out["secret."+aws.StringValue(secret.Name)] = aws.StringValue(secret.ValueFrom)
+	}
+	for _, port := range c.PortMappings {
+		protocol := aws.StringValue(port.Protocol)
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		key := fmt.Sprintf("port.%d/%s", aws.Int64Value(port.ContainerPort), protocol)
+		out[key] = fmt.Sprintf("host %d", aws.Int64Value(port.HostPort))
+	}
+	return out
+}
+
+// setField sets a field that is present, even to an empty value, so that
+// clearing a value is told apart from removing it
+func setField(fields map[string]string, key string, value *string) {
+	if value != nil {
+		fields[key] = *value
+	}
+}
+
+func formatInt64(v *int64) *string {
+	if v == nil {
+		return nil
+	}
+	return aws.String(strconv.FormatInt(*v, 10))
+}
+
+func joinStrings(values []*string) *string {
+	if values == nil {
+		return nil
+	}
+	return aws.String(strings.Join(aws.StringValueSlice(values), " "))
+}
@@ -0,0 +1,89 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestDiffTaskDefinitions(t *testing.T) {
+	from := testTaskDef("api", 1, "api:1")
+	from.ContainerDefinitions[0].Environment = append(from.ContainerDefinitions[0].Environment,
+		&ecs.KeyValuePair{Name: aws.String("REMOVED"), Value: aws.String("x")},
+		&ecs.KeyValuePair{Name: aws.String("CLEARED"), Value: aws.String("y")})
+	from.ContainerDefinitions = append(from.ContainerDefinitions, &ecs.ContainerDefinition{
+		Name:  aws.String("old-sidecar"),
+		Image: aws.String("sidecar:1"),
+	})
+	to := testTaskDef("api", 2, "api:2")
+	to.Memory = aws.String("1024")
+	to.ContainerDefinitions[0].Environment[0].Value = aws.String("prod")
+	to.ContainerDefinitions[0].Environment = append(to.ContainerDefinitions[0].Environment,
+		&ecs.KeyValuePair{Name: aws.String("CLEARED"), Value: aws.String("")},
+		&ecs.KeyValuePair{Name: aws.String("EMPTY"), Value: aws.String("")})
+	to.ContainerDefinitions[0].Memory = aws.Int64(512)
+	to.ContainerDefinitions[0].PortMappings = []*ecs.PortMapping{{ContainerPort: aws.Int64(80), HostPort: aws.Int64(0)}}
+
+	diff := DiffTaskDefinitions(from, to)
+	if diff.IsEmpty() {
+		t.Fatal("expected a diff")
+	}
+	wantTask := []FieldChange{{Field: "memory", To: "1024", Added: true}}
+	if !reflect.DeepEqual(diff.Task, wantTask) {
+		t.Errorf("expected task changes %v, got %v", wantTask, diff.Task)
+	}
+	want := []ContainerDiff{
+		{
+			Name: "api",
+			Changes: []FieldChange{
+				{Field: "env.APP_ENV", From: "qa", To: "prod"},
+				{Field: "env.CLEARED", From: "y", To: ""},
+				{Field: "env.EMPTY", To: "", Added: true},
+				{Field: "env.REMOVED", From: "x", Removed: true},
+				{Field: "image", From: "api:1", To: "api:2"},
+				{Field: "memory", To: "512", Added: true},
+				{Field: "port.80/tcp", To: "host 0", Added: true},
+			},
+		},
+		{
+			Name:    "old-sidecar",
+			Removed: true,
+			Changes: []FieldChange{{Field: "image", From: "sidecar:1", Removed: true}},
+		},
+	}
+	if !reflect.DeepEqual(diff.Containers, want) {
+		t.Errorf("expected container changes\n%+v\ngot\n%+v", want, diff.Containers)
+	}
+	if !DiffTaskDefinitions(from, from).IsEmpty() {
+		t.Errorf("expected identical task definitions to have an empty diff")
+	}
+}
+
+func TestListFamilyRevisions(t *testing.T) {
+	fake := newFakeECS()
+	for i := int64(1); i <= 4; i++ {
+		def := testTaskDef("api", i, "api:1")
+		fake.taskDefs[*def.TaskDefinitionArn] = def
+	}
+	other := testTaskDef("api-worker", 9, "api:1")
+	fake.taskDefs[*other.TaskDefinitionArn] = other
+	client := &Client{ECS: fake}
+	defs, err := client.ListFamilyRevisions("api", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]int64, len(defs))
+	for i, def := range defs {
+		got[i] = *def.Revision
+	}
+	if want := []int64{4, 3, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected revisions %v, got %v", want, got)
+	}
+	for _, max := range []int{0, -1} {
+		if _, err := client.ListFamilyRevisions("api", max); err == nil {
+			t.Errorf("expected listing %d revisions to be an error", max)
+		}
+	}
+}
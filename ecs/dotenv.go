@@ -0,0 +1,131 @@
+package ecs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// envPair is a parsed KEY=value line
+type envPair struct {
+	name  string
+	value string
+}
+
+// parseDotenv parses a block of KEY=value lines the way .env files are
+// written: blank lines and # comments are skipped, an "export " prefix is
+// allowed, unquoted values end at a " #" comment, 'single quoted' values
+// are literal and "double quoted" values understand \n, \r, \t, \" and
+// \\ escapes. Quoted values may span several lines.
+func parseDotenv(input string) ([]envPair, error) {
+	lines := strings.Split(strings.Replace(input, "\r\n", "\n", -1), "\n")
+	out := make([]envPair, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := strings.TrimLeft(lines[i], " \t")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimLeft(line[len("export"):], " \t")
+		}
+		eq := strings.Index(line, "=")
+		if eq <= 0 {
+			return nil, fmt.Errorf("Problem parsing line %d: %s", start, strings.TrimSpace(lines[i]))
+		}
+		name := strings.TrimSpace(line[:eq])
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("Problem parsing line %d: invalid name %q", start, name)
+		}
+		rest := strings.TrimLeft(line[eq+1:], " \t")
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			if comment := strings.Index(rest, " #"); comment >= 0 {
+				rest = rest[:comment]
+			}
+			out = append(out, envPair{name: name, value: strings.TrimSpace(rest)})
+			continue
+		}
+		quote := rest[0]
+		body := rest[1:]
+		for {
+			end := closingQuote(body, quote)
+			if end >= 0 {
+				trailing := strings.TrimSpace(body[end+1:])
+				if trailing != "" && !strings.HasPrefix(trailing, "#") {
+					return nil, fmt.Errorf("Problem parsing line %d: unexpected %q after closing quote", i+1, trailing)
+				}
+				body = body[:end]
+				break
+			}
+			i++
+			if i >= len(lines) {
+				return nil, fmt.Errorf("Problem parsing line %d: unterminated quoted value of %s", start, name)
+			}
+			body += "\n" + lines[i]
+		}
+		if quote == '"' {
+			body = unescapeEnvValue(body)
+		}
+		out = append(out, envPair{name: name, value: body})
+	}
+	return out, nil
+}
+
+// closingQuote finds the quote ending a value, skipping escaped double
+// quotes
+func closingQuote(body string, quote byte) int {
+	for i := 0; i < len(body); i++ {
+		if quote == '"' && body[i] == '\\' {
+			i++
+			continue
+		}
+		if body[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeEnvValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(value[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// formatEnvValue quotes a value when writing it unquoted would not parse
+// back to the same value
+func formatEnvValue(value string) string {
+	needsQuotes := strings.ContainsAny(value, "\n\r") ||
+		strings.Contains(value, " #") ||
+		strings.HasPrefix(value, "\"") ||
+		strings.HasPrefix(value, "'") ||
+		strings.TrimSpace(value) != value
+	if !needsQuotes {
+		return value
+	}
+	r := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\r", "\\r", "\t", "\\t")
+	return "\"" + r.Replace(value) + "\""
+}
+
+// shellQuote single quotes a value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,467 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// maskedSecret replaces secret values in planned requests
+const maskedSecret = "****"
+
+// dryRunAccount stands in for the account id in arns of resources a dry
+// run would have created
+const dryRunAccount = "000000000000"
+
+// PlannedRequest is a write request a dry run client did not send
+type PlannedRequest struct {
+	// Operation is the AWS API call, e.g. RegisterTaskDefinition
+	Operation string
+	// Target names what the request changes: a family, cluster/service,
+	// rule or secret
+	Target string
+	// Request is the input that would have been sent, with secret values
+	// masked
+	Request interface{}
+	// Create is set when the target does not exist yet
+	Create bool
+	// Changes compares the request to the current state of the target
+	Changes []FieldChange
+	// TaskDefinition compares task definitions: the newest revision of the
+	// family for RegisterTaskDefinition, the deployed one for UpdateService
+	TaskDefinition *TaskDefinitionDiff
+}
+
+// RequestJSON renders the request the way it would be sent on the wire
+func (p *PlannedRequest) RequestJSON() (string, error) {
+	body, err := MarshalShape(p.Request)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err = json.Indent(&out, body, "", "  "); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// planner records the requests of a dry run client, and remembers the task
+// definitions it pretended to register so later calls can read them back
+type planner struct {
+	client *Client
+	report func(*PlannedRequest)
+
+	mu       sync.Mutex
+	plan     []*PlannedRequest
+	taskDefs map[string]*ecs.TaskDefinition
+}
+
+func (p *planner) record(req *PlannedRequest) {
+	p.mu.Lock()
+	p.plan = append(p.plan, req)
+	p.mu.Unlock()
+	if p.report != nil {
+		p.report(req)
+	}
+}
+
+func (p *planner) plannedTaskDefinition(ref string) *ecs.TaskDefinition {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.taskDefs[ref]
+}
+
+// EnableDryRun makes the client plan writes instead of making them. Every
+// write call (registering task definitions, updating services, event rules
+// and targets, running tasks, storing secrets) is compared to the current
+// state, passed to report and answered with what AWS would have returned,
+// so commands can carry on planning. Reads still go to AWS.
+func (c *Client) EnableDryRun(report func(*PlannedRequest)) {
+	if c.planner != nil {
+		c.planner.report = report
+		return
+	}
+	p := &planner{client: c, report: report, taskDefs: make(map[string]*ecs.TaskDefinition)}
+	c.planner = p
+	if c.ECS != nil {
+		c.ECS = &dryRunECS{ECSAPI: c.ECS, p: p}
+	}
+	if c.CloudWatchEvents != nil {
+		c.CloudWatchEvents = &dryRunEvents{CloudWatchEventsAPI: c.CloudWatchEvents, p: p}
+	}
+	if c.SSM != nil {
+		c.SSM = &dryRunSSM{SSMAPI: c.SSM, p: p}
+	}
+	if c.SecretsManager != nil {
+		c.SecretsManager = &dryRunSecretsManager{SecretsManagerAPI: c.SecretsManager, p: p}
+	}
+}
+
+// DryRun reports whether the client plans writes rather than making them
+func (c *Client) DryRun() bool {
+	return c.planner != nil
+}
+
+// PrintAction prints a change the client makes or made, or under a dry run,
+// the change it plans instead
+func (c *Client) PrintAction(doing, planned string, args ...interface{}) {
+	format := doing
+	if c.DryRun() {
+		format = planned
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// Plan returns the requests a dry run client has planned so far
+func (c *Client) Plan() []*PlannedRequest {
+	if c.planner == nil {
+		return nil
+	}
+	c.planner.mu.Lock()
+	defer c.planner.mu.Unlock()
+	return append([]*PlannedRequest(nil), c.planner.plan...)
+}
+
+// isNotFound reports whether an error is AWS saying a resource does not
+// exist. ECS has no code of its own for a task definition family that does
+// not exist, only the catch-all ClientException, so its message is checked.
+func isNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch aerr.Code() {
+	case cloudwatchevents.ErrCodeResourceNotFoundException,
+		ssm.ErrCodeParameterNotFound,
+		ecs.ErrCodeServiceNotFoundException:
+		return true
+	case ecs.ErrCodeClientException:
+		return strings.Contains(strings.ToLower(aerr.Message()), "unable to describe task definition")
+	}
+	return false
+}
+
+type dryRunECS struct {
+	ecsiface.ECSAPI
+	p *planner
+}
+
+// DescribeTaskDefinition answers for the task definitions the dry run
+// pretended to register
+func (d *dryRunECS) DescribeTaskDefinition(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+	if def := d.p.plannedTaskDefinition(aws.StringValue(input.TaskDefinition)); def != nil {
+		return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: def}, nil
+	}
+	return d.ECSAPI.DescribeTaskDefinition(input)
+}
+
+func (d *dryRunECS) RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error) {
+	family := aws.StringValue(input.Family)
+	planned, err := taskDefinitionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+	req := &PlannedRequest{Operation: "RegisterTaskDefinition", Target: family, Request: input}
+	revision := int64(1)
+	arn := fmt.Sprintf("arn:aws:ecs:%s:%s:task-definition/%s:%d", d.p.client.Region, dryRunAccount, family, revision)
+	latest, err := d.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: input.Family})
+	switch {
+	case err == nil:
+		revision = aws.Int64Value(latest.TaskDefinition.Revision) + 1
+		arn = strings.TrimSuffix(aws.StringValue(latest.TaskDefinition.TaskDefinitionArn), ":"+strconv.FormatInt(revision-1, 10))
+		arn = fmt.Sprintf("%s:%d", arn, revision)
+		req.TaskDefinition = DiffTaskDefinitions(latest.TaskDefinition, planned)
+	case isNotFound(err):
+		req.Create = true
+	default:
+		return nil, err
+	}
+	planned.TaskDefinitionArn = aws.String(arn)
+	planned.Revision = aws.Int64(revision)
+	planned.Status = aws.String(ecs.TaskDefinitionStatusActive)
+	d.p.mu.Lock()
+	d.p.taskDefs[arn] = planned
+	d.p.taskDefs[fmt.Sprintf("%s:%d", family, revision)] = planned
+	d.p.taskDefs[family] = planned
+	d.p.mu.Unlock()
+	d.p.record(req)
+	return &ecs.RegisterTaskDefinitionOutput{TaskDefinition: planned}, nil
+}
+
+func (d *dryRunECS) UpdateService(input *ecs.UpdateServiceInput) (*ecs.UpdateServiceOutput, error) {
+	service := aws.StringValue(input.Service)
+	output, err := d.DescribeServices(&ecs.DescribeServicesInput{
+		Cluster:  input.Cluster,
+		Services: []*string{input.Service},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Services) == 0 {
+		return nil, fmt.Errorf("service %s not found", service)
+	}
+	current := output.Services[0]
+	updated := *current
+	req := &PlannedRequest{
+		Operation: "UpdateService",
+		Target:    aws.StringValue(input.Cluster) + "/" + service,
+		Request:   input,
+	}
+	from, to := make(map[string]string), make(map[string]string)
+	if input.TaskDefinition != nil {
+		setField(from, "taskDefinition", aws.String(path.Base(aws.StringValue(current.TaskDefinition))))
+		setField(to, "taskDefinition", aws.String(path.Base(aws.StringValue(input.TaskDefinition))))
+		updated.TaskDefinition = input.TaskDefinition
+		if from["taskDefinition"] != to["taskDefinition"] {
+			if req.TaskDefinition, err = d.diffTaskDefinitions(current.TaskDefinition, input.TaskDefinition); err != nil {
+				return nil, err
+			}
+			if def := d.p.plannedTaskDefinition(aws.StringValue(input.TaskDefinition)); def != nil {
+				updated.TaskDefinition = def.TaskDefinitionArn
+			}
+		}
+	}
+	if input.DesiredCount != nil {
+		setField(from, "desiredCount", formatInt64(current.DesiredCount))
+		setField(to, "desiredCount", formatInt64(input.DesiredCount))
+		updated.DesiredCount = input.DesiredCount
+	}
+	if aws.BoolValue(input.ForceNewDeployment) {
+		to["forceNewDeployment"] = "true"
+	}
+	req.Changes = diffFields(from, to)
+	d.p.record(req)
+	return &ecs.UpdateServiceOutput{Service: &updated}, nil
+}
+
+func (d *dryRunECS) diffTaskDefinitions(fromRef, toRef *string) (*TaskDefinitionDiff, error) {
+	from, err := d.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: fromRef})
+	if err != nil {
+		return nil, err
+	}
+	to, err := d.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: toRef})
+	if err != nil {
+		return nil, err
+	}
+	return DiffTaskDefinitions(from.TaskDefinition, to.TaskDefinition), nil
+}
+
+func (d *dryRunECS) RunTask(input *ecs.RunTaskInput) (*ecs.RunTaskOutput, error) {
+	d.p.record(&PlannedRequest{
+		Operation: "RunTask",
+		Target:    aws.StringValue(input.Cluster) + "/" + path.Base(aws.StringValue(input.TaskDefinition)),
+		Request:   input,
+		Create:    true,
+	})
+	return &ecs.RunTaskOutput{}, nil
+}
+
+func (d *dryRunECS) UpdateContainerAgent(input *ecs.UpdateContainerAgentInput) (*ecs.UpdateContainerAgentOutput, error) {
+	d.p.record(&PlannedRequest{
+		Operation: "UpdateContainerAgent",
+		Target:    aws.StringValue(input.Cluster) + "/" + path.Base(aws.StringValue(input.ContainerInstance)),
+		Request:   input,
+	})
+	return &ecs.UpdateContainerAgentOutput{}, nil
+}
+
+type dryRunEvents struct {
+	cloudwatcheventsiface.CloudWatchEventsAPI
+	p *planner
+}
+
+func (d *dryRunEvents) PutRule(input *cloudwatchevents.PutRuleInput) (*cloudwatchevents.PutRuleOutput, error) {
+	name := aws.StringValue(input.Name)
+	req := &PlannedRequest{Operation: "PutRule", Target: name, Request: input}
+	arn := fmt.Sprintf("arn:aws:events:%s:%s:rule/%s", d.p.client.Region, dryRunAccount, name)
+	current := &cloudwatchevents.DescribeRuleOutput{}
+	existing, err := d.DescribeRule(&cloudwatchevents.DescribeRuleInput{Name: input.Name})
+	switch {
+	case err == nil:
+		current = existing
+		arn = aws.StringValue(existing.Arn)
+	case isNotFound(err):
+		req.Create = true
+	default:
+		return nil, err
+	}
+	state := aws.StringValue(input.State)
+	if state == "" {
+		state = cloudwatchevents.RuleStateEnabled
+	}
+	from, to := make(map[string]string), make(map[string]string)
+	ruleFields(from, current.ScheduleExpression, current.EventPattern, current.Description)
+	setField(from, "state", current.State)
+	ruleFields(to, input.ScheduleExpression, input.EventPattern, input.Description)
+	setField(to, "state", aws.String(state))
+	req.Changes = diffFields(from, to)
+	d.p.record(req)
+	return &cloudwatchevents.PutRuleOutput{RuleArn: aws.String(arn)}, nil
+}
+
+func (d *dryRunEvents) PutTargets(input *cloudwatchevents.PutTargetsInput) (*cloudwatchevents.PutTargetsOutput, error) {
+	req := &PlannedRequest{Operation: "PutTargets", Target: aws.StringValue(input.Rule), Request: input}
+	from := make(map[string]string)
+	existing, err := d.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{Rule: input.Rule})
+	switch {
+	case err == nil:
+		for _, target := range existing.Targets {
+			targetFields(from, target)
+		}
+	case isNotFound(err):
+		req.Create = true
+	default:
+		return nil, err
+	}
+	to := make(map[string]string)
+	for _, target := range input.Targets {
+		targetFields(to, target)
+	}
+	// PutTargets adds or replaces targets by id, and leaves the others be
+	for key := range from {
+		if !targetPut(input.Targets, key) {
+			to[key] = from[key]
+		}
+	}
+	req.Changes = diffFields(from, to)
+	d.p.record(req)
+	return &cloudwatchevents.PutTargetsOutput{FailedEntryCount: aws.Int64(0)}, nil
+}
+
+func (d *dryRunEvents) RemoveTargets(input *cloudwatchevents.RemoveTargetsInput) (*cloudwatchevents.RemoveTargetsOutput, error) {
+	req := &PlannedRequest{Operation: "RemoveTargets", Target: aws.StringValue(input.Rule), Request: input}
+	existing, err := d.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{Rule: input.Rule})
+	if err != nil {
+		return nil, err
+	}
+	from := make(map[string]string)
+	for _, target := range existing.Targets {
+		for _, id := range input.Ids {
+			if aws.StringValue(id) == aws.StringValue(target.Id) {
+				targetFields(from, target)
+			}
+		}
+	}
+	req.Changes = diffFields(from, map[string]string{})
+	d.p.record(req)
+	return &cloudwatchevents.RemoveTargetsOutput{FailedEntryCount: aws.Int64(0)}, nil
+}
+
+func (d *dryRunEvents) DeleteRule(input *cloudwatchevents.DeleteRuleInput) (*cloudwatchevents.DeleteRuleOutput, error) {
+	req := &PlannedRequest{Operation: "DeleteRule", Target: aws.StringValue(input.Name), Request: input}
+	existing, err := d.DescribeRule(&cloudwatchevents.DescribeRuleInput{Name: input.Name})
+	if err != nil {
+		return nil, err
+	}
+	from := make(map[string]string)
+	ruleFields(from, existing.ScheduleExpression, existing.EventPattern, existing.Description)
+	req.Changes = diffFields(from, map[string]string{})
+	d.p.record(req)
+	return &cloudwatchevents.DeleteRuleOutput{}, nil
+}
+
+func ruleFields(fields map[string]string, scheduleExpression, eventPattern, description *string) {
+	setField(fields, "scheduleExpression", scheduleExpression)
+	setField(fields, "eventPattern", eventPattern)
+	setField(fields, "description", description)
+}
+
+func targetFields(fields map[string]string, target *cloudwatchevents.Target) {
+	prefix := "target." + aws.StringValue(target.Id) + "."
+	setField(fields, prefix+"arn", target.Arn)
+	setField(fields, prefix+"roleArn", target.RoleArn)
+	setField(fields, prefix+"input", target.Input)
+	if params := target.EcsParameters; params != nil {
+		setField(fields, prefix+"taskDefinition", aws.String(path.Base(aws.StringValue(params.TaskDefinitionArn))))
+		setField(fields, prefix+"taskCount", formatInt64(params.TaskCount))
+	}
+}
+
+// targetPut reports whether a target field key belongs to one of the
+// targets being put
+func targetPut(targets []*cloudwatchevents.Target, key string) bool {
+	for _, target := range targets {
+		if strings.HasPrefix(key, "target."+aws.StringValue(target.Id)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+type dryRunSSM struct {
+	ssmiface.SSMAPI
+	p *planner
+}
+
+func (d *dryRunSSM) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	masked := *input
+	masked.Value = aws.String(maskedSecret)
+	req := &PlannedRequest{Operation: "PutParameter", Target: aws.StringValue(input.Name), Request: &masked}
+	version := int64(1)
+	current, err := d.GetParameter(&ssm.GetParameterInput{Name: input.Name})
+	switch {
+	case err == nil:
+		version = aws.Int64Value(current.Parameter.Version) + 1
+		req.Changes = []FieldChange{{Field: "value", From: maskedSecret, To: maskedSecret}}
+	case isNotFound(err):
+		req.Create = true
+		req.Changes = []FieldChange{{Field: "value", To: maskedSecret, Added: true}}
+	default:
+		return nil, err
+	}
+	d.p.record(req)
+	return &ssm.PutParameterOutput{Version: aws.Int64(version)}, nil
+}
+
+type dryRunSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+	p *planner
+}
+
+// PutSecretValue fails like AWS does for secrets that do not exist, so
+// PutSecret goes on to plan creating them
+func (d *dryRunSecretsManager) PutSecretValue(input *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+	current, err := d.DescribeSecret(&secretsmanager.DescribeSecretInput{SecretId: input.SecretId})
+	if err != nil {
+		return nil, err
+	}
+	masked := *input
+	masked.SecretString = aws.String(maskedSecret)
+	d.p.record(&PlannedRequest{
+		Operation: "PutSecretValue",
+		Target:    aws.StringValue(input.SecretId),
+		Request:   &masked,
+		Changes:   []FieldChange{{Field: "value", From: maskedSecret, To: maskedSecret}},
+	})
+	return &secretsmanager.PutSecretValueOutput{ARN: current.ARN, Name: current.Name}, nil
+}
+
+func (d *dryRunSecretsManager) CreateSecret(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+	masked := *input
+	masked.SecretString = aws.String(maskedSecret)
+	name := aws.StringValue(input.Name)
+	d.p.record(&PlannedRequest{
+		Operation: "CreateSecret",
+		Target:    name,
+		Request:   &masked,
+		Create:    true,
+		Changes:   []FieldChange{{Field: "value", To: maskedSecret, Added: true}},
+	})
+	arn := fmt.Sprintf("arn:aws:secretsmanager:%s:%s:secret:%s", d.p.client.Region, dryRunAccount, name)
+	return &secretsmanager.CreateSecretOutput{ARN: aws.String(arn), Name: input.Name}, nil
+}
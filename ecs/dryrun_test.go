@@ -0,0 +1,269 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+type fakeEvents struct {
+	cloudwatcheventsiface.CloudWatchEventsAPI
+	rules   map[string]*cloudwatchevents.DescribeRuleOutput
+	targets map[string][]*cloudwatchevents.Target
+	puts    int
+}
+
+func (f *fakeEvents) ListRules(input *cloudwatchevents.ListRulesInput) (*cloudwatchevents.ListRulesOutput, error) {
+	out := &cloudwatchevents.ListRulesOutput{}
+	for name, rule := range f.rules {
+		if strings.HasPrefix(name, aws.StringValue(input.NamePrefix)) {
+			out.Rules = append(out.Rules, &cloudwatchevents.Rule{
+				Name:               aws.String(name),
+				Description:        rule.Description,
+				EventPattern:       rule.EventPattern,
+				ScheduleExpression: rule.ScheduleExpression,
+			})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEvents) DescribeRule(input *cloudwatchevents.DescribeRuleInput) (*cloudwatchevents.DescribeRuleOutput, error) {
+	rule, ok := f.rules[*input.Name]
+	if !ok {
+		return nil, awserr.New(cloudwatchevents.ErrCodeResourceNotFoundException, "rule not found", nil)
+	}
+	return rule, nil
+}
+
+func (f *fakeEvents) ListTargetsByRule(input *cloudwatchevents.ListTargetsByRuleInput) (*cloudwatchevents.ListTargetsByRuleOutput, error) {
+	if _, ok := f.rules[*input.Rule]; !ok {
+		return nil, awserr.New(cloudwatchevents.ErrCodeResourceNotFoundException, "rule not found", nil)
+	}
+	return &cloudwatchevents.ListTargetsByRuleOutput{Targets: f.targets[*input.Rule]}, nil
+}
+
+func (f *fakeEvents) PutRule(input *cloudwatchevents.PutRuleInput) (*cloudwatchevents.PutRuleOutput, error) {
+	f.puts++
+	f.rules[*input.Name] = &cloudwatchevents.DescribeRuleOutput{
+		Name:               input.Name,
+		ScheduleExpression: input.ScheduleExpression,
+		EventPattern:       input.EventPattern,
+		Description:        input.Description,
+		State:              aws.String(cloudwatchevents.RuleStateEnabled),
+	}
+	return &cloudwatchevents.PutRuleOutput{}, nil
+}
+
+func (f *fakeEvents) PutTargets(input *cloudwatchevents.PutTargetsInput) (*cloudwatchevents.PutTargetsOutput, error) {
+	f.puts++
+	for _, target := range input.Targets {
+		f.removeTarget(*input.Rule, *target.Id)
+		f.targets[*input.Rule] = append(f.targets[*input.Rule], target)
+	}
+	return &cloudwatchevents.PutTargetsOutput{}, nil
+}
+
+func (f *fakeEvents) RemoveTargets(input *cloudwatchevents.RemoveTargetsInput) (*cloudwatchevents.RemoveTargetsOutput, error) {
+	f.puts++
+	for _, id := range input.Ids {
+		f.removeTarget(*input.Rule, *id)
+	}
+	return &cloudwatchevents.RemoveTargetsOutput{}, nil
+}
+
+func (f *fakeEvents) removeTarget(rule, id string) {
+	kept := make([]*cloudwatchevents.Target, 0)
+	for _, target := range f.targets[rule] {
+		if *target.Id != id {
+			kept = append(kept, target)
+		}
+	}
+	f.targets[rule] = kept
+}
+
+func (f *fakeEvents) DeleteRule(input *cloudwatchevents.DeleteRuleInput) (*cloudwatchevents.DeleteRuleOutput, error) {
+	f.puts++
+	if len(f.targets[*input.Name]) > 0 {
+		return nil, fmt.Errorf("rule %s still has targets", *input.Name)
+	}
+	delete(f.rules, *input.Name)
+	return &cloudwatchevents.DeleteRuleOutput{}, nil
+}
+
+func TestDryRunDeployPlansWithoutWriting(t *testing.T) {
+	fake := newFakeECS()
+	def := testTaskDef("api", 1, "api:1")
+	fake.addService("qa", "api", def)
+	fake.taskDefs["api"] = def
+	reported := 0
+	client := &Client{ECS: fake, Region: "us-west-2"}
+	client.EnableDryRun(func(*PlannedRequest) { reported++ })
+
+	newTask, err := client.CreateNewTaskWithImage(def, "api:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *newTask.TaskDefinitionArn != "arn:aws:ecs:us-west-2:1:task-definition/api:2" {
+		t.Errorf("expected the next revision, got %s", *newTask.TaskDefinitionArn)
+	}
+	svc, err := client.DeployTaskToService("qa", "api", newTask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.registered) != 0 || len(fake.updates) != 0 {
+		t.Fatalf("expected no writes, got %d registrations and %d updates", len(fake.registered), len(fake.updates))
+	}
+	if *svc.TaskDefinition != *newTask.TaskDefinitionArn || *fake.services["qa/api"].TaskDefinition != *def.TaskDefinitionArn {
+		t.Errorf("expected only the planned service to change, got %s", *svc.TaskDefinition)
+	}
+	plan := client.Plan()
+	if len(plan) != 2 || reported != 2 {
+		t.Fatalf("expected 2 planned requests, got %d (%d reported)", len(plan), reported)
+	}
+	if plan[0].Operation != "RegisterTaskDefinition" || plan[0].Create {
+		t.Errorf("unexpected first request %+v", plan[0])
+	}
+	image := FieldChange{Field: "image", From: "api:1", To: "api:2"}
+	if diff := plan[0].TaskDefinition; diff == nil || !reflect.DeepEqual(diff.Containers[0].Changes, []FieldChange{image}) {
+		t.Errorf("expected the registration to change the image, got %+v", diff)
+	}
+	update := plan[1]
+	if update.Operation != "UpdateService" || update.Target != "qa/api" {
+		t.Errorf("unexpected second request %+v", update)
+	}
+	want := []FieldChange{{Field: "taskDefinition", From: "api:1", To: "api:2"}}
+	if !reflect.DeepEqual(update.Changes, want) {
+		t.Errorf("expected %v, got %v", want, update.Changes)
+	}
+	if update.TaskDefinition == nil || !reflect.DeepEqual(update.TaskDefinition.Containers[0].Changes, []FieldChange{image}) {
+		t.Errorf("expected the update to diff against the deployed task definition, got %+v", update.TaskDefinition)
+	}
+	body, err := update.RequestJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, `"taskDefinition": "arn:aws:ecs:us-west-2:1:task-definition/api:2"`) {
+		t.Errorf("expected the request as sent on the wire, got\n%s", body)
+	}
+}
+
+func TestDryRunScheduledTask(t *testing.T) {
+	fake := newFakeECS()
+	fake.clusters = []string{"qa"}
+	def := testTaskDef("api", 1, "api:1")
+	def.TaskRoleArn = aws.String("arn:aws:iam::1:role/api")
+	fake.addService("qa", "api", def)
+	events := &fakeEvents{
+		rules: map[string]*cloudwatchevents.DescribeRuleOutput{
+			"qa-api-report": {
+				Name:               aws.String("qa-api-report"),
+				Arn:                aws.String("arn:aws:events:us-west-2:1:rule/qa-api-report"),
+				ScheduleExpression: aws.String("rate(1 hour)"),
+				Description:        aws.String("Schedule Expression for api Service in qa ECS Cluster"),
+				State:              aws.String(cloudwatchevents.RuleStateEnabled),
+			},
+		},
+		targets: map[string][]*cloudwatchevents.Target{
+			"qa-api-report": {{
+				Id:            aws.String("1"),
+				Arn:           aws.String("arn:aws:ecs:us-west-2:1:cluster/qa"),
+				RoleArn:       def.TaskRoleArn,
+				EcsParameters: &cloudwatchevents.EcsParameters{TaskDefinitionArn: def.TaskDefinitionArn, TaskCount: aws.Int64(1)},
+			}},
+		},
+	}
+	client := &Client{ECS: fake, CloudWatchEvents: events}
+	client.EnableDryRun(nil)
+
+	if err := client.CreateScheduledTask("qa", "api", "report", "rate(1 day)", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateScheduledTask("qa", "api", "cleanup", "rate(1 day)", ""); err != nil {
+		t.Fatal(err)
+	}
+	if events.puts != 0 {
+		t.Fatalf("expected no writes, got %d", events.puts)
+	}
+	plan := client.Plan()
+	if len(plan) != 4 {
+		t.Fatalf("expected 4 planned requests, got %d", len(plan))
+	}
+	want := []FieldChange{{Field: "scheduleExpression", From: "rate(1 hour)", To: "rate(1 day)"}}
+	if plan[0].Operation != "PutRule" || plan[0].Create || !reflect.DeepEqual(plan[0].Changes, want) {
+		t.Errorf("expected the existing rule's schedule to change, got %+v", plan[0])
+	}
+	if plan[1].Operation != "PutTargets" || len(plan[1].Changes) != 0 {
+		t.Errorf("expected the existing target to be unchanged, got %+v", plan[1])
+	}
+	if !plan[2].Create || !plan[3].Create {
+		t.Errorf("expected the new rule and its target to be created, got %+v %+v", plan[2], plan[3])
+	}
+	fields := make([]string, len(plan[3].Changes))
+	for i, change := range plan[3].Changes {
+		fields[i] = change.Field + "=" + change.To
+	}
+	wantFields := []string{"target.1.arn=arn:aws:ecs:us-west-2:1:cluster/qa", "target.1.roleArn=arn:aws:iam::1:role/api", "target.1.taskCount=1", "target.1.taskDefinition=api:1"}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("expected %v, got %v", wantFields, fields)
+	}
+}
+
+func (f *fakeSSM) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	if _, ok := f.params[*input.Name]; !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "not found", nil)
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Name: input.Name, Version: aws.Int64(3)}}, nil
+}
+
+func TestDryRunMasksSecrets(t *testing.T) {
+	fake := &fakeSSM{params: map[string]string{}}
+	client := &Client{SSM: fake}
+	client.EnableDryRun(nil)
+	ref, err := client.PutSecret(SecretStoreSSM, "/qa/api/DB_PASSWORD", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != "/qa/api/DB_PASSWORD" || len(fake.params) != 0 {
+		t.Errorf("expected the parameter to be planned only, got %s %v", ref, fake.params)
+	}
+	plan := client.Plan()
+	if len(plan) != 1 || !plan[0].Create {
+		t.Fatalf("expected a planned creation, got %+v", plan)
+	}
+	body, err := plan[0].RequestJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(body, "hunter2") || !strings.Contains(body, maskedSecret) {
+		t.Errorf("expected the value to be masked, got\n%s", body)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{awserr.New(cloudwatchevents.ErrCodeResourceNotFoundException, "Rule qa-api-report does not exist.", nil), true},
+		{awserr.New(ssm.ErrCodeParameterNotFound, "", nil), true},
+		{awserr.New(ecs.ErrCodeClientException, "Unable to describe task definition.", nil), true},
+		{awserr.New(ecs.ErrCodeServiceNotFoundException, "Service not found.", nil), true},
+		{awserr.New(ecs.ErrCodeClientException, "User is not authorized to perform that action.", nil), false},
+		{awserr.New(ecs.ErrCodeClientException, "Too many concurrent attempts to create a new revision of the specified family.", nil), false},
+		{fmt.Errorf("not found"), false},
+	}
+	for _, tt := range tests {
+		if got := isNotFound(tt.err); got != tt.want {
+			t.Errorf("%v: expected %v, got %v", tt.err, tt.want, got)
+		}
+	}
+}
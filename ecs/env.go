@@ -0,0 +1,171 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Formats env get can print variables in
+const (
+	EnvFormatDotenv      = "dotenv"
+	EnvFormatJSON        = "json"
+	EnvFormatShellExport = "shell-export"
+)
+
+// FormatEnvVars renders variables as a .env block, a JSON object or shell
+// export statements. Secrets are rendered as their store:reference value.
+func FormatEnvVars(vars []EnvVar, format string) (string, error) {
+	switch format {
+	case EnvFormatDotenv, "":
+		return EnvVarsToString(vars), nil
+	case EnvFormatJSON:
+		object := make(map[string]string, len(vars))
+		for _, v := range vars {
+			object[v.Name] = v.reference()
+		}
+		out, err := json.MarshalIndent(object, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(out) + "\n", nil
+	case EnvFormatShellExport:
+		var b strings.Builder
+		for _, v := range vars {
+			fmt.Fprintf(&b, "export %s=%s\n", v.Name, shellQuote(v.reference()))
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected %s, %s or %s", format, EnvFormatDotenv, EnvFormatJSON, EnvFormatShellExport)
+}
+
+// reference is the unquoted value of a variable, prefixed with its store
+// for secrets
+func (v EnvVar) reference() string {
+	if v.IsSecret() {
+		return v.Store + ":" + v.Value
+	}
+	return v.Value
+}
+
+// MergeEnvVars sets each of updates on top of vars, keeping the order of
+// vars and appending new names
+func MergeEnvVars(vars, updates []EnvVar) []EnvVar {
+	out := append([]EnvVar{}, vars...)
+	for _, v := range updates {
+		out = SetEnvVar(out, v)
+	}
+	return out
+}
+
+// RemoveEnvVars removes the named variables, returning the names that
+// were not set
+func RemoveEnvVars(vars []EnvVar, names []string) ([]EnvVar, []string) {
+	out := make([]EnvVar, 0, len(vars))
+	removed := make(map[string]bool, len(names))
+	for _, v := range vars {
+		if containsString(names, v.Name) {
+			removed[v.Name] = true
+			continue
+		}
+		out = append(out, v)
+	}
+	missing := make([]string, 0)
+	for _, name := range names {
+		if !removed[name] {
+			missing = append(missing, name)
+		}
+	}
+	return out, missing
+}
+
+// EnvVarsEqual reports whether two lists hold the same variables, in any
+// order
+func EnvVarsEqual(a, b []EnvVar) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	byName := make(map[string]EnvVar, len(a))
+	for _, v := range a {
+		byName[v.Name] = v
+	}
+	for _, v := range b {
+		if existing, ok := byName[v.Name]; !ok || existing != v {
+			return false
+		}
+	}
+	return true
+}
+
+// EnvChange is a variable that differs between two environments. From is
+// nil for added variables, To is nil for removed ones.
+type EnvChange struct {
+	Name string
+	From *EnvVar
+	To   *EnvVar
+}
+
+// DiffEnvVars compares two environments, ordered by variable name
+func DiffEnvVars(from, to []EnvVar) []EnvChange {
+	fromByName := make(map[string]EnvVar, len(from))
+	names := make([]string, 0, len(from)+len(to))
+	for _, v := range from {
+		fromByName[v.Name] = v
+		names = append(names, v.Name)
+	}
+	toByName := make(map[string]EnvVar, len(to))
+	for _, v := range to {
+		toByName[v.Name] = v
+		if _, ok := fromByName[v.Name]; !ok {
+			names = append(names, v.Name)
+		}
+	}
+	sort.Strings(names)
+	changes := make([]EnvChange, 0)
+	for _, name := range names {
+		a, inFrom := fromByName[name]
+		b, inTo := toByName[name]
+		if inFrom && inTo && a == b {
+			continue
+		}
+		change := EnvChange{Name: name}
+		if inFrom {
+			change.From = &a
+		}
+		if inTo {
+			change.To = &b
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// MaskedValue is the value of a variable safe to print: secret references
+// as they are, plain values hidden unless reveal is set
+func (v EnvVar) MaskedValue(reveal bool) string {
+	if reveal || v.IsSecret() || v.Value == "" {
+		return v.reference()
+	}
+	return "****"
+}
+
+// SelectEnvVars picks the named variables, in the order named, returning
+// an error if any is not set
+func SelectEnvVars(vars []EnvVar, names []string) ([]EnvVar, error) {
+	out := make([]EnvVar, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, v := range vars {
+			if v.Name == name {
+				out = append(out, v)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s is not set", name)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,177 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestStringToKeyPairs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"plain", "A=1\nB=two words\n", map[string]string{"A": "1", "B": "two words"}, false},
+		{"comments and blanks", "# header\n\nA=1 # trailing\n  # indented\nB=x#y\n", map[string]string{"A": "1", "B": "x#y"}, false},
+		{"export prefix", "export A=1\nexport\tB=2", map[string]string{"A": "1", "B": "2"}, false},
+		{"spaces around =", "A = 1 ", map[string]string{"A": "1"}, false},
+		{"empty value", "A=\nB=''", map[string]string{"A": "", "B": ""}, false},
+		{"value with =", "URL=postgres://u:p@h/db?sslmode=require", map[string]string{"URL": "postgres://u:p@h/db?sslmode=require"}, false},
+		{"double quoted", `A="a \"quoted\" # not a comment\tand tab"`, map[string]string{"A": "a \"quoted\" # not a comment\tand tab"}, false},
+		{"single quoted is literal", `A='$HOME \n "x"'`, map[string]string{"A": `$HOME \n "x"`}, false},
+		{"multi-line", "KEY=\"-----BEGIN-----\nabc\n-----END-----\"\nNEXT=1", map[string]string{"KEY": "-----BEGIN-----\nabc\n-----END-----", "NEXT": "1"}, false},
+		{"escaped newline", `A="line1\nline2"`, map[string]string{"A": "line1\nline2"}, false},
+		{"crlf", "A=1\r\nB=2\r\n", map[string]string{"A": "1", "B": "2"}, false},
+		{"missing =", "A=1\nnope", nil, true},
+		{"unterminated quote", "A=\"open\nB=2", nil, true},
+		{"junk after quote", `A="x" y`, nil, true},
+		{"space in name", "MY VAR=1", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, err := StringToKeyPairs(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make(map[string]string, len(pairs))
+			for _, pair := range pairs {
+				got[*pair.Name] = *pair.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAssignmentsToKeyPairs(t *testing.T) {
+	pairs, err := AssignmentsToKeyPairs([]string{"MSG='it", "Q=a #b", "URL=h?x=1", "EMPTY=", "NEXT=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"MSG", "'it"}, {"Q", "a #b"}, {"URL", "h?x=1"}, {"EMPTY", ""}, {"NEXT", "1"}}
+	if len(pairs) != len(want) {
+		t.Fatalf("expected %d pairs, got %v", len(want), pairs)
+	}
+	for i, pair := range pairs {
+		if *pair.Name != want[i][0] || *pair.Value != want[i][1] {
+			t.Errorf("expected %s=%s, got %s=%s", want[i][0], want[i][1], *pair.Name, *pair.Value)
+		}
+	}
+	for _, bad := range []string{"NOPE", "=1"} {
+		if _, err := AssignmentsToKeyPairs([]string{bad}); err == nil {
+			t.Errorf("expected %q to be an error", bad)
+		}
+	}
+}
+
+func TestKeyPairsRoundTrip(t *testing.T) {
+	values := []string{"plain", "", "a # b", "\"quoted\"", "'single'", " padded ", "multi\nline\r\n", `back\slash`, "tab\there"}
+	for _, value := range values {
+		block := KeyPairsToString(keyPairs("K", value))
+		pairs, err := StringToKeyPairs(block)
+		if err != nil {
+			t.Errorf("%q did not parse back: %v", block, err)
+			continue
+		}
+		if len(pairs) != 1 || *pairs[0].Value != value {
+			t.Errorf("%q round tripped to %v", value, pairs)
+		}
+	}
+}
+
+func TestFormatEnvVars(t *testing.T) {
+	vars := []EnvVar{
+		{Name: "A", Value: "it's"},
+		{Name: "DB_PASSWORD", Value: "/qa/db", Store: SecretStoreSSM},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{EnvFormatDotenv, "A=it's\nDB_PASSWORD=ssm:/qa/db\n"},
+		{EnvFormatJSON, "{\n  \"A\": \"it's\",\n  \"DB_PASSWORD\": \"ssm:/qa/db\"\n}\n"},
+		{EnvFormatShellExport, "export A='it'\\''s'\nexport DB_PASSWORD='ssm:/qa/db'\n"},
+	}
+	for _, tt := range tests {
+		got, err := FormatEnvVars(vars, tt.format)
+		if err != nil {
+			t.Errorf("%s: %v", tt.format, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.format, tt.want, got)
+		}
+	}
+	if _, err := FormatEnvVars(vars, "xml"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestMergeAndRemoveEnvVars(t *testing.T) {
+	vars := []EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "/b", Store: SecretStoreSSM}}
+	merged := MergeEnvVars(vars, []EnvVar{{Name: "B", Value: "2"}, {Name: "C", Value: "3"}})
+	want := []EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}, {Name: "C", Value: "3"}}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("expected %v, got %v", want, merged)
+	}
+	if vars[1].Store != SecretStoreSSM {
+		t.Errorf("merge must not modify its input, got %v", vars)
+	}
+	removed, missing := RemoveEnvVars(merged, []string{"A", "C", "Z"})
+	if !reflect.DeepEqual(removed, []EnvVar{{Name: "B", Value: "2"}}) || !reflect.DeepEqual(missing, []string{"Z"}) {
+		t.Errorf("unexpected removal result %v, missing %v", removed, missing)
+	}
+	if !EnvVarsEqual(want, []EnvVar{want[2], want[0], want[1]}) {
+		t.Errorf("expected order not to matter")
+	}
+	if EnvVarsEqual(want, merged[:2]) || EnvVarsEqual(vars, []EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "/b"}}) {
+		t.Errorf("expected differing lists not to be equal")
+	}
+}
+
+func keyPairs(name, value string) []*ecs.KeyValuePair {
+	return []*ecs.KeyValuePair{{Name: aws.String(name), Value: aws.String(value)}}
+}
+
+func TestDiffEnvVars(t *testing.T) {
+	from := []EnvVar{{Name: "SAME", Value: "1"}, {Name: "CHANGED", Value: "a"}, {Name: "GONE", Value: "x"}, {Name: "EMPTY", Value: ""}}
+	to := []EnvVar{{Name: "SAME", Value: "1"}, {Name: "CHANGED", Value: "/changed", Store: SecretStoreSSM}, {Name: "EMPTY", Value: ""}, {Name: "ADDED", Value: ""}}
+	changes := DiffEnvVars(from, to)
+	got := make([]string, len(changes))
+	for i, change := range changes {
+		got[i] = change.Name
+		if change.From == nil {
+			got[i] = "+" + got[i] + "=" + change.To.MaskedValue(true)
+		} else if change.To == nil {
+			got[i] = "-" + got[i] + "=" + change.From.MaskedValue(true)
+		} else {
+			got[i] = "~" + got[i] + "=" + change.From.MaskedValue(false) + ">" + change.To.MaskedValue(false)
+		}
+	}
+	want := []string{"+ADDED=", "~CHANGED=****>ssm:/changed", "-GONE=x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSelectEnvVars(t *testing.T) {
+	vars := []EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}, {Name: "C", Value: "3"}}
+	selected, err := SelectEnvVars(vars, []string{"C", "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(selected, []EnvVar{vars[2], vars[0]}) {
+		t.Errorf("unexpected selection %v", selected)
+	}
+	if _, err := SelectEnvVars(vars, []string{"A", "Z"}); err == nil {
+		t.Errorf("expected an error for a variable that is not set")
+	}
+}
@@ -0,0 +1,300 @@
+package ecs
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// DefaultSearchConcurrency is the number of parallel AWS calls SearchEnv
+// makes when EnvSearch.Concurrency is not set
+const DefaultSearchConcurrency = 8
+
+// maxDescribeServices is the most services DescribeServices accepts
+const maxDescribeServices = 10
+
+// throttleRetries and throttleBackoff bound retries of throttled calls,
+// on top of the SDK's own retries
+var throttleRetries = 5
+var throttleBackoff = 500 * time.Millisecond
+
+// Fields of a container SearchEnv can match
+const (
+	EnvMatchEnv    = "env"
+	EnvMatchSecret = "secret"
+	EnvMatchImage  = "image"
+)
+
+// EnvSearch selects the variables (and images) SearchEnv looks for
+type EnvSearch struct {
+	// Name is the variable name, matched exactly unless Regexp is set.
+	// Leave it empty to only search images.
+	Name string
+	// Value is contained in matching values (plain values and secret
+	// references), or matched as a regular expression if Regexp is set.
+	// Empty matches any value.
+	Value string
+	// Image is contained in matching container images (or matched as a
+	// regular expression if Regexp is set)
+	Image string
+	// Regexp treats Name, Value and Image as regular expressions
+	Regexp bool
+	// Concurrency bounds parallel AWS calls
+	Concurrency int
+}
+
+// EnvMatch is a container variable or image that matched a search
+type EnvMatch struct {
+	Cluster        string
+	Service        string
+	TaskDefinition string
+	Container      string
+	Field          string
+	Name           string
+	Value          string
+}
+
+// EnvSearchResult holds the matches of a search, and the errors of the
+// clusters, services or task definitions that could not be searched
+type EnvSearchResult struct {
+	Matches []EnvMatch
+	Errors  []error
+}
+
+type envMatcher struct {
+	name, value, image func(string) bool
+}
+
+func (s EnvSearch) matcher() (*envMatcher, error) {
+	compile := func(pattern string, exact bool) (func(string) bool, error) {
+		if s.Regexp {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			return re.MatchString, nil
+		}
+		if exact {
+			return func(v string) bool { return v == pattern }, nil
+		}
+		return func(v string) bool { return strings.Contains(v, pattern) }, nil
+	}
+	m := &envMatcher{}
+	var err error
+	if s.Name != "" {
+		if m.name, err = compile(s.Name, true); err != nil {
+			return nil, err
+		}
+		if m.value, err = compile(s.Value, false); err != nil {
+			return nil, err
+		}
+	}
+	if s.Image != "" {
+		if m.image, err = compile(s.Image, false); err != nil {
+			return nil, err
+		}
+	}
+	if m.name == nil && m.image == nil {
+		return nil, fmt.Errorf("nothing to search for, give a variable name or an image")
+	}
+	return m, nil
+}
+
+func (m *envMatcher) match(container *ecs.ContainerDefinition) []EnvMatch {
+	out := make([]EnvMatch, 0)
+	if m.name != nil {
+		for _, v := range ContainerEnv(container) {
+			if !m.name(v.Name) || !m.value(v.Value) {
+				continue
+			}
+			field := EnvMatchEnv
+			if v.IsSecret() {
+				field = EnvMatchSecret
+			}
+			out = append(out, EnvMatch{Field: field, Name: v.Name, Value: v.reference()})
+		}
+	}
+	if m.image != nil && m.image(aws.StringValue(container.Image)) {
+		out = append(out, EnvMatch{Field: EnvMatchImage, Name: "image", Value: aws.StringValue(container.Image)})
+	}
+	return out
+}
+
+// SearchEnv searches the containers of every service of every cluster for
+// variables (plain or secret) and images. Clusters are listed in parallel,
+// services described in batches of ten and each task definition described
+// once, with at most Concurrency calls in flight. Anything that cannot be
+// searched is reported in the result's Errors rather than stopping the
+// search.
+func (c *Client) SearchEnv(search EnvSearch) (*EnvSearchResult, error) {
+	m, err := search.matcher()
+	if err != nil {
+		return nil, err
+	}
+	concurrency := search.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSearchConcurrency
+	}
+	clusters, err := c.ListClusters()
+	if err != nil {
+		return nil, err
+	}
+	result := &EnvSearchResult{Matches: make([]EnvMatch, 0)}
+	var mu sync.Mutex
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Errors = append(result.Errors, err)
+	}
+	sem := make(chan struct{}, concurrency)
+	// a slot is held for each attempt only, so backing off from a
+	// throttled call leaves room for the others
+	limited := func(fn func() error) error {
+		return retryThrottled(func() error {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			return fn()
+		})
+	}
+
+	services := make([]*ecs.Service, 0)
+	wg := &sync.WaitGroup{}
+	for _, cluster := range clusters {
+		wg.Add(1)
+		go func(cluster string) {
+			defer wg.Done()
+			found, err := c.describeAllServices(cluster, limited)
+			if err != nil {
+				fail(fmt.Errorf("cluster %s: %v", cluster, err))
+			}
+			mu.Lock()
+			services = append(services, found...)
+			mu.Unlock()
+		}(cluster)
+	}
+	wg.Wait()
+
+	arns := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, service := range services {
+		if arn := aws.StringValue(service.TaskDefinition); !seen[arn] {
+			seen[arn] = true
+			arns = append(arns, arn)
+		}
+	}
+	taskDefs := make(map[string]*ecs.TaskDefinition, len(arns))
+	for _, arn := range arns {
+		wg.Add(1)
+		go func(arn string) {
+			defer wg.Done()
+			var def *ecs.TaskDefinition
+			err := limited(func() error {
+				var err error
+				def, err = c.GetTaskDefinition(arn)
+				return err
+			})
+			if err != nil {
+				fail(fmt.Errorf("task definition %s: %v", path.Base(arn), err))
+				return
+			}
+			mu.Lock()
+			taskDefs[arn] = def
+			mu.Unlock()
+		}(arn)
+	}
+	wg.Wait()
+
+	for _, service := range services {
+		def := taskDefs[aws.StringValue(service.TaskDefinition)]
+		if def == nil {
+			continue
+		}
+		for _, container := range def.ContainerDefinitions {
+			for _, match := range m.match(container) {
+				match.Cluster = path.Base(aws.StringValue(service.ClusterArn))
+				match.Service = aws.StringValue(service.ServiceName)
+				match.TaskDefinition = path.Base(aws.StringValue(def.TaskDefinitionArn))
+				match.Container = aws.StringValue(container.Name)
+				result.Matches = append(result.Matches, match)
+			}
+		}
+	}
+	sort.Slice(result.Matches, func(i, j int) bool {
+		a, b := result.Matches[i], result.Matches[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Container != b.Container {
+			return a.Container < b.Container
+		}
+		return a.Name < b.Name
+	})
+	return result, nil
+}
+
+// describeAllServices lists and describes the services of a cluster,
+// returning the services it could describe along with the first error
+func (c *Client) describeAllServices(cluster string, limited func(func() error) error) ([]*ecs.Service, error) {
+	svc := c.ECS
+	arns := make([]*string, 0)
+	err := limited(func() error {
+		arns = arns[:0]
+		return svc.ListServicesPages(&ecs.ListServicesInput{Cluster: aws.String(cluster)}, func(page *ecs.ListServicesOutput, lastPage bool) bool {
+			arns = append(arns, page.ServiceArns...)
+			return !lastPage
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*ecs.Service, 0, len(arns))
+	var firstErr error
+	for start := 0; start < len(arns); start += maxDescribeServices {
+		end := start + maxDescribeServices
+		if end > len(arns) {
+			end = len(arns)
+		}
+		var output *ecs.DescribeServicesOutput
+		err := limited(func() error {
+			var err error
+			output, err = svc.DescribeServices(&ecs.DescribeServicesInput{
+				Cluster:  aws.String(cluster),
+				Services: arns[start:end],
+			})
+			return err
+		})
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		services = append(services, output.Services...)
+	}
+	return services, firstErr
+}
+
+// retryThrottled retries a call while AWS reports it as throttled, backing
+// off exponentially
+func retryThrottled(fn func() error) error {
+	backoff := throttleBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !request.IsErrorThrottle(err) || attempt >= throttleRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,171 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// fakeSearchECS adds cluster and service listing to fakeECS, counts task
+// definition lookups and throttles the first few of them
+type fakeSearchECS struct {
+	*fakeECS
+	mu        sync.Mutex
+	describes map[string]int
+	throttle  int
+	broken    string
+}
+
+func (f *fakeSearchECS) ListClustersPages(input *ecs.ListClustersInput, fn func(*ecs.ListClustersOutput, bool) bool) error {
+	out, _ := f.ListClusters(input)
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeSearchECS) ListServicesPages(input *ecs.ListServicesInput, fn func(*ecs.ListServicesOutput, bool) bool) error {
+	if *input.Cluster == f.broken {
+		return fmt.Errorf("AccessDeniedException")
+	}
+	out := &ecs.ListServicesOutput{}
+	for key, s := range f.services {
+		if strings.HasPrefix(key, *input.Cluster+"/") {
+			out.ServiceArns = append(out.ServiceArns, s.ServiceArn)
+		}
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeSearchECS) DescribeTaskDefinition(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+	f.mu.Lock()
+	if f.throttle > 0 {
+		f.throttle--
+		f.mu.Unlock()
+		return nil, awserr.New("ThrottlingException", "Rate exceeded", nil)
+	}
+	f.describes[*input.TaskDefinition]++
+	f.mu.Unlock()
+	return f.fakeECS.DescribeTaskDefinition(input)
+}
+
+func searchTestClient() (*Client, *fakeSearchECS) {
+	fake := &fakeSearchECS{fakeECS: newFakeECS(), describes: make(map[string]int), throttle: 2, broken: "legacy"}
+	fake.clusters = []string{"qa", "prod", "legacy"}
+	shared := testTaskDef("worker", 3, "worker:v3")
+	shared.ContainerDefinitions[0].Environment = append(shared.ContainerDefinitions[0].Environment,
+		&ecs.KeyValuePair{Name: aws.String("REDIS_URL"), Value: aws.String("redis://cache-1:6379")})
+	// a dozen services sharing one task definition, more than one
+	// DescribeServices call can take
+	for i := 0; i < 12; i++ {
+		fake.addService("qa", fmt.Sprintf("worker-%02d", i), shared)
+	}
+	api := testTaskDef("api", 7, "nginx:1.19")
+	api.ContainerDefinitions[0].Secrets = []*ecs.Secret{{Name: aws.String("REDIS_URL"), ValueFrom: aws.String("/prod/redis")}}
+	fake.addService("prod", "api", api)
+	return &Client{ECS: fake}, fake
+}
+
+func TestSearchEnv(t *testing.T) {
+	prev := throttleBackoff
+	throttleBackoff = time.Millisecond
+	defer func() { throttleBackoff = prev }()
+
+	client, fake := searchTestClient()
+	result, err := client.SearchEnv(EnvSearch{Name: "REDIS_URL", Concurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Matches) != 13 {
+		t.Fatalf("expected 12 workers and the api to match, got %d: %+v", len(result.Matches), result.Matches)
+	}
+	if m := result.Matches[0]; m.Cluster != "prod" || m.Service != "api" || m.Field != EnvMatchSecret || m.Value != "ssm:/prod/redis" {
+		t.Errorf("expected the api secret to match first, got %+v", m)
+	}
+	if m := result.Matches[1]; m.Cluster != "qa" || m.Service != "worker-00" || m.TaskDefinition != "worker:3" || m.Field != EnvMatchEnv {
+		t.Errorf("unexpected worker match %+v", m)
+	}
+	for arn, n := range fake.describes {
+		if n != 1 {
+			t.Errorf("expected %s to be described once, got %d", arn, n)
+		}
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error(), "legacy") {
+		t.Errorf("expected the legacy cluster to be reported, got %v", result.Errors)
+	}
+}
+
+func TestSearchEnvMatching(t *testing.T) {
+	prev := throttleBackoff
+	throttleBackoff = time.Millisecond
+	defer func() { throttleBackoff = prev }()
+
+	client, _ := searchTestClient()
+	tests := []struct {
+		search EnvSearch
+		want   []string
+	}{
+		{EnvSearch{Name: "REDIS_URL", Value: "cache-1"}, []string{"worker-00"}},
+		{EnvSearch{Name: "REDIS", Value: ""}, []string{}},
+		{EnvSearch{Name: "^(REDIS|CACHE)_URL$", Value: "^/prod/", Regexp: true}, []string{"api"}},
+		{EnvSearch{Image: "nginx"}, []string{"api"}},
+		{EnvSearch{Name: "APP_ENV", Value: "qa", Image: "nginx"}, []string{"api", "api", "worker-00"}},
+	}
+	for _, tt := range tests {
+		result, err := client.SearchEnv(tt.search)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.search, err)
+			continue
+		}
+		got := make([]string, 0)
+		for _, m := range result.Matches {
+			if m.Service == "api" || m.Service == "worker-00" {
+				got = append(got, m.Service)
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: expected %v, got %v", tt.search, tt.want, got)
+		}
+	}
+	if _, err := client.SearchEnv(EnvSearch{}); err == nil {
+		t.Errorf("expected an error when searching for nothing")
+	}
+	if _, err := client.SearchEnv(EnvSearch{Name: "(", Regexp: true}); err == nil {
+		t.Errorf("expected an error for an invalid regular expression")
+	}
+}
+
+func TestFindServicesWithEnvVar(t *testing.T) {
+	prev := throttleBackoff
+	throttleBackoff = time.Millisecond
+	defer func() { throttleBackoff = prev }()
+
+	client, fake := searchTestClient()
+	fake.broken = ""
+	tests := []struct {
+		name, value string
+		want        int
+	}{
+		{"REDIS_URL", "", 12},
+		{"REDIS_URL", "redis://cache-1:6379", 12},
+		{"REDIS_URL", "cache-1", 0},
+		{"REDIS", "", 0},
+	}
+	for _, tt := range tests {
+		services, err := client.FindServicesWithEnvVar(tt.name, tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(services) != tt.want {
+			t.Errorf("%s=%s: expected %d services, got %d", tt.name, tt.value, tt.want, len(services))
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// ServiceExport is a running service in the shape of the requests that
+// would recreate it, and as a spec apply can manage it with
+type ServiceExport struct {
+	// TaskDefinition is the deployed task definition without its read-only
+	// fields, tags included
+	TaskDefinition *ecs.RegisterTaskDefinitionInput
+	Service        *ecs.CreateServiceInput
+	// Rules are the scheduled and post-deployment rules ecsy created for
+	// the service, when asked for
+	Rules []ExportedRule
+	Spec  *ServiceSpec
+}
+
+// ExportedRule is a scheduled or post-deployment rule with its targets
+type ExportedRule struct {
+	Kind    string
+	Rule    *cloudwatchevents.PutRuleInput
+	Targets *cloudwatchevents.PutTargetsInput
+}
+
+// ExportService reads a service, its deployed task definition and
+// optionally its scheduled and post-deployment rules
+func (c *Client) ExportService(cluster, service string, withRules bool) (*ServiceExport, error) {
+	result, err := c.ECS.DescribeServices(&ecs.DescribeServicesInput{
+		Cluster:  aws.String(cluster),
+		Services: []*string{aws.String(service)},
+		Include:  []*string{aws.String(ecs.ServiceFieldTags)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Services) != 1 {
+		return nil, fmt.Errorf("did not find one (%d) services matching name %s in %s cluster, unable to continue", len(result.Services), service, cluster)
+	}
+	svc := result.Services[0]
+	def, err := c.GetTaskDefinition(aws.StringValue(svc.TaskDefinition))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find current task definition: %v", err)
+	}
+	input, err := RegisterInputFromTaskDefinition(def)
+	if err != nil {
+		return nil, err
+	}
+	if input.Tags, err = c.taskDefinitionTags(aws.StringValue(def.TaskDefinitionArn)); err != nil {
+		return nil, err
+	}
+	out := &ServiceExport{
+		TaskDefinition: input,
+		Service:        createServiceInput(cluster, svc, def),
+		Spec:           specFromService(cluster, svc, def),
+	}
+	if !withRules {
+		return out, nil
+	}
+	rules, err := c.serviceRules(cluster, service)
+	if err != nil {
+		return nil, err
+	}
+	out.Spec.Schedules = make([]ScheduleSpec, 0)
+	out.Spec.PostDeployment = make([]PostDeploymentSpec, 0)
+	for _, rule := range rules {
+		out.Rules = append(out.Rules, ExportedRule{
+			Kind: rule.kind,
+			Rule: &cloudwatchevents.PutRuleInput{
+				Name:               rule.rule.Name,
+				Description:        rule.rule.Description,
+				EventPattern:       rule.rule.EventPattern,
+				ScheduleExpression: rule.rule.ScheduleExpression,
+				State:              rule.rule.State,
+			},
+			Targets: &cloudwatchevents.PutTargetsInput{Rule: rule.rule.Name, Targets: rule.targets},
+		})
+		if err = c.addRuleToSpec(out.Spec, rule); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// createServiceInput converts a described service into the request that
+// would create it again. The service linked role is left out, as ECS
+// refuses it being passed explicitly.
+func createServiceInput(cluster string, svc *ecs.Service, def *ecs.TaskDefinition) *ecs.CreateServiceInput {
+	input := &ecs.CreateServiceInput{
+		Cluster:                       aws.String(cluster),
+		ServiceName:                   svc.ServiceName,
+		TaskDefinition:                aws.String(fmt.Sprintf("%s:%d", aws.StringValue(def.Family), aws.Int64Value(def.Revision))),
+		DesiredCount:                  svc.DesiredCount,
+		LaunchType:                    svc.LaunchType,
+		CapacityProviderStrategy:      svc.CapacityProviderStrategy,
+		PlatformVersion:               svc.PlatformVersion,
+		SchedulingStrategy:            svc.SchedulingStrategy,
+		DeploymentConfiguration:       svc.DeploymentConfiguration,
+		DeploymentController:          svc.DeploymentController,
+		NetworkConfiguration:          svc.NetworkConfiguration,
+		LoadBalancers:                 svc.LoadBalancers,
+		ServiceRegistries:             svc.ServiceRegistries,
+		HealthCheckGracePeriodSeconds: svc.HealthCheckGracePeriodSeconds,
+		PlacementConstraints:          svc.PlacementConstraints,
+		PlacementStrategy:             svc.PlacementStrategy,
+		EnableECSManagedTags:          svc.EnableECSManagedTags,
+		EnableExecuteCommand:          svc.EnableExecuteCommand,
+		PropagateTags:                 svc.PropagateTags,
+		Tags:                          svc.Tags,
+	}
+	if role := aws.StringValue(svc.RoleArn); role != "" && !strings.Contains(role, ":role/aws-service-role/") {
+		input.Role = svc.RoleArn
+	}
+	return input
+}
+
+// specFromService describes a service's essential container and desired
+// count as a spec
+func specFromService(cluster string, svc *ecs.Service, def *ecs.TaskDefinition) *ServiceSpec {
+	spec := &ServiceSpec{
+		Cluster:      cluster,
+		Service:      aws.StringValue(svc.ServiceName),
+		TaskCpu:      aws.StringValue(def.Cpu),
+		TaskMemory:   aws.StringValue(def.Memory),
+		DesiredCount: svc.DesiredCount,
+	}
+	container := findEssential(def)
+	if container == nil {
+		return spec
+	}
+	spec.Container = aws.StringValue(container.Name)
+	spec.Image = aws.StringValue(container.Image)
+	spec.Cpu = container.Cpu
+	spec.Memory = container.Memory
+	spec.MemoryReservation = container.MemoryReservation
+	spec.Env = make(map[string]string, len(container.Environment))
+	for _, env := range container.Environment {
+		spec.Env[aws.StringValue(env.Name)] = aws.StringValue(env.Value)
+	}
+	spec.Secrets = make(map[string]string, len(container.Secrets))
+	for _, secret := range container.Secrets {
+		spec.Secrets[aws.StringValue(secret.Name)] = aws.StringValue(secret.ValueFrom)
+	}
+	return spec
+}
+
+// addRuleToSpec lists a rule in the spec's schedules or post-deployment
+// tasks, undoing the naming of CreateScheduledTask and
+// CreatePostDeploymentTask
+func (c *Client) addRuleToSpec(spec *ServiceSpec, rule serviceRule) error {
+	name := aws.StringValue(rule.rule.Name)
+	var target *cloudwatchevents.Target
+	for _, t := range rule.targets {
+		if t.EcsParameters != nil {
+			target = t
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("rule %s does not run an ECS task", name)
+	}
+	command, err := targetCommand(target)
+	if err != nil {
+		return fmt.Errorf("rule %s: %v", name, err)
+	}
+	prefix := spec.Cluster + "-" + spec.Service + "-"
+	if rule.kind == RuleKindSchedule {
+		spec.Schedules = append(spec.Schedules, ScheduleSpec{
+			Name:     strings.TrimPrefix(name, prefix),
+			Schedule: aws.StringValue(rule.rule.ScheduleExpression),
+			Command:  command,
+		})
+		return nil
+	}
+	targetCluster := path.Base(aws.StringValue(target.Arn))
+	targetService := strings.TrimPrefix(name, prefix+"stable-"+targetCluster+"-")
+	if len(name) == 64 {
+		// the name may have been truncated, find the service it started
+		services, err := c.ListServices(targetCluster)
+		if err != nil {
+			return err
+		}
+		matches := make([]string, 0)
+		for _, s := range services {
+			if strings.HasPrefix(s, targetService) {
+				matches = append(matches, s)
+			}
+		}
+		if len(matches) != 1 {
+			return fmt.Errorf("unable to tell which service of %s rule %s runs, found %v", targetCluster, name, matches)
+		}
+		targetService = matches[0]
+	}
+	task := PostDeploymentSpec{Service: targetService, Command: command}
+	if targetCluster != spec.Cluster {
+		task.Cluster = targetCluster
+	}
+	spec.PostDeployment = append(spec.PostDeployment, task)
+	return nil
+}
+
+// targetCommand reads back the command override of a rule target
+func targetCommand(target *cloudwatchevents.Target) (string, error) {
+	if aws.StringValue(target.Input) == "" {
+		return "", nil
+	}
+	overrides := ecsCommandOverrideJSON{}
+	if err := json.Unmarshal([]byte(*target.Input), &overrides); err != nil {
+		return "", fmt.Errorf("unable to read command override: %v", err)
+	}
+	if len(overrides.ContainerOverrides) == 0 {
+		return "", nil
+	}
+	return formatCommandOverride(overrides.ContainerOverrides[0].Command)
+}
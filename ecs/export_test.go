@@ -0,0 +1,74 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestExportService(t *testing.T) {
+	client, fake, events := specTestClient()
+	arn := "arn:aws:ecs:us-west-2:1:task-definition/api:1"
+	fake.tags[arn] = []*ecs.Tag{{Key: aws.String("team"), Value: aws.String("core")}}
+	fake.taskDefs[arn].ContainerDefinitions[0].Secrets = []*ecs.Secret{
+		{Name: aws.String("DATABASE_URL"), ValueFrom: aws.String("/qa/api/DATABASE_URL")},
+	}
+	fake.taskDefs[arn].Status = aws.String(ecs.TaskDefinitionStatusActive)
+	fake.services["qa/api"].RoleArn = aws.String("arn:aws:iam::1:role/aws-service-role/ecs.amazonaws.com/AWSServiceRoleForECS")
+	events.targets["qa-api-report"][0].Input = aws.String(`{"containerOverrides":[{"name":"api","command":["sh","-c","bin/report --all"]}]}`)
+	pattern, err := client.createPostDeploymentPattern("qa", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events.rules["qa-api-stable-qa-api"] = &cloudwatchevents.DescribeRuleOutput{
+		Name:         aws.String("qa-api-stable-qa-api"),
+		EventPattern: aws.String(pattern),
+		Description:  aws.String("Post-Deployment Expression for api Service in qa ECS Cluster"),
+	}
+	events.targets["qa-api-stable-qa-api"] = []*cloudwatchevents.Target{{
+		Id:            aws.String("1"),
+		Arn:           aws.String("arn:aws:ecs:us-west-2:1:cluster/qa"),
+		RoleArn:       aws.String("arn:aws:iam::1:role/api"),
+		Input:         aws.String(`{"containerOverrides":[{"name":"api","command":["bin/migrate"]}]}`),
+		EcsParameters: &cloudwatchevents.EcsParameters{TaskDefinitionArn: aws.String(arn), TaskCount: aws.Int64(1)},
+	}}
+
+	export, err := client.ExportService("qa", "api", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *export.TaskDefinition.Family != "api" || len(export.TaskDefinition.Tags) != 1 {
+		t.Errorf("expected the task definition with its tags, got %v", export.TaskDefinition)
+	}
+	if *export.Service.TaskDefinition != "api:1" || *export.Service.DesiredCount != 1 || export.Service.Role != nil {
+		t.Errorf("unexpected service request %v", export.Service)
+	}
+	if len(export.Rules) != 3 || export.Rules[0].Kind != RuleKindSchedule || *export.Rules[0].Rule.Name != "qa-api-cleanup" {
+		t.Fatalf("expected the service's three rules sorted by name, got %+v", export.Rules)
+	}
+	wantSchedules := []ScheduleSpec{
+		{Name: "cleanup", Schedule: "rate(1 day)"},
+		{Name: "report", Schedule: "rate(1 hour)", Command: `sh -c "bin/report --all"`},
+	}
+	if !reflect.DeepEqual(export.Spec.Schedules, wantSchedules) {
+		t.Errorf("expected %v, got %v", wantSchedules, export.Spec.Schedules)
+	}
+	wantPostDeployment := []PostDeploymentSpec{{Service: "api", Command: "bin/migrate"}}
+	if !reflect.DeepEqual(export.Spec.PostDeployment, wantPostDeployment) {
+		t.Errorf("expected %v, got %v", wantPostDeployment, export.Spec.PostDeployment)
+	}
+	if export.Spec.Image != "api:1" || export.Spec.Env["APP_ENV"] != "qa" || export.Spec.Secrets["DATABASE_URL"] != "/qa/api/DATABASE_URL" {
+		t.Errorf("unexpected spec %+v", export.Spec)
+	}
+
+	diff, err := client.DiffServiceSpec(export.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.IsEmpty() {
+		t.Errorf("expected the exported spec to match the service, got %+v", diff.Drift())
+	}
+}
@@ -0,0 +1,116 @@
+package ecs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// insightsPollInterval is the time between checks of a running query
+var insightsPollInterval = time.Second
+
+// InsightsQuery is a CloudWatch Logs Insights query over a time window
+type InsightsQuery struct {
+	Query string
+	Since time.Time
+	Until time.Time
+	Limit int64
+}
+
+// InsightsResult holds the rows of a completed query. Fields lists the
+// result columns in the order they first appear.
+type InsightsResult struct {
+	Fields         []string
+	Rows           []map[string]string
+	RecordsMatched float64
+	RecordsScanned float64
+}
+
+// ServiceLogGroups returns the distinct log groups the named containers of
+// a task definition (all of them if none are named) write to
+func ServiceLogGroups(def *ecs.TaskDefinition, containers []string) ([]string, error) {
+	available, unavailable, err := LogContainers(def, containers)
+	if err != nil {
+		return nil, err
+	}
+	if len(available) == 0 {
+		return nil, noLogsError(def, unavailable)
+	}
+	groups := make([]string, 0, len(available))
+	for _, container := range available {
+		stream, err := ContainerLogStream(container, "")
+		if err != nil {
+			return nil, err
+		}
+		if !containsString(groups, stream.Group) {
+			groups = append(groups, stream.Group)
+		}
+	}
+	return groups, nil
+}
+
+// RunInsightsQuery starts a Logs Insights query over a set of log groups
+// and polls until it completes
+func (c *Client) RunInsightsQuery(groups []string, query InsightsQuery) (*InsightsResult, error) {
+	svc := c.CloudWatchLogs
+	until := query.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+	input := &cloudwatchlogs.StartQueryInput{
+		LogGroupNames: aws.StringSlice(groups),
+		QueryString:   aws.String(query.Query),
+		StartTime:     aws.Int64(query.Since.Unix()),
+		EndTime:       aws.Int64(until.Unix()),
+	}
+	if query.Limit > 0 {
+		input.Limit = aws.Int64(query.Limit)
+	}
+	started, err := svc.StartQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		output, err := svc.GetQueryResults(&cloudwatchlogs.GetQueryResultsInput{QueryId: started.QueryId})
+		if err != nil {
+			return nil, err
+		}
+		switch status := aws.StringValue(output.Status); status {
+		case cloudwatchlogs.QueryStatusComplete:
+			return insightsResult(output), nil
+		case cloudwatchlogs.QueryStatusFailed, cloudwatchlogs.QueryStatusCancelled, cloudwatchlogs.QueryStatusTimeout:
+			return nil, fmt.Errorf("query %s: %s", aws.StringValue(started.QueryId), status)
+		}
+		time.Sleep(insightsPollInterval)
+	}
+}
+
+func insightsResult(output *cloudwatchlogs.GetQueryResultsOutput) *InsightsResult {
+	result := &InsightsResult{
+		Fields: make([]string, 0),
+		Rows:   make([]map[string]string, 0, len(output.Results)),
+	}
+	if output.Statistics != nil {
+		result.RecordsMatched = aws.Float64Value(output.Statistics.RecordsMatched)
+		result.RecordsScanned = aws.Float64Value(output.Statistics.RecordsScanned)
+	}
+	for _, fields := range output.Results {
+		row := make(map[string]string, len(fields))
+		for _, field := range fields {
+			name := aws.StringValue(field.Field)
+			// @ptr is an opaque pointer to the log record, not a result column
+			if name == "@ptr" {
+				continue
+			}
+			if !containsString(result.Fields, name) {
+				result.Fields = append(result.Fields, name)
+			}
+			row[name] = aws.StringValue(field.Value)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result
+}
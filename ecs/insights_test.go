@@ -0,0 +1,117 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// fakeInsights completes a query after a number of polls
+type fakeInsights struct {
+	fakeLogs
+	started  *cloudwatchlogs.StartQueryInput
+	polls    int
+	complete int
+	status   string
+	results  [][]*cloudwatchlogs.ResultField
+}
+
+func (f *fakeInsights) StartQuery(input *cloudwatchlogs.StartQueryInput) (*cloudwatchlogs.StartQueryOutput, error) {
+	f.started = input
+	return &cloudwatchlogs.StartQueryOutput{QueryId: aws.String("q-1")}, nil
+}
+
+func (f *fakeInsights) GetQueryResults(input *cloudwatchlogs.GetQueryResultsInput) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	f.polls++
+	if f.polls < f.complete {
+		return &cloudwatchlogs.GetQueryResultsOutput{Status: aws.String(cloudwatchlogs.QueryStatusRunning)}, nil
+	}
+	return &cloudwatchlogs.GetQueryResultsOutput{
+		Status:     aws.String(f.status),
+		Results:    f.results,
+		Statistics: &cloudwatchlogs.QueryStatistics{RecordsMatched: aws.Float64(2), RecordsScanned: aws.Float64(10)},
+	}, nil
+}
+
+func resultRow(pairs ...string) []*cloudwatchlogs.ResultField {
+	row := make([]*cloudwatchlogs.ResultField, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		row = append(row, &cloudwatchlogs.ResultField{Field: aws.String(pairs[i]), Value: aws.String(pairs[i+1])})
+	}
+	return row
+}
+
+func TestRunInsightsQuery(t *testing.T) {
+	prev := insightsPollInterval
+	insightsPollInterval = time.Millisecond
+	defer func() { insightsPollInterval = prev }()
+
+	fake := &fakeInsights{
+		complete: 3,
+		status:   cloudwatchlogs.QueryStatusComplete,
+		results: [][]*cloudwatchlogs.ResultField{
+			resultRow("@timestamp", "2021-06-01 10:00:00.000", "@message", "boom", "@ptr", "xyz"),
+			resultRow("@timestamp", "2021-06-01 10:01:00.000", "level", "error", "@ptr", "abc"),
+		},
+	}
+	client := &Client{CloudWatchLogs: fake}
+	since := time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+	until := time.Date(2021, 6, 1, 11, 0, 0, 0, time.UTC)
+	result, err := client.RunInsightsQuery([]string{"/ecs/api"}, InsightsQuery{Query: "fields @message", Since: since, Until: until, Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.polls != 3 {
+		t.Errorf("expected to poll until complete, polled %d times", fake.polls)
+	}
+	if *fake.started.StartTime != since.Unix() || *fake.started.EndTime != until.Unix() || *fake.started.Limit != 5 {
+		t.Errorf("unexpected query input %v", fake.started)
+	}
+	if want := []string{"@timestamp", "@message", "level"}; !reflect.DeepEqual(result.Fields, want) {
+		t.Errorf("expected fields %v, got %v", want, result.Fields)
+	}
+	if result.Rows[1]["level"] != "error" || result.Rows[1]["@message"] != "" || result.RecordsMatched != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	fake.polls = 0
+	fake.status = cloudwatchlogs.QueryStatusFailed
+	if _, err := client.RunInsightsQuery([]string{"/ecs/api"}, InsightsQuery{Query: "bad"}); err == nil {
+		t.Errorf("expected a failed query to return an error")
+	}
+}
+
+func TestServiceLogGroups(t *testing.T) {
+	awslogs := func(name, group string) *ecs.ContainerDefinition {
+		return &ecs.ContainerDefinition{
+			Name: aws.String(name),
+			LogConfiguration: &ecs.LogConfiguration{
+				LogDriver: aws.String("awslogs"),
+				Options:   aws.StringMap(map[string]string{"awslogs-group": group, "awslogs-stream-prefix": "ecs"}),
+			},
+		}
+	}
+	def := &ecs.TaskDefinition{
+		Family: aws.String("api"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			awslogs("app", "/ecs/api"),
+			awslogs("worker", "/ecs/api"),
+			awslogs("proxy", "/ecs/proxy"),
+			{Name: aws.String("xray")},
+		},
+	}
+	groups, err := ServiceLogGroups(def, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/ecs/api", "/ecs/proxy"}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("expected %v, got %v", want, groups)
+	}
+	if _, err := ServiceLogGroups(def, []string{"xray"}); err == nil {
+		t.Errorf("expected an error when no container has logs")
+	}
+}
@@ -0,0 +1,176 @@
+package ecs
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// LogExportManifestName is the file describing an export, written next to
+// the exported logs
+const LogExportManifestName = "manifest.json"
+
+// LogExportManifest describes a log export. Since and Until are nil when
+// the export is not bounded that way.
+type LogExportManifest struct {
+	Cluster    string          `json:"cluster"`
+	Service    string          `json:"service"`
+	Since      *time.Time      `json:"since,omitempty"`
+	Until      *time.Time      `json:"until,omitempty"`
+	ExportedAt time.Time       `json:"exportedAt"`
+	Files      []LogExportFile `json:"files"`
+}
+
+// optionalTime returns nil for the zero time, which omitempty does not
+// leave out of JSON
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// LogExportFile is the exported log of one container of a task
+type LogExportFile struct {
+	TaskID    string `json:"taskId"`
+	Container string `json:"container"`
+	LogGroup  string `json:"logGroup"`
+	LogStream string `json:"logStream"`
+	File      string `json:"file"`
+	Events    int    `json:"events"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ExportServiceLogs downloads the log streams of a service's tasks within
+// the query's time window into dir, one task-id/container.log file per
+// stream, and writes a manifest. Events are written as they are read, so
+// large streams are never held in memory. A stream that cannot be read is
+// recorded in the manifest rather than failing the export.
+func (c *Client) ExportServiceLogs(cluster, service string, query LogsQuery, dir string) (*LogExportManifest, error) {
+	streams, err := c.ServiceLogStreams(cluster, service, query)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &LogExportManifest{
+		Cluster:    cluster,
+		Service:    service,
+		Since:      optionalTime(query.Since),
+		Until:      optionalTime(query.Until),
+		ExportedAt: time.Now(),
+		Files:      make([]LogExportFile, 0, len(streams)),
+	}
+	for _, stream := range streams {
+		file := LogExportFile{
+			TaskID:    stream.TaskID,
+			Container: stream.Container,
+			LogGroup:  stream.Group,
+			LogStream: stream.Name,
+			File:      filepath.Join(stream.TaskID, stream.Container+".log"),
+		}
+		file.Events, err = c.exportLogStream(stream, query, filepath.Join(dir, file.File))
+		if err != nil {
+			file.Error = err.Error()
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, LogExportManifestName), content, 0644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (c *Client) exportLogStream(stream *LogStream, query LogsQuery, path string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	params := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(stream.Group),
+		LogStreamName: aws.String(stream.Name),
+		StartFromHead: aws.Bool(true),
+	}
+	if !query.Since.IsZero() {
+		params.StartTime = aws.Int64(toMillis(query.Since))
+	}
+	if !query.Until.IsZero() {
+		params.EndTime = aws.Int64(toMillis(query.Until))
+	}
+	count := 0
+	err = c.EachLogEvent(params, func(event *cloudwatchlogs.OutputLogEvent) error {
+		count++
+		_, err := fmt.Fprintln(w, FormatLogEvent(event))
+		return err
+	})
+	if isResourceNotFound(err) {
+		err = fmt.Errorf("log stream %s not found", stream.Name)
+	}
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	return count, err
+}
+
+// WriteTarGz archives the files of dir into a gzipped tarball at path,
+// with names relative to dir
+func WriteTarGz(dir, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		in, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,119 @@
+package ecs
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// GetLogEventsPages pages through GetLogEvents until the forward token
+// repeats, applying the time window the way CloudWatch Logs does
+func (f *fakeLogs) GetLogEventsPages(input *cloudwatchlogs.GetLogEventsInput, fn func(*cloudwatchlogs.GetLogEventsOutput, bool) bool) error {
+	params := *input
+	for {
+		output, err := f.GetLogEvents(&params)
+		if err != nil {
+			return err
+		}
+		events := make([]*cloudwatchlogs.OutputLogEvent, 0, len(output.Events))
+		for _, event := range output.Events {
+			if input.StartTime != nil && *event.Timestamp < *input.StartTime {
+				continue
+			}
+			if input.EndTime != nil && *event.Timestamp >= *input.EndTime {
+				continue
+			}
+			events = append(events, event)
+		}
+		lastPage := aws.StringValue(output.NextForwardToken) == aws.StringValue(params.NextToken)
+		if !fn(&cloudwatchlogs.GetLogEventsOutput{Events: events}, lastPage) || lastPage {
+			return nil
+		}
+		params.NextToken = output.NextForwardToken
+	}
+}
+
+func TestExportServiceLogs(t *testing.T) {
+	client, logs := logsTestClient()
+	logs.appendAt("/ecs/api:ecs/api/aaa", 1000, "too old")
+	for i, m := range []string{"one", "two", "three"} {
+		logs.appendAt("/ecs/api:ecs/api/aaa", int64(2000+i*1000), m)
+	}
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	manifest, err := client.ExportServiceLogs("qa", "api", LogsQuery{Since: fromMillis(2000)}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].TaskID < manifest.Files[j].TaskID })
+	if len(manifest.Files) != 2 {
+		t.Fatalf("expected a file per task, got %+v", manifest.Files)
+	}
+	if f := manifest.Files[0]; f.File != filepath.Join("aaa", "api.log") || f.Events != 3 || f.Error != "" {
+		t.Errorf("unexpected export of task aaa: %+v", f)
+	}
+	if f := manifest.Files[1]; f.Events != 0 || !strings.Contains(f.Error, "not found") {
+		t.Errorf("expected the missing stream of task bbb to be noted, got %+v", f)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "aaa", "api.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], " one") || !strings.HasSuffix(lines[2], " three") {
+		t.Errorf("unexpected exported log:\n%s", content)
+	}
+	var written LogExportManifest
+	content, err = ioutil.ReadFile(filepath.Join(dir, LogExportManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(content, &written); err != nil || written.Service != "api" || len(written.Files) != 2 {
+		t.Errorf("unexpected manifest %s (%v)", content, err)
+	}
+	if written.Since == nil || !written.Since.Equal(fromMillis(2000)) || written.Until != nil || strings.Contains(string(content), `"until"`) {
+		t.Errorf("expected since and no until in the manifest, got %s", content)
+	}
+
+	archive := filepath.Join(dir, "..", filepath.Base(dir)+".tar.gz")
+	defer os.Remove(archive)
+	if err := WriteTarGz(dir, archive); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"aaa/api.log", "bbb/api.log", "manifest.json"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected archive entries %v, got %v", want, names)
+	}
+}
@@ -0,0 +1,147 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LogFields is a log message parsed as a JSON object
+type LogFields map[string]interface{}
+
+// ParseLogFields parses a JSON object log message. Numbers keep their
+// original formatting, so "status": 500 compares equal to "500".
+func ParseLogFields(message string) (LogFields, bool) {
+	message = strings.TrimSpace(message)
+	if !strings.HasPrefix(message, "{") {
+		return nil, false
+	}
+	decoder := json.NewDecoder(strings.NewReader(message))
+	decoder.UseNumber()
+	fields := make(LogFields)
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, false
+	}
+	return fields, true
+}
+
+// Get returns the value of a field, following dots into nested objects
+// ("http.status")
+func (fields LogFields) Get(path string) (interface{}, bool) {
+	if value, ok := fields[path]; ok {
+		return value, true
+	}
+	var current interface{} = map[string]interface{}(fields)
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Project returns only the named fields, keyed by the names given
+func (fields LogFields) Project(names []string) LogFields {
+	out := make(LogFields, len(names))
+	for _, name := range names {
+		if value, ok := fields.Get(name); ok {
+			out[name] = value
+		}
+	}
+	return out
+}
+
+// Format renders the named fields (all of them, sorted, if none are named)
+// as space separated key=value pairs
+func (fields LogFields) Format(names []string) string {
+	if len(names) == 0 {
+		names = make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		if value, ok := fields.Get(name); ok {
+			parts = append(parts, fmt.Sprintf("%s=%s", name, FormatLogValue(value)))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// FormatLogValue renders a field value, strings and numbers as is and
+// anything else as JSON
+func FormatLogValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(out)
+}
+
+// LogCondition matches a field of JSON log events, parsed from
+// "field=value" or "field!=value"
+type LogCondition struct {
+	Field  string
+	Value  string
+	Negate bool
+}
+
+// ParseLogCondition parses a --where expression
+func ParseLogCondition(expr string) (LogCondition, error) {
+	if i := strings.Index(expr, "!="); i > 0 {
+		return LogCondition{Field: expr[:i], Value: expr[i+2:], Negate: true}, nil
+	}
+	if i := strings.Index(expr, "="); i > 0 {
+		return LogCondition{Field: expr[:i], Value: expr[i+1:]}, nil
+	}
+	return LogCondition{}, fmt.Errorf("invalid condition %q, expected field=value or field!=value", expr)
+}
+
+// Match reports whether the fields satisfy the condition. A missing field
+// only satisfies a negated condition.
+func (cond LogCondition) Match(fields LogFields) bool {
+	value, ok := fields.Get(cond.Field)
+	if !ok {
+		return cond.Negate
+	}
+	return (FormatLogValue(value) == cond.Value) != cond.Negate
+}
+
+// NDJSON renders an event as a single line JSON object, the parsed fields
+// (or the raw message under "log") with the task id, container name and
+// event time attached, named as FireLens names them
+func (e *LogEvent) NDJSON(fields LogFields) ([]byte, error) {
+	out := make(LogFields, len(fields)+4)
+	for k, v := range fields {
+		out[k] = v
+	}
+	if fields == nil {
+		out["log"] = e.Message
+	}
+	out["ecs_task_id"] = e.TaskID
+	out["container_name"] = e.Container
+	out["log_timestamp"] = e.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(out); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
@@ -0,0 +1,114 @@
+package ecs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogFields(t *testing.T) {
+	fields, ok := ParseLogFields(`{"level":"error","status":500,"http":{"path":"/health"},"tags":["a"]}`)
+	if !ok {
+		t.Fatal("expected a JSON object to parse")
+	}
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"level", "error", true},
+		{"status", "500", true},
+		{"http.path", "/health", true},
+		{"tags", `["a"]`, true},
+		{"http.missing", "", false},
+		{"level.nested", "", false},
+	}
+	for _, tt := range tests {
+		value, ok := fields.Get(tt.path)
+		if ok != tt.ok {
+			t.Errorf("Get(%q) ok = %v, want %v", tt.path, ok, tt.ok)
+			continue
+		}
+		if ok && FormatLogValue(value) != tt.want {
+			t.Errorf("Get(%q) = %v, want %v", tt.path, FormatLogValue(value), tt.want)
+		}
+	}
+	for _, message := range []string{"plain text", "[1,2]", "{broken"} {
+		if _, ok := ParseLogFields(message); ok {
+			t.Errorf("expected %q not to parse as fields", message)
+		}
+	}
+}
+
+func TestLogCondition(t *testing.T) {
+	fields, _ := ParseLogFields(`{"level":"error","status":500,"http":{"method":"GET"}}`)
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{"level=error", true},
+		{"level=info", false},
+		{"level!=info", true},
+		{"status=500", true},
+		{"http.method=GET", true},
+		{"user=bob", false},
+		{"user!=bob", true},
+		{"msg=a=b", false},
+	}
+	for _, tt := range tests {
+		cond, err := ParseLogCondition(tt.expr)
+		if err != nil {
+			t.Errorf("ParseLogCondition(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := cond.Match(fields); got != tt.match {
+			t.Errorf("%q matched = %v, want %v", tt.expr, got, tt.match)
+		}
+	}
+	for _, expr := range []string{"level", "=error"} {
+		if _, err := ParseLogCondition(expr); err == nil {
+			t.Errorf("expected %q to be invalid", expr)
+		}
+	}
+	cond, _ := ParseLogCondition("msg=a=b")
+	if cond.Field != "msg" || cond.Value != "a=b" {
+		t.Errorf("expected the value to keep its =, got %+v", cond)
+	}
+}
+
+func TestLogFieldsFormatAndProject(t *testing.T) {
+	fields, _ := ParseLogFields(`{"ts":"10:00","level":"error","msg":"boom","extra":true}`)
+	if got := fields.Format([]string{"ts", "level", "msg"}); got != "ts=10:00 level=error msg=boom" {
+		t.Errorf("unexpected projection: %s", got)
+	}
+	if got := fields.Format(nil); got != "extra=true level=error msg=boom ts=10:00" {
+		t.Errorf("unexpected format of all fields: %s", got)
+	}
+	projected := fields.Project([]string{"level", "missing"})
+	if len(projected) != 1 || projected["level"] != "error" {
+		t.Errorf("unexpected projection: %v", projected)
+	}
+}
+
+func TestLogEventNDJSON(t *testing.T) {
+	event := &LogEvent{
+		Timestamp: time.Date(2021, 6, 1, 10, 0, 0, 5e6, time.UTC),
+		Message:   `{"level":"error","status":500}`,
+		TaskID:    "abc",
+		Container: "app",
+	}
+	fields, _ := ParseLogFields(event.Message)
+	line, err := event.NDJSON(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"container_name":"app","ecs_task_id":"abc","level":"error","log_timestamp":"2021-06-01T10:00:00.005Z","status":500}`
+	if string(line) != want {
+		t.Errorf("expected %s, got %s", want, line)
+	}
+	event.Message = "<b>plain</b>"
+	line, _ = event.NDJSON(nil)
+	want = `{"container_name":"app","ecs_task_id":"abc","log":"<b>plain</b>","log_timestamp":"2021-06-01T10:00:00.005Z"}`
+	if string(line) != want {
+		t.Errorf("expected %s, got %s", want, line)
+	}
+}
@@ -0,0 +1,466 @@
+package ecs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// logTimeFormat is how event timestamps are printed
+const logTimeFormat = "2006-01-02 15:04:05 MST"
+
+// tailInterval is the time between polls of a tailed log stream
+var tailInterval = 2 * time.Second
+
+// LogStream identifies the CloudWatch log stream of one container of a task
+type LogStream struct {
+	Group     string
+	Name      string
+	Region    string
+	Container string
+	TaskID    string
+}
+
+// FormatLogEvent formats a log event as "[time] message"
+func FormatLogEvent(event *cloudwatchlogs.OutputLogEvent) string {
+	val := time.Unix(*event.Timestamp/1000, 0)
+	return fmt.Sprintf("[%v] %v", val.Format(logTimeFormat), *event.Message)
+}
+
+// ContainerLogStream resolves the CloudWatch log stream a container of a
+// task writes to. Containers using the awslogs driver write to
+// prefix/container-name/task-id, FireLens containers routed to CloudWatch
+// Logs to the stream their output plugin names.
+func ContainerLogStream(container *ecs.ContainerDefinition, taskID string) (*LogStream, error) {
+	logConfig := container.LogConfiguration
+	if logConfig == nil {
+		return nil, fmt.Errorf("container %s has no log configuration", aws.StringValue(container.Name))
+	}
+	switch driver := aws.StringValue(logConfig.LogDriver); driver {
+	case "awslogs":
+		return awslogsStream(container, taskID)
+	case "awsfirelens":
+		return firelensStream(container, taskID)
+	default:
+		return nil, fmt.Errorf("container %s uses the %s log driver, which does not write to CloudWatch Logs", aws.StringValue(container.Name), driver)
+	}
+}
+
+func awslogsStream(container *ecs.ContainerDefinition, taskID string) (*LogStream, error) {
+	options := container.LogConfiguration.Options
+	prefix := aws.StringValue(options["awslogs-stream-prefix"])
+	if prefix == "" {
+		return nil, fmt.Errorf("container %s has no awslogs-stream-prefix, its log stream cannot be determined", aws.StringValue(container.Name))
+	}
+	return &LogStream{
+		Group:     aws.StringValue(options["awslogs-group"]),
+		Name:      fmt.Sprintf("%s/%s/%s", prefix, aws.StringValue(container.Name), taskID),
+		Region:    aws.StringValue(options["awslogs-region"]),
+		Container: aws.StringValue(container.Name),
+		TaskID:    taskID,
+	}, nil
+}
+
+// firelensStream resolves the stream of a FireLens container whose output
+// plugin is cloudwatch or cloudwatch_logs. Without an explicit
+// log_stream_name, the plugin appends the FireLens tag
+// (container-name-firelens-task-id) to log_stream_prefix.
+func firelensStream(container *ecs.ContainerDefinition, taskID string) (*LogStream, error) {
+	name := aws.StringValue(container.Name)
+	options := container.LogConfiguration.Options
+	output := aws.StringValue(options["Name"])
+	if !strings.EqualFold(output, "cloudwatch") && !strings.EqualFold(output, "cloudwatch_logs") {
+		return nil, fmt.Errorf("container %s sends FireLens output to %q, not CloudWatch Logs", name, output)
+	}
+	tag := fmt.Sprintf("%s-firelens-%s", name, taskID)
+	expand := strings.NewReplacer("$(ecs_task_id)", taskID, "$(tag)", tag, "$(container_name)", name)
+	group := expand.Replace(aws.StringValue(options["log_group_name"]))
+	stream := expand.Replace(aws.StringValue(options["log_stream_name"]))
+	if stream == "" && aws.StringValue(options["log_stream_prefix"]) != "" {
+		stream = expand.Replace(aws.StringValue(options["log_stream_prefix"])) + tag
+	}
+	if group == "" || stream == "" {
+		return nil, fmt.Errorf("container %s has no FireLens log_group_name and log_stream_name or log_stream_prefix", name)
+	}
+	if strings.Contains(group, "$(") || strings.Contains(stream, "$(") {
+		return nil, fmt.Errorf("container %s names its FireLens log stream with a template that cannot be resolved", name)
+	}
+	return &LogStream{
+		Group:     group,
+		Name:      stream,
+		Region:    aws.StringValue(options["region"]),
+		Container: name,
+		TaskID:    taskID,
+	}, nil
+}
+
+// UnavailableLogs is a container whose logs cannot be retrieved, and why
+type UnavailableLogs struct {
+	Container string
+	Reason    error
+}
+
+// LogContainers splits the named containers of a task definition (all of
+// them if none are named) into those whose logs can be retrieved and those
+// whose logs cannot. Naming a container that does not exist is an error.
+func LogContainers(def *ecs.TaskDefinition, names []string) ([]*ecs.ContainerDefinition, []UnavailableLogs, error) {
+	containers := def.ContainerDefinitions
+	if len(names) > 0 {
+		containers = make([]*ecs.ContainerDefinition, 0, len(names))
+		for _, name := range names {
+			container, err := FindContainer(def, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			containers = append(containers, container)
+		}
+	}
+	available := make([]*ecs.ContainerDefinition, 0, len(containers))
+	unavailable := make([]UnavailableLogs, 0)
+	for _, container := range containers {
+		if _, err := ContainerLogStream(container, ""); err != nil {
+			unavailable = append(unavailable, UnavailableLogs{Container: aws.StringValue(container.Name), Reason: err})
+			continue
+		}
+		available = append(available, container)
+	}
+	return available, unavailable, nil
+}
+
+// FindContainer returns a container definition by name, or the first
+// container if name is empty
+func FindContainer(def *ecs.TaskDefinition, name string) (*ecs.ContainerDefinition, error) {
+	for _, container := range def.ContainerDefinitions {
+		if name == "" || aws.StringValue(container.Name) == name {
+			return container, nil
+		}
+	}
+	return nil, fmt.Errorf("container %s not found in task definition %s", name, aws.StringValue(def.Family))
+}
+
+// TailLogStream prints events of a log stream as they arrive, until stop is
+// closed. It then reads the stream to its end before returning, so no
+// output written before the task stopped is lost. A stream that does not
+// exist yet (the task is still starting) is waited for.
+func (c *Client) TailLogStream(stream *LogStream, stop <-chan struct{}, print func(event *cloudwatchlogs.OutputLogEvent)) error {
+	svc := c.CloudWatchLogs
+	input := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(stream.Group),
+		LogStreamName: aws.String(stream.Name),
+		StartFromHead: aws.Bool(true),
+	}
+	stopping := false
+	for {
+		output, err := svc.GetLogEvents(input)
+		if err != nil && !isResourceNotFound(err) {
+			return err
+		}
+		caughtUp := true
+		if err == nil {
+			for _, event := range output.Events {
+				print(event)
+			}
+			token := aws.StringValue(output.NextForwardToken)
+			caughtUp = token == aws.StringValue(input.NextToken)
+			input.NextToken = output.NextForwardToken
+		}
+		if stopping && caughtUp {
+			return nil
+		}
+		if !caughtUp {
+			continue
+		}
+		select {
+		case <-stop:
+			stopping = true
+		case <-time.After(tailInterval):
+		}
+	}
+}
+
+func isResourceNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == cloudwatchlogs.ErrCodeResourceNotFoundException
+}
+
+// maxFilterStreams is the most log stream names FilterLogEvents accepts
+const maxFilterStreams = 100
+
+// LogsQuery selects log events of a service's tasks
+type LogsQuery struct {
+	// Status limits tasks to "running" or "stopped" ones, "all" or empty
+	// for both
+	Status string
+	// TaskIDs limits the logs to specific tasks
+	TaskIDs []string
+	// Containers limits the logs to specific containers, all containers
+	// when empty
+	Containers []string
+	// Since and Until bound the event times, when not zero
+	Since time.Time
+	Until time.Time
+	// FilterPattern is a CloudWatch Logs filter pattern
+	FilterPattern string
+}
+
+// LogEvent is a log event of one container of a task
+type LogEvent struct {
+	ID        string
+	Timestamp time.Time
+	Message   string
+	TaskID    string
+	Container string
+}
+
+// String formats the event as "[time] message"
+func (e *LogEvent) String() string {
+	return fmt.Sprintf("[%v] %v", e.Timestamp.Format(logTimeFormat), e.Message)
+}
+
+// ParseLogTime parses a time flag, either a duration before now ("15m",
+// "2h") or an RFC3339 timestamp
+func ParseLogTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected a duration (15m) or RFC3339 timestamp", value)
+	}
+	return t, nil
+}
+
+// ServiceLogStreams resolves the log streams of the containers of the tasks
+// of a service matching the query, skipping containers whose logs cannot be
+// retrieved (see LogContainers)
+func (c *Client) ServiceLogStreams(cluster, service string, query LogsQuery) ([]*LogStream, error) {
+	def, err := c.GetCurrentTaskDefinition(cluster, service)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]*ecs.Task, 0)
+	for _, status := range []string{"RUNNING", "STOPPED"} {
+		if query.Status != "" && query.Status != "all" && !strings.EqualFold(query.Status, status) {
+			continue
+		}
+		found, err := c.GetAllTasksByDefinitionStatus(cluster, def, aws.String(status))
+		if err != nil {
+			return nil, fmt.Errorf("Problem getting tasks by definition: %v", err)
+		}
+		tasks = append(tasks, found...)
+	}
+	containers, unavailable, err := LogContainers(def, query.Containers)
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, noLogsError(def, unavailable)
+	}
+	streams := make([]*LogStream, 0, len(tasks)*len(containers))
+	for _, task := range tasks {
+		taskID := GetTaskIDFromArn(*task.TaskArn)
+		if len(query.TaskIDs) > 0 && !containsString(query.TaskIDs, taskID) {
+			continue
+		}
+		for _, container := range containers {
+			stream, err := ContainerLogStream(container, taskID)
+			if err != nil {
+				return nil, err
+			}
+			streams = append(streams, stream)
+		}
+	}
+	return streams, nil
+}
+
+// GetServiceLogs returns the log events of a service's tasks matching
+// the query, ordered by event time
+func (c *Client) GetServiceLogs(cluster, service string, query LogsQuery) ([]*LogEvent, error) {
+	streams, err := c.ServiceLogStreams(cluster, service, query)
+	if err != nil {
+		return nil, err
+	}
+	return c.FilterLogEvents(streams, query)
+}
+
+// FilterLogEvents returns the events of a set of log streams matching the
+// query's time range and filter pattern, ordered by event time
+func (c *Client) FilterLogEvents(streams []*LogStream, query LogsQuery) ([]*LogEvent, error) {
+	svc := c.CloudWatchLogs
+	byGroup := make(map[string][]*LogStream)
+	groups := make([]string, 0)
+	for _, stream := range streams {
+		if _, ok := byGroup[stream.Group]; !ok {
+			groups = append(groups, stream.Group)
+		}
+		byGroup[stream.Group] = append(byGroup[stream.Group], stream)
+	}
+	events := make([]*LogEvent, 0)
+	for _, group := range groups {
+		groupStreams := byGroup[group]
+		byName := make(map[string]*LogStream, len(groupStreams))
+		for _, stream := range groupStreams {
+			byName[stream.Name] = stream
+		}
+		for start := 0; start < len(groupStreams); start += maxFilterStreams {
+			end := start + maxFilterStreams
+			if end > len(groupStreams) {
+				end = len(groupStreams)
+			}
+			names := make([]*string, 0, end-start)
+			for _, stream := range groupStreams[start:end] {
+				names = append(names, aws.String(stream.Name))
+			}
+			input := &cloudwatchlogs.FilterLogEventsInput{
+				LogGroupName:   aws.String(group),
+				LogStreamNames: names,
+			}
+			if !query.Since.IsZero() {
+				input.StartTime = aws.Int64(toMillis(query.Since))
+			}
+			if !query.Until.IsZero() {
+				input.EndTime = aws.Int64(toMillis(query.Until))
+			}
+			if query.FilterPattern != "" {
+				input.FilterPattern = aws.String(query.FilterPattern)
+			}
+			collect := func(page *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+				for _, event := range page.Events {
+					stream := byName[aws.StringValue(event.LogStreamName)]
+					logEvent := &LogEvent{
+						ID:        aws.StringValue(event.EventId),
+						Timestamp: fromMillis(aws.Int64Value(event.Timestamp)),
+						Message:   aws.StringValue(event.Message),
+					}
+					if stream != nil {
+						logEvent.TaskID = stream.TaskID
+						logEvent.Container = stream.Container
+					}
+					events = append(events, logEvent)
+				}
+				return !lastPage
+			}
+			err := svc.FilterLogEventsPages(input, collect)
+			if isResourceNotFound(err) {
+				// a single stream that does not exist, such as one of a task
+				// that never logged, fails the whole batch: retry with the
+				// streams that do
+				input.LogStreamNames, err = c.existingLogStreams(group, names)
+				if err == nil && len(input.LogStreamNames) > 0 {
+					err = svc.FilterLogEventsPages(input, collect)
+				}
+			}
+			if err != nil && !isResourceNotFound(err) {
+				return nil, err
+			}
+		}
+	}
+	// tasks are listed in no particular order, so break ties between events
+	// logged at the same millisecond by task, keeping each stream's order
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Timestamp.Equal(events[j].Timestamp) {
+			return events[i].Timestamp.Before(events[j].Timestamp)
+		}
+		if events[i].TaskID != events[j].TaskID {
+			return events[i].TaskID < events[j].TaskID
+		}
+		return events[i].Container < events[j].Container
+	})
+	return events, nil
+}
+
+// existingLogStreams returns the names of a log group's streams that exist
+func (c *Client) existingLogStreams(group string, names []*string) ([]*string, error) {
+	existing := make([]*string, 0, len(names))
+	for _, name := range names {
+		output, err := c.CloudWatchLogs.DescribeLogStreams(&cloudwatchlogs.DescribeLogStreamsInput{
+			LogGroupName:        aws.String(group),
+			LogStreamNamePrefix: name,
+			Limit:               aws.Int64(1),
+		})
+		if err != nil {
+			return nil, err
+		}
+		// streams come sorted by name, so an exact match comes first
+		if len(output.LogStreams) > 0 && aws.StringValue(output.LogStreams[0].LogStreamName) == *name {
+			existing = append(existing, name)
+		}
+	}
+	return existing, nil
+}
+
+// FollowServiceLogs prints new log events of a service's tasks as they
+// arrive, picking up tasks started while following, until stop is closed
+func (c *Client) FollowServiceLogs(cluster, service string, query LogsQuery, stop <-chan struct{}, print func(event *LogEvent)) error {
+	if query.Since.IsZero() {
+		query.Since = time.Now()
+	}
+	query.Until = time.Time{}
+	seen := make(map[string]time.Time)
+	for {
+		streams, err := c.ServiceLogStreams(cluster, service, query)
+		if err != nil {
+			return err
+		}
+		events, err := c.FilterLogEvents(streams, query)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if _, ok := seen[event.ID]; ok {
+				continue
+			}
+			seen[event.ID] = event.Timestamp
+			print(event)
+			if event.Timestamp.After(query.Since) {
+				query.Since = event.Timestamp
+			}
+		}
+		// events at the new start time will be returned again, everything
+		// older can be forgotten
+		for id, ts := range seen {
+			if ts.Before(query.Since) {
+				delete(seen, id)
+			}
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(tailInterval):
+		}
+	}
+}
+
+func noLogsError(def *ecs.TaskDefinition, unavailable []UnavailableLogs) error {
+	reasons := make([]string, 0, len(unavailable))
+	for _, u := range unavailable {
+		reasons = append(reasons, u.Reason.Error())
+	}
+	return fmt.Errorf("no container of %s has retrievable logs: %s", aws.StringValue(def.Family), strings.Join(reasons, "; "))
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMillis(ms int64) time.Time {
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
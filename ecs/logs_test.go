@@ -0,0 +1,452 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// fakeLogs serves log events of streams, which can grow while being read
+type fakeLogs struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+	mu      sync.Mutex
+	streams map[string][]*cloudwatchlogs.OutputLogEvent
+}
+
+func (f *fakeLogs) append(stream string, messages ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.streams == nil {
+		f.streams = make(map[string][]*cloudwatchlogs.OutputLogEvent)
+	}
+	for _, m := range messages {
+		ts := int64(len(f.streams[stream])) * 1000
+		f.streams[stream] = append(f.streams[stream], &cloudwatchlogs.OutputLogEvent{Message: aws.String(m), Timestamp: aws.Int64(ts)})
+	}
+}
+
+// GetLogEvents returns at most two events per call, using the index of the
+// next event as the forward token
+func (f *fakeLogs) GetLogEvents(input *cloudwatchlogs.GetLogEventsInput) (*cloudwatchlogs.GetLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events, ok := f.streams[*input.LogGroupName+":"+*input.LogStreamName]
+	if !ok {
+		return nil, awserr.New(cloudwatchlogs.ErrCodeResourceNotFoundException, "stream not found", nil)
+	}
+	start := 0
+	if input.NextToken != nil {
+		start, _ = strconv.Atoi(*input.NextToken)
+	}
+	end := start + 2
+	if end > len(events) {
+		end = len(events)
+	}
+	return &cloudwatchlogs.GetLogEventsOutput{
+		Events:           events[start:end],
+		NextForwardToken: aws.String(strconv.Itoa(end)),
+	}, nil
+}
+
+func (f *fakeLogs) appendAt(stream string, ts int64, message string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.streams == nil {
+		f.streams = make(map[string][]*cloudwatchlogs.OutputLogEvent)
+	}
+	f.streams[stream] = append(f.streams[stream], &cloudwatchlogs.OutputLogEvent{Message: aws.String(message), Timestamp: aws.Int64(ts)})
+}
+
+// FilterLogEventsPages returns the matching events stream by stream, one
+// page per stream, treating the filter pattern as a substring. Like AWS, it
+// fails when any of the streams does not exist.
+func (f *fakeLogs) FilterLogEventsPages(input *cloudwatchlogs.FilterLogEventsInput, fn func(*cloudwatchlogs.FilterLogEventsOutput, bool) bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, name := range input.LogStreamNames {
+		if _, ok := f.streams[*input.LogGroupName+":"+*name]; !ok {
+			return awserr.New(cloudwatchlogs.ErrCodeResourceNotFoundException, "The specified log stream does not exist.", nil)
+		}
+	}
+	for i, name := range input.LogStreamNames {
+		page := &cloudwatchlogs.FilterLogEventsOutput{}
+		for j, event := range f.streams[*input.LogGroupName+":"+*name] {
+			ts := aws.Int64Value(event.Timestamp)
+			if input.StartTime != nil && ts < *input.StartTime {
+				continue
+			}
+			if input.EndTime != nil && ts > *input.EndTime {
+				continue
+			}
+			if input.FilterPattern != nil && !strings.Contains(*event.Message, *input.FilterPattern) {
+				continue
+			}
+			page.Events = append(page.Events, &cloudwatchlogs.FilteredLogEvent{
+				EventId:       aws.String(fmt.Sprintf("%s-%d", *name, j)),
+				LogStreamName: name,
+				Message:       event.Message,
+				Timestamp:     event.Timestamp,
+			})
+		}
+		if !fn(page, i == len(input.LogStreamNames)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeLogs) DescribeLogStreams(input *cloudwatchlogs.DescribeLogStreamsInput) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	output := &cloudwatchlogs.DescribeLogStreamsOutput{}
+	if _, ok := f.streams[*input.LogGroupName+":"+*input.LogStreamNamePrefix]; ok {
+		output.LogStreams = []*cloudwatchlogs.LogStream{{LogStreamName: input.LogStreamNamePrefix}}
+	}
+	return output, nil
+}
+
+func TestContainerLogStream(t *testing.T) {
+	container := &ecs.ContainerDefinition{
+		Name: aws.String("web"),
+		LogConfiguration: &ecs.LogConfiguration{
+			LogDriver: aws.String("awslogs"),
+			Options: aws.StringMap(map[string]string{
+				"awslogs-group":         "/ecs/api",
+				"awslogs-region":        "us-east-1",
+				"awslogs-stream-prefix": "api",
+			}),
+		},
+	}
+	stream, err := ContainerLogStream(container, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &LogStream{Group: "/ecs/api", Name: "api/web/abc123", Region: "us-east-1", Container: "web", TaskID: "abc123"}
+	if !reflect.DeepEqual(stream, want) {
+		t.Errorf("expected %+v, got %+v", want, stream)
+	}
+	container.LogConfiguration.LogDriver = aws.String("splunk")
+	if _, err := ContainerLogStream(container, "abc123"); err == nil {
+		t.Errorf("expected an error for a non awslogs container")
+	}
+}
+
+func TestFirelensLogStream(t *testing.T) {
+	firelens := func(options map[string]string) *ecs.ContainerDefinition {
+		return &ecs.ContainerDefinition{
+			Name: aws.String("app"),
+			LogConfiguration: &ecs.LogConfiguration{
+				LogDriver: aws.String("awsfirelens"),
+				Options:   aws.StringMap(options),
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		options map[string]string
+		want    *LogStream
+	}{
+		{
+			"stream prefix",
+			map[string]string{"Name": "cloudwatch", "region": "us-east-1", "log_group_name": "/ecs/app", "log_stream_prefix": "from-fluent-bit-"},
+			&LogStream{Group: "/ecs/app", Name: "from-fluent-bit-app-firelens-abc123", Region: "us-east-1", Container: "app", TaskID: "abc123"},
+		},
+		{
+			"stream name template",
+			map[string]string{"Name": "cloudwatch_logs", "log_group_name": "/ecs/app", "log_stream_name": "app/$(ecs_task_id)"},
+			&LogStream{Group: "/ecs/app", Name: "app/abc123", Container: "app", TaskID: "abc123"},
+		},
+		{
+			"not cloudwatch",
+			map[string]string{"Name": "datadog", "apikey": "x"},
+			nil,
+		},
+		{
+			"unresolvable template",
+			map[string]string{"Name": "cloudwatch_logs", "log_group_name": "/ecs/app", "log_stream_name": "$(ecs_cluster)/app"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := ContainerLogStream(firelens(tt.options), "abc123")
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", stream)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(stream, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, stream)
+			}
+		})
+	}
+}
+
+func TestLogContainers(t *testing.T) {
+	def := &ecs.TaskDefinition{
+		Family: aws.String("api"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), LogConfiguration: &ecs.LogConfiguration{
+				LogDriver: aws.String("awslogs"),
+				Options:   aws.StringMap(map[string]string{"awslogs-group": "/ecs/api", "awslogs-stream-prefix": "ecs"}),
+			}},
+			{Name: aws.String("sidecar"), LogConfiguration: &ecs.LogConfiguration{LogDriver: aws.String("json-file")}},
+			{Name: aws.String("init")},
+		},
+	}
+	available, unavailable, err := LogContainers(def, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(available) != 1 || *available[0].Name != "app" {
+		t.Errorf("expected only app to have logs, got %v", available)
+	}
+	if len(unavailable) != 2 || unavailable[0].Container != "sidecar" || unavailable[1].Container != "init" {
+		t.Errorf("expected sidecar and init to be unavailable, got %v", unavailable)
+	}
+	available, unavailable, err = LogContainers(def, []string{"sidecar"})
+	if err != nil || len(available) != 0 || len(unavailable) != 1 {
+		t.Errorf("expected only sidecar, unavailable, got %v %v %v", available, unavailable, err)
+	}
+	if _, _, err := LogContainers(def, []string{"missing"}); err == nil {
+		t.Errorf("expected an error for an unknown container")
+	}
+}
+
+func TestTailLogStreamFlushesAfterStop(t *testing.T) {
+	prev := tailInterval
+	tailInterval = 5 * time.Millisecond
+	defer func() { tailInterval = prev }()
+
+	fake := &fakeLogs{}
+	client := &Client{CloudWatchLogs: fake}
+	stream := &LogStream{Group: "/ecs/api", Name: "api/web/abc"}
+	stop := make(chan struct{})
+	done := make(chan error)
+	got := make([]string, 0)
+	go func() {
+		done <- client.TailLogStream(stream, stop, func(event *cloudwatchlogs.OutputLogEvent) {
+			got = append(got, *event.Message)
+		})
+	}()
+	// the stream does not exist until the task starts logging
+	time.Sleep(15 * time.Millisecond)
+	fake.append("/ecs/api:api/web/abc", "one", "two", "three")
+	time.Sleep(15 * time.Millisecond)
+	// output written just before the task stopped must still be printed
+	fake.append("/ecs/api:api/web/abc", "four", "five", "six", "seven")
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"one", "two", "three", "four", "five", "six", "seven"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"15m", now.Add(-15 * time.Minute), false},
+		{"2h", now.Add(-2 * time.Hour), false},
+		{"2021-06-01T10:30:00Z", time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC), false},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLogTime(tt.value, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLogTime(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseLogTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func logsTestClient() (*Client, *fakeLogs) {
+	def := testTaskDef("api", 1, "api:v1")
+	def.ContainerDefinitions[0].LogConfiguration = &ecs.LogConfiguration{
+		LogDriver: aws.String("awslogs"),
+		Options: aws.StringMap(map[string]string{
+			"awslogs-group":         "/ecs/api",
+			"awslogs-stream-prefix": "ecs",
+		}),
+	}
+	fake := newFakeECS()
+	fake.addService("qa", "api", def)
+	for _, id := range []string{"aaa", "bbb"} {
+		arn := "arn:aws:ecs:us-west-2:1:task/qa/" + id
+		fake.tasks[arn] = &ecs.Task{
+			TaskArn:           aws.String(arn),
+			TaskDefinitionArn: def.TaskDefinitionArn,
+			DesiredStatus:     aws.String("RUNNING"),
+		}
+	}
+	logs := &fakeLogs{}
+	return &Client{ECS: fake, CloudWatchLogs: logs}, logs
+}
+
+func TestGetServiceLogsAllContainers(t *testing.T) {
+	client, logs := logsTestClient()
+	def := client.ECS.(*fakeECS).taskDefs["arn:aws:ecs:us-west-2:1:task-definition/api:1"]
+	def.ContainerDefinitions = append(def.ContainerDefinitions,
+		&ecs.ContainerDefinition{
+			Name: aws.String("router"),
+			LogConfiguration: &ecs.LogConfiguration{
+				LogDriver: aws.String("awsfirelens"),
+				Options:   aws.StringMap(map[string]string{"Name": "cloudwatch", "log_group_name": "/ecs/router", "log_stream_prefix": "fb-"}),
+			},
+		},
+		&ecs.ContainerDefinition{Name: aws.String("xray")},
+	)
+	logs.appendAt("/ecs/api:ecs/api/aaa", 1000, "api")
+	logs.appendAt("/ecs/router:fb-router-firelens-aaa", 2000, "router")
+
+	events, err := client.GetServiceLogs("qa", "api", LogsQuery{TaskIDs: []string{"aaa"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(events))
+	for i, event := range events {
+		got[i] = event.Container + ":" + event.Message
+	}
+	want := []string{"api:api", "router:router"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if _, err := client.GetServiceLogs("qa", "api", LogsQuery{Containers: []string{"xray"}}); err == nil {
+		t.Errorf("expected an error when no requested container has logs")
+	}
+}
+
+func messages(events []*LogEvent) []string {
+	out := make([]string, len(events))
+	for i, event := range events {
+		out[i] = event.TaskID + ":" + event.Message
+	}
+	return out
+}
+
+func TestGetServiceLogsInterleavesByTimestamp(t *testing.T) {
+	client, logs := logsTestClient()
+	logs.appendAt("/ecs/api:ecs/api/aaa", 1000, "a1")
+	logs.appendAt("/ecs/api:ecs/api/aaa", 9000, "a9 ERROR")
+	logs.appendAt("/ecs/api:ecs/api/aaa", 10000, "a10")
+	logs.appendAt("/ecs/api:ecs/api/bbb", 2000, "b2 ERROR")
+	logs.appendAt("/ecs/api:ecs/api/bbb", 10000, "b10")
+
+	tests := []struct {
+		name  string
+		query LogsQuery
+		want  []string
+	}{
+		{"all", LogsQuery{}, []string{"aaa:a1", "bbb:b2 ERROR", "aaa:a9 ERROR", "aaa:a10", "bbb:b10"}},
+		{"time range", LogsQuery{Since: fromMillis(2000), Until: fromMillis(9000)}, []string{"bbb:b2 ERROR", "aaa:a9 ERROR"}},
+		{"filter pattern", LogsQuery{FilterPattern: "ERROR"}, []string{"bbb:b2 ERROR", "aaa:a9 ERROR"}},
+		{"task", LogsQuery{TaskIDs: []string{"bbb"}}, []string{"bbb:b2 ERROR", "bbb:b10"}},
+		{"stopped", LogsQuery{Status: "stopped"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := client.GetServiceLogs("qa", "api", tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := messages(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetServiceLogsOrdersTiesByTask(t *testing.T) {
+	client, logs := logsTestClient()
+	logs.appendAt("/ecs/api:ecs/api/bbb", 5000, "b5")
+	logs.appendAt("/ecs/api:ecs/api/bbb", 5000, "b5 again")
+	logs.appendAt("/ecs/api:ecs/api/aaa", 5000, "a5")
+	want := []string{"aaa:a5", "bbb:b5", "bbb:b5 again"}
+	// the tasks are listed in map order, so ask a few times
+	for i := 0; i < 10; i++ {
+		events, err := client.GetServiceLogs("qa", "api", LogsQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := messages(events); !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestFilterLogEventsSkipsMissingStreams(t *testing.T) {
+	client, logs := logsTestClient()
+	logs.appendAt("/ecs/api:ecs/api/aaa", 1000, "a1")
+	streams := []*LogStream{
+		{Group: "/ecs/api", Name: "ecs/api/aaa", TaskID: "aaa"},
+		// a task that stopped before logging anything
+		{Group: "/ecs/api", Name: "ecs/api/ccc", TaskID: "ccc"},
+	}
+	events, err := client.FilterLogEvents(streams, LogsQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := messages(events), []string{"aaa:a1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFollowServiceLogsPrintsNewEventsOnce(t *testing.T) {
+	prev := tailInterval
+	tailInterval = 5 * time.Millisecond
+	defer func() { tailInterval = prev }()
+
+	client, logs := logsTestClient()
+	logs.appendAt("/ecs/api:ecs/api/aaa", 1000, "old")
+	logs.appendAt("/ecs/api:ecs/api/aaa", 5000, "a5")
+	stop := make(chan struct{})
+	done := make(chan error)
+	var mu sync.Mutex
+	got := make([]string, 0)
+	go func() {
+		done <- client.FollowServiceLogs("qa", "api", LogsQuery{Since: fromMillis(2000)}, stop, func(event *LogEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, event.TaskID+":"+event.Message)
+		})
+	}()
+	time.Sleep(15 * time.Millisecond)
+	// an event sharing the last seen timestamp must not be lost
+	logs.appendAt("/ecs/api:ecs/api/bbb", 5000, "b5")
+	logs.appendAt("/ecs/api:ecs/api/aaa", 6000, "a6")
+	time.Sleep(15 * time.Millisecond)
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"aaa:a5", "bbb:b5", "aaa:a6"}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,112 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// RegisterInputFromTaskDefinition converts a described task definition into
+// an equivalent registration request. Read-only fields (revision, status,
+// requiresAttributes, compatibilities, registeredAt/By...) are dropped, and
+// the result shares no pointers with the task definition, so it can be
+// modified freely. Tags are not part of a described task definition, see
+// CloneTaskDefinition.
+func RegisterInputFromTaskDefinition(def *ecs.TaskDefinition) (*ecs.RegisterTaskDefinitionInput, error) {
+	input := &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions:    def.ContainerDefinitions,
+		Cpu:                     def.Cpu,
+		EphemeralStorage:        def.EphemeralStorage,
+		ExecutionRoleArn:        def.ExecutionRoleArn,
+		Family:                  def.Family,
+		InferenceAccelerators:   def.InferenceAccelerators,
+		IpcMode:                 def.IpcMode,
+		Memory:                  def.Memory,
+		NetworkMode:             def.NetworkMode,
+		PidMode:                 def.PidMode,
+		PlacementConstraints:    def.PlacementConstraints,
+		ProxyConfiguration:      def.ProxyConfiguration,
+		RequiresCompatibilities: def.RequiresCompatibilities,
+		RuntimePlatform:         def.RuntimePlatform,
+		TaskRoleArn:             def.TaskRoleArn,
+		Volumes:                 def.Volumes,
+	}
+	return copyRegisterInput(input)
+}
+
+// copyRegisterInput deep copies a registration request
+func copyRegisterInput(input *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionInput, error) {
+	encoded, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy task definition: %v", err)
+	}
+	out := &ecs.RegisterTaskDefinitionInput{}
+	if err = json.Unmarshal(encoded, out); err != nil {
+		return nil, fmt.Errorf("unable to copy task definition: %v", err)
+	}
+	return out, nil
+}
+
+// taskDefinitionFromInput describes the task definition a registration
+// request would create, without an arn or revision
+func taskDefinitionFromInput(input *ecs.RegisterTaskDefinitionInput) (*ecs.TaskDefinition, error) {
+	encoded, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	def := &ecs.TaskDefinition{}
+	if err = json.Unmarshal(encoded, def); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// CloneTaskDefinition registers a new revision that is a faithful copy of an
+// existing task definition, including its tags, after letting configure
+// modify the registration request. The existing task definition is not
+// modified. Every path that creates a revision from another one should go
+// through here, so no settings are silently dropped.
+func (c *Client) CloneTaskDefinition(existingTask *ecs.TaskDefinition, configure func(*ecs.RegisterTaskDefinitionInput) error) (*ecs.TaskDefinition, error) {
+	input, err := RegisterInputFromTaskDefinition(existingTask)
+	if err != nil {
+		return nil, err
+	}
+	if existingTask.TaskDefinitionArn != nil {
+		tags, err := c.taskDefinitionTags(*existingTask.TaskDefinitionArn)
+		if err != nil {
+			return nil, err
+		}
+		input.Tags = tags
+	}
+	if configure != nil {
+		if err = configure(input); err != nil {
+			return nil, err
+		}
+	}
+	return c.RegisterTaskDefinition(input)
+}
+
+// RegisterTaskDefinition registers a new task definition revision
+func (c *Client) RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (*ecs.TaskDefinition, error) {
+	output, err := c.ECS.RegisterTaskDefinition(input)
+	if err != nil {
+		return nil, err
+	}
+	return output.TaskDefinition, nil
+}
+
+func (c *Client) taskDefinitionTags(arn string) ([]*ecs.Tag, error) {
+	output, err := c.ECS.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(arn),
+		Include:        []*string{aws.String(ecs.TaskDefinitionFieldTags)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Tags) == 0 {
+		return nil, nil
+	}
+	return output.Tags, nil
+}
@@ -0,0 +1,121 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// fullTaskDef returns a task definition with every registrable field set
+func fullTaskDef() *ecs.TaskDefinition {
+	def := testTaskDef("api", 7, "api:1")
+	def.ContainerDefinitions = append(def.ContainerDefinitions, &ecs.ContainerDefinition{
+		Name:      aws.String("sidecar"),
+		Image:     aws.String("envoy:1"),
+		Essential: aws.Bool(false),
+	})
+	def.ContainerDefinitions[0].LogConfiguration = &ecs.LogConfiguration{
+		LogDriver: aws.String("awslogs"),
+		Options:   map[string]*string{"awslogs-group": aws.String("/ecs/api")},
+	}
+	def.Cpu = aws.String("512")
+	def.Memory = aws.String("1024")
+	def.EphemeralStorage = &ecs.EphemeralStorage{SizeInGiB: aws.Int64(30)}
+	def.ExecutionRoleArn = aws.String("arn:aws:iam::1:role/exec")
+	def.TaskRoleArn = aws.String("arn:aws:iam::1:role/task")
+	def.InferenceAccelerators = []*ecs.InferenceAccelerator{{DeviceName: aws.String("a"), DeviceType: aws.String("eia2.medium")}}
+	def.IpcMode = aws.String("task")
+	def.PidMode = aws.String("task")
+	def.NetworkMode = aws.String("awsvpc")
+	def.PlacementConstraints = []*ecs.TaskDefinitionPlacementConstraint{{Type: aws.String("memberOf"), Expression: aws.String("attribute:ecs.os-type == linux")}}
+	def.ProxyConfiguration = &ecs.ProxyConfiguration{ContainerName: aws.String("sidecar"), Type: aws.String("APPMESH")}
+	def.RequiresCompatibilities = aws.StringSlice([]string{"FARGATE"})
+	def.RuntimePlatform = &ecs.RuntimePlatform{CpuArchitecture: aws.String("ARM64"), OperatingSystemFamily: aws.String("LINUX")}
+	def.Volumes = []*ecs.Volume{{Name: aws.String("data"), Host: &ecs.HostVolumeProperties{SourcePath: aws.String("/data")}}}
+	def.Status = aws.String("ACTIVE")
+	def.Compatibilities = aws.StringSlice([]string{"EC2", "FARGATE"})
+	def.RequiresAttributes = []*ecs.Attribute{{Name: aws.String("com.amazonaws.ecs.capability.docker-remote-api.1.18")}}
+	return def
+}
+
+func TestCloneTaskDefinitionRoundTripsEveryField(t *testing.T) {
+	def := fullTaskDef()
+	fake := newFakeECS()
+	fake.taskDefs[*def.TaskDefinitionArn] = def
+	fake.tags[*def.TaskDefinitionArn] = []*ecs.Tag{{Key: aws.String("team"), Value: aws.String("platform")}}
+	client := &Client{ECS: fake}
+
+	if _, err := client.CreateNewTaskWithImage(def, "api:2"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.registered) != 1 {
+		t.Fatalf("expected 1 registration, got %d", len(fake.registered))
+	}
+	input := fake.registered[0]
+
+	// every registrable field must match the source task definition,
+	// except the image we changed
+	wantContainers := fullTaskDef().ContainerDefinitions
+	wantContainers[0].Image = aws.String("api:2")
+	source := reflect.ValueOf(fullTaskDef()).Elem()
+	registered := reflect.ValueOf(input).Elem()
+	for i := 0; i < registered.NumField(); i++ {
+		field := registered.Type().Field(i)
+		if field.PkgPath != "" || field.Name == "Tags" {
+			continue
+		}
+		want := source.FieldByName(field.Name)
+		if !want.IsValid() {
+			t.Errorf("field %s has no counterpart in TaskDefinition", field.Name)
+			continue
+		}
+		if field.Name == "ContainerDefinitions" {
+			want = reflect.ValueOf(wantContainers)
+		}
+		if want.IsZero() {
+			t.Errorf("test task definition does not set %s", field.Name)
+		}
+		if !reflect.DeepEqual(registered.Field(i).Interface(), want.Interface()) {
+			t.Errorf("field %s did not round trip: expected %v, got %v", field.Name, want.Interface(), registered.Field(i).Interface())
+		}
+	}
+	if !reflect.DeepEqual(input.Tags, fake.tags[*def.TaskDefinitionArn]) {
+		t.Errorf("expected tags %v, got %v", fake.tags[*def.TaskDefinitionArn], input.Tags)
+	}
+	if EssentialImage(def) != "api:1" {
+		t.Errorf("source task definition was modified")
+	}
+}
+
+func TestCopyTaskDefinitionKeepsSettings(t *testing.T) {
+	def := fullTaskDef()
+	fake := newFakeECS()
+	fake.taskDefs[*def.TaskDefinitionArn] = def
+	client := &Client{ECS: fake}
+
+	if _, err := client.CopyTaskDefinition(def, "api-worker", WithCommand("bin/worker --queue default")); err != nil {
+		t.Fatal(err)
+	}
+	input := fake.registered[0]
+	if *input.Family != "api-worker" {
+		t.Errorf("expected family api-worker, got %s", *input.Family)
+	}
+	essential := findEssentialDefinition(input.ContainerDefinitions)
+	if *essential.Name != "api-worker" {
+		t.Errorf("expected essential container to be renamed, got %s", *essential.Name)
+	}
+	if got := aws.StringValueSlice(essential.Command); !reflect.DeepEqual(got, []string{"bin/worker", "--queue", "default"}) {
+		t.Errorf("unexpected command %v", got)
+	}
+	if *input.NetworkMode != "awsvpc" || *input.TaskRoleArn != *def.TaskRoleArn || *input.Cpu != "512" {
+		t.Errorf("copy dropped task settings: %v", input)
+	}
+	if *findEssential(def).Name != "api" {
+		t.Errorf("source task definition was modified")
+	}
+	if input.Tags != nil {
+		t.Errorf("expected no tags, got %v", input.Tags)
+	}
+}
@@ -0,0 +1,225 @@
+package ecs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// maxRunTaskCount is the most tasks a single RunTask call can start
+const maxRunTaskCount = 10
+
+// RunTaskOptions configures how and where a one-off task runs
+type RunTaskOptions struct {
+	// Container is the name of the container the overrides apply to,
+	// the first container of the task definition if empty
+	Container string
+	// Command overrides the command of the container, if not empty
+	Command string
+	// Environment adds to, or overrides, the container's environment
+	Environment []*ecs.KeyValuePair
+	// Cpu and Memory override the container's cpu units and memory (MiB)
+	Cpu    int64
+	Memory int64
+	// TaskCpu and TaskMemory override the task level sizing, which Fargate
+	// tasks need raised along with their container's
+	TaskCpu    string
+	TaskMemory string
+	// TaskRoleArn overrides the role the task's containers assume
+	TaskRoleArn string
+	// Count is the number of tasks to start, 1 if zero
+	Count int64
+	// LaunchType is EC2, FARGATE or EXTERNAL. It cannot be combined
+	// with a capacity provider strategy.
+	LaunchType               string
+	CapacityProviderStrategy []*ecs.CapacityProviderStrategyItem
+	PlatformVersion          string
+	// Subnets, SecurityGroups and AssignPublicIP make up the awsvpc
+	// network configuration, which is required for Fargate tasks
+	Subnets        []string
+	SecurityGroups []string
+	AssignPublicIP string
+}
+
+// RunTaskOptionsFromService returns options that run a task the same way
+// a service runs its tasks: same launch type or capacity provider strategy,
+// platform version and network configuration
+func RunTaskOptionsFromService(svc *ecs.Service) RunTaskOptions {
+	opts := RunTaskOptions{
+		LaunchType:               aws.StringValue(svc.LaunchType),
+		CapacityProviderStrategy: svc.CapacityProviderStrategy,
+		PlatformVersion:          aws.StringValue(svc.PlatformVersion),
+	}
+	if len(opts.CapacityProviderStrategy) > 0 {
+		opts.LaunchType = ""
+	}
+	if svc.NetworkConfiguration != nil && svc.NetworkConfiguration.AwsvpcConfiguration != nil {
+		vpc := svc.NetworkConfiguration.AwsvpcConfiguration
+		opts.Subnets = aws.StringValueSlice(vpc.Subnets)
+		opts.SecurityGroups = aws.StringValueSlice(vpc.SecurityGroups)
+		opts.AssignPublicIP = aws.StringValue(vpc.AssignPublicIp)
+	}
+	return opts
+}
+
+// ParseCapacityProvider parses a capacity provider strategy item in the
+// form provider[:weight[:base]]
+func ParseCapacityProvider(value string) (*ecs.CapacityProviderStrategyItem, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 || parts[0] == "" {
+		return nil, fmt.Errorf("invalid capacity provider %q, expected provider[:weight[:base]]", value)
+	}
+	item := &ecs.CapacityProviderStrategyItem{CapacityProvider: aws.String(parts[0])}
+	if len(parts) > 1 {
+		weight, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid capacity provider weight %q: %v", parts[1], err)
+		}
+		item.Weight = aws.Int64(weight)
+	}
+	if len(parts) > 2 {
+		base, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid capacity provider base %q: %v", parts[2], err)
+		}
+		item.Base = aws.Int64(base)
+	}
+	return item, nil
+}
+
+// buildRunTaskInput translates options into a RunTask request
+func buildRunTaskInput(cluster string, task *ecs.TaskDefinition, opts RunTaskOptions) (*ecs.RunTaskInput, error) {
+	input := &ecs.RunTaskInput{
+		Cluster:        aws.String(cluster),
+		TaskDefinition: task.TaskDefinitionArn,
+	}
+	if opts.LaunchType != "" && len(opts.CapacityProviderStrategy) > 0 {
+		return nil, fmt.Errorf("a launch type and a capacity provider strategy cannot both be specified")
+	}
+	if opts.LaunchType != "" {
+		input.LaunchType = aws.String(opts.LaunchType)
+	}
+	if len(opts.CapacityProviderStrategy) > 0 {
+		input.CapacityProviderStrategy = opts.CapacityProviderStrategy
+	}
+	if opts.PlatformVersion != "" {
+		input.PlatformVersion = aws.String(opts.PlatformVersion)
+	}
+	if len(opts.Subnets) > 0 {
+		vpc := &ecs.AwsVpcConfiguration{
+			Subnets: aws.StringSlice(opts.Subnets),
+		}
+		if len(opts.SecurityGroups) > 0 {
+			vpc.SecurityGroups = aws.StringSlice(opts.SecurityGroups)
+		}
+		if opts.AssignPublicIP != "" {
+			vpc.AssignPublicIp = aws.String(opts.AssignPublicIP)
+		}
+		input.NetworkConfiguration = &ecs.NetworkConfiguration{AwsvpcConfiguration: vpc}
+	} else if aws.StringValue(task.NetworkMode) == ecs.NetworkModeAwsvpc {
+		return nil, fmt.Errorf("task definition %s uses awsvpc networking, please specify subnets", aws.StringValue(task.Family))
+	}
+	overrides, err := buildTaskOverride(task, opts)
+	if err != nil {
+		return nil, err
+	}
+	input.Overrides = overrides
+	return input, nil
+}
+
+// buildTaskOverride maps the override options onto a TaskOverride, returning
+// nil if nothing is overridden
+func buildTaskOverride(task *ecs.TaskDefinition, opts RunTaskOptions) (*ecs.TaskOverride, error) {
+	container := task.ContainerDefinitions[0]
+	if opts.Container != "" {
+		container = nil
+		for _, def := range task.ContainerDefinitions {
+			if aws.StringValue(def.Name) == opts.Container {
+				container = def
+			}
+		}
+		if container == nil {
+			return nil, fmt.Errorf("container %s not found in task definition %s", opts.Container, aws.StringValue(task.Family))
+		}
+	}
+	containerOverride := &ecs.ContainerOverride{Name: container.Name}
+	override := &ecs.TaskOverride{}
+	overridden := false
+	if opts.Command != "" {
+		commandParts, err := parseCommandOverride(opts.Command)
+		if err != nil {
+			return nil, err
+		}
+		containerOverride.Command = mapCommandStrPointer(commandParts)
+		overridden = true
+	}
+	if len(opts.Environment) > 0 {
+		containerOverride.Environment = opts.Environment
+		overridden = true
+	}
+	if opts.Cpu > 0 {
+		containerOverride.Cpu = aws.Int64(opts.Cpu)
+		overridden = true
+	}
+	if opts.Memory > 0 {
+		containerOverride.Memory = aws.Int64(opts.Memory)
+		overridden = true
+	}
+	if opts.TaskCpu != "" {
+		override.Cpu = aws.String(opts.TaskCpu)
+		overridden = true
+	}
+	if opts.TaskMemory != "" {
+		override.Memory = aws.String(opts.TaskMemory)
+		overridden = true
+	}
+	if opts.TaskRoleArn != "" {
+		override.TaskRoleArn = aws.String(opts.TaskRoleArn)
+		overridden = true
+	}
+	if !overridden {
+		return nil, nil
+	}
+	if containerOverride.Command != nil || containerOverride.Environment != nil || containerOverride.Cpu != nil || containerOverride.Memory != nil {
+		override.ContainerOverrides = []*ecs.ContainerOverride{containerOverride}
+	}
+	return override, nil
+}
+
+// RunTask runs one or more one-off tasks of a task definition, starting
+// them in batches when more than RunTask allows at once are requested. If a
+// batch fails, the tasks started by the earlier batches are returned along
+// with the error, since they are running all the same.
+func (c *Client) RunTask(cluster string, task *ecs.TaskDefinition, opts RunTaskOptions) (*ecs.RunTaskOutput, error) {
+	input, err := buildRunTaskInput(cluster, task, opts)
+	if err != nil {
+		return nil, err
+	}
+	count := opts.Count
+	if count < 1 {
+		count = 1
+	}
+	remaining := count
+	output := &ecs.RunTaskOutput{}
+	for remaining > 0 {
+		batch := remaining
+		if batch > maxRunTaskCount {
+			batch = maxRunTaskCount
+		}
+		input.Count = aws.Int64(batch)
+		result, err := c.ECS.RunTask(input)
+		if err != nil {
+			if len(output.Tasks) > 0 {
+				return output, fmt.Errorf("started %d of %d tasks: %v", len(output.Tasks), count, err)
+			}
+			return nil, err
+		}
+		output.Tasks = append(output.Tasks, result.Tasks...)
+		output.Failures = append(output.Failures, result.Failures...)
+		remaining -= batch
+	}
+	return output, nil
+}
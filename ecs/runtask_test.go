@@ -0,0 +1,240 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestRunTaskOptionsFromService(t *testing.T) {
+	fargate := &ecs.Service{
+		LaunchType:      aws.String("FARGATE"),
+		PlatformVersion: aws.String("1.4.0"),
+		NetworkConfiguration: &ecs.NetworkConfiguration{
+			AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+				Subnets:        aws.StringSlice([]string{"subnet-a", "subnet-b"}),
+				SecurityGroups: aws.StringSlice([]string{"sg-1"}),
+				AssignPublicIp: aws.String("DISABLED"),
+			},
+		},
+	}
+	spot := []*ecs.CapacityProviderStrategyItem{{CapacityProvider: aws.String("FARGATE_SPOT"), Weight: aws.Int64(1)}}
+	tests := []struct {
+		name string
+		svc  *ecs.Service
+		want RunTaskOptions
+	}{
+		{"ec2", &ecs.Service{LaunchType: aws.String("EC2")}, RunTaskOptions{LaunchType: "EC2"}},
+		{"fargate", fargate, RunTaskOptions{
+			LaunchType:      "FARGATE",
+			PlatformVersion: "1.4.0",
+			Subnets:         []string{"subnet-a", "subnet-b"},
+			SecurityGroups:  []string{"sg-1"},
+			AssignPublicIP:  "DISABLED",
+		}},
+		{"capacity providers", &ecs.Service{LaunchType: aws.String(""), CapacityProviderStrategy: spot}, RunTaskOptions{CapacityProviderStrategy: spot}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RunTaskOptionsFromService(tt.svc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseCapacityProvider(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *ecs.CapacityProviderStrategyItem
+		wantErr bool
+	}{
+		{"FARGATE", &ecs.CapacityProviderStrategyItem{CapacityProvider: aws.String("FARGATE")}, false},
+		{"FARGATE_SPOT:3:1", &ecs.CapacityProviderStrategyItem{CapacityProvider: aws.String("FARGATE_SPOT"), Weight: aws.Int64(3), Base: aws.Int64(1)}, false},
+		{"FARGATE:x", nil, true},
+		{":1", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseCapacityProvider(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildRunTaskInput(t *testing.T) {
+	def := testTaskDef("api", 1, "api:1")
+	def.NetworkMode = aws.String("awsvpc")
+	_, err := buildRunTaskInput("qa", def, RunTaskOptions{LaunchType: "FARGATE"})
+	if err == nil || !strings.Contains(err.Error(), "subnets") {
+		t.Errorf("expected missing subnets error, got %v", err)
+	}
+	_, err = buildRunTaskInput("qa", def, RunTaskOptions{
+		LaunchType:               "FARGATE",
+		CapacityProviderStrategy: []*ecs.CapacityProviderStrategyItem{{CapacityProvider: aws.String("FARGATE")}},
+	})
+	if err == nil {
+		t.Errorf("expected launch type and capacity provider conflict")
+	}
+	input, err := buildRunTaskInput("qa", def, RunTaskOptions{
+		Command:        "bin/migrate --force",
+		LaunchType:     "FARGATE",
+		Subnets:        []string{"subnet-a"},
+		SecurityGroups: []string{"sg-1"},
+		AssignPublicIP: "ENABLED",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *input.LaunchType != "FARGATE" || input.CapacityProviderStrategy != nil {
+		t.Errorf("unexpected placement %v", input)
+	}
+	vpc := input.NetworkConfiguration.AwsvpcConfiguration
+	if aws.StringValueSlice(vpc.Subnets)[0] != "subnet-a" || *vpc.AssignPublicIp != "ENABLED" || *vpc.SecurityGroups[0] != "sg-1" {
+		t.Errorf("unexpected network configuration %v", vpc)
+	}
+	command := aws.StringValueSlice(input.Overrides.ContainerOverrides[0].Command)
+	if !reflect.DeepEqual(command, []string{"bin/migrate", "--force"}) {
+		t.Errorf("unexpected command %v", command)
+	}
+}
+
+func TestBuildTaskOverride(t *testing.T) {
+	def := testTaskDef("api", 1, "api:1")
+	def.ContainerDefinitions = append(def.ContainerDefinitions, &ecs.ContainerDefinition{Name: aws.String("worker")})
+	env := []*ecs.KeyValuePair{{Name: aws.String("DRY_RUN"), Value: aws.String("false")}}
+	tests := []struct {
+		name    string
+		task    *ecs.TaskDefinition
+		opts    RunTaskOptions
+		want    *ecs.TaskOverride
+		wantErr bool
+	}{
+		{"nothing", def, RunTaskOptions{}, nil, false},
+		{"unknown container", def, RunTaskOptions{Container: "nope", Command: "ls"}, nil, true},
+		{
+			"container env and sizing",
+			def,
+			RunTaskOptions{Container: "worker", Environment: env, Cpu: 256, Memory: 2048},
+			&ecs.TaskOverride{ContainerOverrides: []*ecs.ContainerOverride{{
+				Name:        aws.String("worker"),
+				Environment: env,
+				Cpu:         aws.Int64(256),
+				Memory:      aws.Int64(2048),
+			}}},
+			false,
+		},
+		{
+			"fargate sizing and role",
+			func() *ecs.TaskDefinition {
+				d := testTaskDef("api", 1, "api:1")
+				d.Cpu = aws.String("256")
+				d.Memory = aws.String("512")
+				return d
+			}(),
+			RunTaskOptions{Memory: 1024, TaskMemory: "2048", TaskRoleArn: "arn:aws:iam::1:role/migrate"},
+			&ecs.TaskOverride{
+				Memory:      aws.String("2048"),
+				TaskRoleArn: aws.String("arn:aws:iam::1:role/migrate"),
+				ContainerOverrides: []*ecs.ContainerOverride{{
+					Name:   aws.String("api"),
+					Memory: aws.Int64(1024),
+				}},
+			},
+			false,
+		},
+		{
+			"container sizing leaves the task's",
+			func() *ecs.TaskDefinition {
+				d := testTaskDef("api", 1, "api:1")
+				d.Cpu = aws.String("256")
+				d.Memory = aws.String("512")
+				return d
+			}(),
+			RunTaskOptions{Cpu: 128, Memory: 256},
+			&ecs.TaskOverride{ContainerOverrides: []*ecs.ContainerOverride{{
+				Name:   aws.String("api"),
+				Cpu:    aws.Int64(128),
+				Memory: aws.Int64(256),
+			}}},
+			false,
+		},
+		{
+			"role only",
+			def,
+			RunTaskOptions{TaskRoleArn: "arn:aws:iam::1:role/migrate"},
+			&ecs.TaskOverride{TaskRoleArn: aws.String("arn:aws:iam::1:role/migrate")},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTaskOverride(tt.task, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRunTaskCountBatches(t *testing.T) {
+	fake := newFakeECS()
+	client := &Client{ECS: fake}
+	output, err := client.RunTask("qa", testTaskDef("api", 1, "api:1"), RunTaskOptions{Count: 23})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Tasks) != 23 {
+		t.Errorf("expected 23 tasks, got %d", len(output.Tasks))
+	}
+	counts := make([]int64, len(fake.runs))
+	for i, run := range fake.runs {
+		counts[i] = *run.Count
+	}
+	if want := []int64{10, 10, 3}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("expected batches %v, got %v", want, counts)
+	}
+}
+
+// failingRunECS starts tasks for the first few RunTask calls, then fails
+type failingRunECS struct {
+	*fakeECS
+	succeed int
+}
+
+func (f *failingRunECS) RunTask(input *ecs.RunTaskInput) (*ecs.RunTaskOutput, error) {
+	if len(f.runs) >= f.succeed {
+		return nil, fmt.Errorf("ThrottlingException: Rate exceeded")
+	}
+	return f.fakeECS.RunTask(input)
+}
+
+func TestRunTaskReturnsStartedTasksOnError(t *testing.T) {
+	fake := &failingRunECS{fakeECS: newFakeECS(), succeed: 2}
+	client := &Client{ECS: fake}
+	output, err := client.RunTask("qa", testTaskDef("api", 1, "api:1"), RunTaskOptions{Count: 23})
+	if err == nil || !strings.Contains(err.Error(), "started 20 of 23 tasks") {
+		t.Errorf("expected an error about the tasks started, got %v", err)
+	}
+	if output == nil || len(output.Tasks) != 20 {
+		t.Fatalf("expected the 20 tasks already started, got %v", output)
+	}
+
+	fake = &failingRunECS{fakeECS: newFakeECS()}
+	client = &Client{ECS: fake}
+	if output, err = client.RunTask("qa", testTaskDef("api", 1, "api:1"), RunTaskOptions{}); err == nil || output != nil {
+		t.Errorf("expected only an error when no task started, got %v, %v", output, err)
+	}
+}
@@ -0,0 +1,205 @@
+package ecs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// Secret stores an environment variable can reference, written as the
+// prefix of its value ("KEY=ssm:/path", "KEY=secretsmanager:arn")
+const (
+	SecretStoreSSM            = "ssm"
+	SecretStoreSecretsManager = "secretsmanager"
+)
+
+// EnvVar is an environment entry of a container: a plain value, or a
+// secret whose Value is the valueFrom reference in Store
+type EnvVar struct {
+	Name  string
+	Value string
+	Store string
+}
+
+// IsSecret reports whether the variable references a secret
+func (v EnvVar) IsSecret() bool {
+	return v.Store != ""
+}
+
+// String formats the variable as a KEY=value line
+func (v EnvVar) String() string {
+	if v.IsSecret() {
+		return fmt.Sprintf("%s=%s:%s", v.Name, v.Store, v.Value)
+	}
+	return fmt.Sprintf("%s=%s", v.Name, formatEnvValue(v.Value))
+}
+
+// ParseEnvVar classifies a name and value, treating values prefixed with
+// ssm: or secretsmanager: as secret references
+func ParseEnvVar(name, value string) EnvVar {
+	for _, store := range []string{SecretStoreSSM, SecretStoreSecretsManager} {
+		if strings.HasPrefix(value, store+":") {
+			return EnvVar{Name: name, Value: strings.TrimPrefix(value, store+":"), Store: store}
+		}
+	}
+	return EnvVar{Name: name, Value: value}
+}
+
+// secretStore works out which store a valueFrom reference points to.
+// Secrets Manager is always referenced by arn, so anything else is SSM.
+func secretStore(valueFrom string) string {
+	if strings.HasPrefix(valueFrom, "arn:") && strings.Contains(valueFrom, ":secretsmanager:") {
+		return SecretStoreSecretsManager
+	}
+	return SecretStoreSSM
+}
+
+// ContainerEnv lists the plain environment and the secrets of a container
+func ContainerEnv(container *ecs.ContainerDefinition) []EnvVar {
+	out := make([]EnvVar, 0, len(container.Environment)+len(container.Secrets))
+	for _, pair := range container.Environment {
+		out = append(out, EnvVar{Name: aws.StringValue(pair.Name), Value: aws.StringValue(pair.Value)})
+	}
+	for _, secret := range container.Secrets {
+		valueFrom := aws.StringValue(secret.ValueFrom)
+		out = append(out, EnvVar{Name: aws.StringValue(secret.Name), Value: valueFrom, Store: secretStore(valueFrom)})
+	}
+	return out
+}
+
+// EnvVarsToString formats variables as a multiline KEY=value block
+func EnvVarsToString(vars []EnvVar) string {
+	out := ""
+	for _, v := range vars {
+		out += v.String() + "\n"
+	}
+	return out
+}
+
+// StringToEnvVars parses a KEY=value block, recognizing secret references
+func StringToEnvVars(input string) ([]EnvVar, error) {
+	pairs, err := StringToKeyPairs(input)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]EnvVar, 0, len(pairs))
+	for _, pair := range pairs {
+		out = append(out, ParseEnvVar(aws.StringValue(pair.Name), aws.StringValue(pair.Value)))
+	}
+	return out, nil
+}
+
+// SetEnvVar replaces the variable of the same name (plain or secret), or
+// appends it
+func SetEnvVar(vars []EnvVar, v EnvVar) []EnvVar {
+	for i := range vars {
+		if vars[i].Name == v.Name {
+			vars[i] = v
+			return vars
+		}
+	}
+	return append(vars, v)
+}
+
+// SplitEnvVars splits variables into a container's Environment and Secrets
+func SplitEnvVars(vars []EnvVar) ([]*ecs.KeyValuePair, []*ecs.Secret) {
+	env := make([]*ecs.KeyValuePair, 0, len(vars))
+	secrets := make([]*ecs.Secret, 0)
+	for _, v := range vars {
+		if v.IsSecret() {
+			secrets = append(secrets, &ecs.Secret{Name: aws.String(v.Name), ValueFrom: aws.String(v.Value)})
+			continue
+		}
+		env = append(env, &ecs.KeyValuePair{Name: aws.String(v.Name), Value: aws.String(v.Value)})
+	}
+	return env, secrets
+}
+
+// CreateNewTaskWithEnv registers a new task, based on the passed task, with
+// the essential container's environment and secrets replaced
+func (c *Client) CreateNewTaskWithEnv(existingTask *ecs.TaskDefinition, vars []EnvVar) (*ecs.TaskDefinition, error) {
+	env, secrets := SplitEnvVars(vars)
+	if len(secrets) > 0 && aws.StringValue(existingTask.ExecutionRoleArn) == "" {
+		return nil, fmt.Errorf("task definition %s has no execution role, which ECS needs to read secrets", aws.StringValue(existingTask.Family))
+	}
+	return c.updateEssential(existingTask, func(container *ecs.ContainerDefinition) {
+		container.SetEnvironment(env)
+		if len(secrets) > 0 {
+			container.SetSecrets(secrets)
+		} else {
+			container.Secrets = nil
+		}
+	})
+}
+
+// DefaultSecretName is where env set --secret stores a service's secret
+// when no name is given: /cluster/service/KEY in SSM, cluster/service/KEY
+// in Secrets Manager
+func DefaultSecretName(store, cluster, service, key string) string {
+	if store == SecretStoreSSM {
+		return fmt.Sprintf("/%s/%s/%s", cluster, service, key)
+	}
+	return fmt.Sprintf("%s/%s/%s", cluster, service, key)
+}
+
+// PutSecret creates or updates a secret, returning the valueFrom reference
+// a container definition uses to read it. SSM parameters are stored as
+// SecureString, Secrets Manager secrets are created if they do not exist.
+func (c *Client) PutSecret(store, name, value string) (string, error) {
+	switch store {
+	case SecretStoreSSM:
+		name = ssmParameterName(name)
+		_, err := c.SSM.PutParameter(&ssm.PutParameterInput{
+			Name:      aws.String(name),
+			Value:     aws.String(value),
+			Type:      aws.String(ssm.ParameterTypeSecureString),
+			Overwrite: aws.Bool(true),
+		})
+		if err != nil {
+			return "", err
+		}
+		return name, nil
+	case SecretStoreSecretsManager:
+		svc := c.SecretsManager
+		output, err := svc.PutSecretValue(&secretsmanager.PutSecretValueInput{
+			SecretId:     aws.String(name),
+			SecretString: aws.String(value),
+		})
+		if err == nil {
+			return aws.StringValue(output.ARN), nil
+		}
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != secretsmanager.ErrCodeResourceNotFoundException {
+			return "", err
+		}
+		created, err := svc.CreateSecret(&secretsmanager.CreateSecretInput{
+			Name:         aws.String(name),
+			SecretString: aws.String(value),
+		})
+		if err != nil {
+			return "", err
+		}
+		return aws.StringValue(created.ARN), nil
+	}
+	return "", fmt.Errorf("unknown secret store %q, expected %s or %s", store, SecretStoreSSM, SecretStoreSecretsManager)
+}
+
+// ssmParameterName turns a parameter arn into the name PutParameter takes
+func ssmParameterName(ref string) string {
+	if !strings.HasPrefix(ref, "arn:") {
+		return ref
+	}
+	i := strings.Index(ref, ":parameter")
+	if i < 0 {
+		return ref
+	}
+	name := strings.TrimPrefix(ref[i+len(":parameter"):], "/")
+	if strings.Contains(name, "/") {
+		return "/" + name
+	}
+	return name
+}
@@ -0,0 +1,138 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+type fakeSSM struct {
+	ssmiface.SSMAPI
+	params map[string]string
+}
+
+func (f *fakeSSM) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	if aws.StringValue(input.Type) != ssm.ParameterTypeSecureString {
+		return nil, awserr.New("ValidationException", "expected a SecureString", nil)
+	}
+	f.params[*input.Name] = *input.Value
+	return &ssm.PutParameterOutput{}, nil
+}
+
+type fakeSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+	secrets map[string]string
+}
+
+func (f *fakeSecretsManager) arn(name string) string {
+	return "arn:aws:secretsmanager:us-west-2:1:secret:" + name + "-AbCdEf"
+}
+
+func (f *fakeSecretsManager) PutSecretValue(input *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+	for name := range f.secrets {
+		if *input.SecretId == name || *input.SecretId == f.arn(name) {
+			f.secrets[name] = *input.SecretString
+			return &secretsmanager.PutSecretValueOutput{ARN: aws.String(f.arn(name))}, nil
+		}
+	}
+	return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+}
+
+func (f *fakeSecretsManager) CreateSecret(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+	f.secrets[*input.Name] = *input.SecretString
+	return &secretsmanager.CreateSecretOutput{ARN: aws.String(f.arn(*input.Name))}, nil
+}
+
+func TestEnvVarsRoundTrip(t *testing.T) {
+	container := &ecs.ContainerDefinition{
+		Environment: []*ecs.KeyValuePair{{Name: aws.String("APP_ENV"), Value: aws.String("qa")}},
+		Secrets: []*ecs.Secret{
+			{Name: aws.String("DB_PASSWORD"), ValueFrom: aws.String("/qa/api/DB_PASSWORD")},
+			{Name: aws.String("API_KEY"), ValueFrom: aws.String("arn:aws:secretsmanager:us-west-2:1:secret:api-key-AbCdEf")},
+		},
+	}
+	block := EnvVarsToString(ContainerEnv(container))
+	want := "APP_ENV=qa\nDB_PASSWORD=ssm:/qa/api/DB_PASSWORD\nAPI_KEY=secretsmanager:arn:aws:secretsmanager:us-west-2:1:secret:api-key-AbCdEf\n"
+	if block != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, block)
+	}
+	vars, err := StringToEnvVars(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, secrets := SplitEnvVars(vars)
+	if !reflect.DeepEqual(env, container.Environment) || !reflect.DeepEqual(secrets, container.Secrets) {
+		t.Errorf("expected the block to round trip, got %v %v", env, secrets)
+	}
+}
+
+func TestSetEnvVarReplacesAcrossKinds(t *testing.T) {
+	vars := []EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "/b", Store: SecretStoreSSM}}
+	vars = SetEnvVar(vars, ParseEnvVar("B", "plain"))
+	vars = SetEnvVar(vars, ParseEnvVar("C", "ssm:/c"))
+	want := []EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "plain"}, {Name: "C", Value: "/c", Store: SecretStoreSSM}}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("expected %v, got %v", want, vars)
+	}
+}
+
+func TestCreateNewTaskWithEnv(t *testing.T) {
+	fake := newFakeECS()
+	def := testTaskDef("api", 1, "api:v1")
+	fake.taskDefs[*def.TaskDefinitionArn] = def
+	client := &Client{ECS: fake}
+	vars := []EnvVar{{Name: "APP_ENV", Value: "prod"}, {Name: "DB_PASSWORD", Value: "/prod/db", Store: SecretStoreSSM}}
+	if _, err := client.CreateNewTaskWithEnv(def, vars); err == nil {
+		t.Fatal("expected an error without an execution role")
+	}
+	def.ExecutionRoleArn = aws.String("arn:aws:iam::1:role/ecsTaskExecutionRole")
+	if _, err := client.CreateNewTaskWithEnv(def, vars); err != nil {
+		t.Fatal(err)
+	}
+	container := fake.registered[0].ContainerDefinitions[0]
+	if len(container.Environment) != 1 || *container.Environment[0].Value != "prod" {
+		t.Errorf("unexpected environment %v", container.Environment)
+	}
+	if len(container.Secrets) != 1 || *container.Secrets[0].ValueFrom != "/prod/db" {
+		t.Errorf("unexpected secrets %v", container.Secrets)
+	}
+}
+
+func TestPutSecret(t *testing.T) {
+	ssmFake := &fakeSSM{params: make(map[string]string)}
+	smFake := &fakeSecretsManager{secrets: map[string]string{"qa/api/EXISTING": "old"}}
+	client := &Client{SSM: ssmFake, SecretsManager: smFake}
+	tests := []struct {
+		store string
+		name  string
+		want  string
+	}{
+		{SecretStoreSSM, "/qa/api/DB_PASSWORD", "/qa/api/DB_PASSWORD"},
+		{SecretStoreSSM, "arn:aws:ssm:us-west-2:1:parameter/qa/api/TOKEN", "/qa/api/TOKEN"},
+		{SecretStoreSecretsManager, "qa/api/EXISTING", smFake.arn("qa/api/EXISTING")},
+		{SecretStoreSecretsManager, "qa/api/NEW", smFake.arn("qa/api/NEW")},
+	}
+	for _, tt := range tests {
+		got, err := client.PutSecret(tt.store, tt.name, "s3cret")
+		if err != nil {
+			t.Errorf("PutSecret(%s, %s): %v", tt.store, tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("PutSecret(%s, %s) = %s, want %s", tt.store, tt.name, got, tt.want)
+		}
+	}
+	if ssmFake.params["/qa/api/TOKEN"] != "s3cret" || smFake.secrets["qa/api/EXISTING"] != "s3cret" || smFake.secrets["qa/api/NEW"] != "s3cret" {
+		t.Errorf("expected the secrets to be stored, got %v %v", ssmFake.params, smFake.secrets)
+	}
+	if _, err := client.PutSecret("vault", "x", "y"); err == nil {
+		t.Errorf("expected an error for an unknown store")
+	}
+}
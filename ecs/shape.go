@@ -0,0 +1,148 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MarshalShape encodes an AWS SDK request or response shape as JSON, with
+// the field names AWS uses on the wire and the AWS CLI takes in
+// --cli-input-json. The SDK keeps those names in locationName tags rather
+// than json tags, so the shape is first mapped to plain maps and slices;
+// unset fields are left out.
+func MarshalShape(shape interface{}) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	// commands and patterns are full of && and <, keep them readable
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(shapeValue(reflect.ValueOf(shape))); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+func shapeValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return shapeValue(v.Elem())
+	case reflect.Struct:
+		if _, ok := v.Interface().(json.Marshaler); ok {
+			// time.Time
+			return v.Interface()
+		}
+		out := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			value := v.Field(i)
+			if field.PkgPath != "" || isUnsetShape(value) {
+				continue
+			}
+			out[shapeFieldName(field)] = shapeValue(value)
+		}
+		return out
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// blobs are base64 encoded, as encoding/json does with []byte
+			return v.Bytes()
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = shapeValue(v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			out[key.String()] = shapeValue(v.MapIndex(key))
+		}
+		return out
+	}
+	return v.Interface()
+}
+
+func isUnsetShape(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
+
+// shapeFieldName is the wire name of a field of a shape
+func shapeFieldName(field reflect.StructField) string {
+	if name := field.Tag.Get("locationName"); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// checkShapeFields reports the first key of a decoded JSON document that
+// is not a field of the shape type t, skipping the ignored top level keys.
+// jsonutil drops unknown keys silently, which hides typos in files.
+func checkShapeFields(t reflect.Type, value interface{}, path string, ignored map[string]bool) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			// values of the wrong type are left for jsonutil to report
+			return nil
+		}
+		fields := make(map[string]reflect.Type, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.PkgPath == "" {
+				fields[shapeFieldName(field)] = field.Type
+			}
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		// report the same unknown key first on every run
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldType, ok := fields[key]
+			if !ok {
+				if ignored[key] {
+					continue
+				}
+				return fmt.Errorf("unknown field %s", joinShapePath(path, key))
+			}
+			if err := checkShapeFields(fieldType, object[key], joinShapePath(path, key), nil); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		items, _ := value.([]interface{})
+		for i, item := range items {
+			if err := checkShapeFields(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i), nil); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		object, _ := value.(map[string]interface{})
+		for key, item := range object {
+			if err := checkShapeFields(t.Elem(), item, joinShapePath(path, key), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinShapePath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,31 @@
+package ecs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestMarshalShape(t *testing.T) {
+	input := &ecs.RegisterTaskDefinitionInput{
+		Family: aws.String("api"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{
+			Name:         aws.String("api"),
+			Essential:    aws.Bool(true),
+			Memory:       aws.Int64(512),
+			Command:      aws.StringSlice([]string{"sh", "-c", "bin/migrate && bin/serve"}),
+			DockerLabels: aws.StringMap(map[string]string{"team": "core"}),
+			PortMappings: []*ecs.PortMapping{{ContainerPort: aws.Int64(80)}},
+		}},
+		Tags: []*ecs.Tag{},
+	}
+	got, err := MarshalShape(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"containerDefinitions":[{"command":["sh","-c","bin/migrate && bin/serve"],"dockerLabels":{"team":"core"},"essential":true,"memory":512,"name":"api","portMappings":[{"containerPort":80}]}],"family":"api","tags":[]}`
+	if string(got) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+}
@@ -0,0 +1,553 @@
+package ecs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Kinds of EventBridge rules a spec manages
+const (
+	RuleKindSchedule       = "schedule"
+	RuleKindPostDeployment = "post-deployment"
+)
+
+// ServiceSpec is the desired state of a service, kept in a versioned YAML
+// (or JSON) file and reconciled by ecsy apply. Settings left out of the
+// file are not managed, and stay as they are on the live service. Env,
+// secrets, schedules and post-deployment tasks are complete sets when
+// given: variables and rules that are not listed are removed.
+type ServiceSpec struct {
+	Cluster string `yaml:"cluster" json:"cluster"`
+	Service string `yaml:"service" json:"service"`
+	// Container the container settings apply to, the essential one by default
+	Container string            `yaml:"container,omitempty" json:"container,omitempty"`
+	Image     string            `yaml:"image,omitempty" json:"image,omitempty"`
+	Env       map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	// Secrets maps variable names to the SSM parameter or Secrets Manager
+	// arn they are read from
+	Secrets           map[string]string `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+	Cpu               *int64            `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+	Memory            *int64            `yaml:"memory,omitempty" json:"memory,omitempty"`
+	MemoryReservation *int64            `yaml:"memory_reservation,omitempty" json:"memory_reservation,omitempty"`
+	// TaskCpu and TaskMemory size the whole task, as Fargate requires
+	TaskCpu        string               `yaml:"task_cpu,omitempty" json:"task_cpu,omitempty"`
+	TaskMemory     string               `yaml:"task_memory,omitempty" json:"task_memory,omitempty"`
+	DesiredCount   *int64               `yaml:"desired_count,omitempty" json:"desired_count,omitempty"`
+	Schedules      []ScheduleSpec       `yaml:"schedules,omitempty" json:"schedules,omitempty"`
+	PostDeployment []PostDeploymentSpec `yaml:"post_deployment,omitempty" json:"post_deployment,omitempty"`
+}
+
+// ScheduleSpec is a scheduled task of the service, as created by
+// schedule-task: the rule is named cluster-service-name
+type ScheduleSpec struct {
+	Name     string `yaml:"name" json:"name"`
+	Schedule string `yaml:"schedule" json:"schedule"`
+	Command  string `yaml:"command,omitempty" json:"command,omitempty"`
+}
+
+// PostDeploymentSpec is a task run each time the service reaches a steady
+// state, as created by create-post-deployment-task. Cluster defaults to the
+// service's cluster.
+type PostDeploymentSpec struct {
+	Cluster string `yaml:"cluster,omitempty" json:"cluster,omitempty"`
+	Service string `yaml:"service" json:"service"`
+	Command string `yaml:"command,omitempty" json:"command,omitempty"`
+}
+
+// LoadServiceSpec reads a service spec file
+func LoadServiceSpec(path string) (*ServiceSpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := ParseServiceSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return spec, nil
+}
+
+// ParseServiceSpec parses a YAML or JSON service spec. Unknown fields are
+// an error, so a misspelled setting is not silently left unmanaged.
+func ParseServiceSpec(data []byte) (*ServiceSpec, error) {
+	spec := &ServiceSpec{}
+	if err := yaml.UnmarshalStrict(data, spec); err != nil {
+		return nil, err
+	}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func (s *ServiceSpec) validate() error {
+	if s.Cluster == "" || s.Service == "" {
+		return fmt.Errorf("cluster and service are required")
+	}
+	names := make(map[string]bool)
+	for _, schedule := range s.Schedules {
+		if schedule.Name == "" || schedule.Schedule == "" {
+			return fmt.Errorf("schedules need a name and a schedule expression")
+		}
+		if names[schedule.Name] {
+			return fmt.Errorf("schedule %s is declared twice", schedule.Name)
+		}
+		names[schedule.Name] = true
+	}
+	for _, task := range s.PostDeployment {
+		if task.Service == "" {
+			return fmt.Errorf("post_deployment tasks need a service")
+		}
+	}
+	return nil
+}
+
+// applyTo sets the spec's settings on a registration request
+func (s *ServiceSpec) applyTo(input *ecs.RegisterTaskDefinitionInput) error {
+	container := findEssentialDefinition(input.ContainerDefinitions)
+	if s.Container != "" {
+		container = nil
+		for _, def := range input.ContainerDefinitions {
+			if aws.StringValue(def.Name) == s.Container {
+				container = def
+			}
+		}
+	}
+	if container == nil {
+		return fmt.Errorf("container %q not found in task definition %s", s.Container, aws.StringValue(input.Family))
+	}
+	if s.Image != "" {
+		container.SetImage(s.Image)
+	}
+	if s.Cpu != nil {
+		container.SetCpu(*s.Cpu)
+	}
+	if s.Memory != nil {
+		container.SetMemory(*s.Memory)
+	}
+	if s.MemoryReservation != nil {
+		container.SetMemoryReservation(*s.MemoryReservation)
+	}
+	if s.TaskCpu != "" {
+		input.SetCpu(s.TaskCpu)
+	}
+	if s.TaskMemory != "" {
+		input.SetMemory(s.TaskMemory)
+	}
+	if s.Env != nil {
+		env := make([]*ecs.KeyValuePair, 0, len(s.Env))
+		for _, name := range sortedKeys(s.Env) {
+			env = append(env, &ecs.KeyValuePair{Name: aws.String(name), Value: aws.String(s.Env[name])})
+		}
+		container.SetEnvironment(env)
+	}
+	if s.Secrets != nil {
+		if len(s.Secrets) > 0 && aws.StringValue(input.ExecutionRoleArn) == "" {
+			return fmt.Errorf("task definition %s has no execution role, which ECS needs to read secrets", aws.StringValue(input.Family))
+		}
+		container.Secrets = nil
+		for _, name := range sortedKeys(s.Secrets) {
+			container.Secrets = append(container.Secrets, &ecs.Secret{Name: aws.String(name), ValueFrom: aws.String(s.Secrets[name])})
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SpecDiff is what applying a spec changes on the live service
+type SpecDiff struct {
+	Spec    *ServiceSpec
+	Service *ecs.Service
+	// Current is the deployed task definition, TaskDefinition how the spec
+	// changes it
+	Current        *ecs.TaskDefinition
+	TaskDefinition *TaskDefinitionDiff
+	// ServiceChanges lists the changes to the service itself
+	ServiceChanges []FieldChange
+	// Rules lists the EventBridge rules to create, update or delete
+	Rules []RuleDiff
+}
+
+// RuleDiff is a scheduled or post-deployment rule that differs from the spec
+type RuleDiff struct {
+	Name    string
+	Kind    string
+	Create  bool
+	Delete  bool
+	Changes []FieldChange
+
+	rule       *cloudwatchevents.PutRuleInput
+	clusterArn string
+	command    string
+	// taskDefinition is the task the rule runs, nil for the service's own
+	// task definition once applied
+	taskDefinition *ecs.TaskDefinition
+	// staleTargets are targets of the rule besides the one ecsy puts
+	staleTargets []*string
+}
+
+// RegistersTaskDefinition reports whether applying registers a new revision
+func (d *SpecDiff) RegistersTaskDefinition() bool {
+	return !d.TaskDefinition.IsEmpty()
+}
+
+// IsEmpty reports whether the live service matches the spec
+func (d *SpecDiff) IsEmpty() bool {
+	return !d.RegistersTaskDefinition() && len(d.ServiceChanges) == 0 && len(d.Rules) == 0
+}
+
+// Drift flattens the diff into the fields where the live service differs
+// from the spec, From being the live value and To the declared one. Task
+// definition fields are prefixed with task. or container.NAME., rule fields
+// with rule.NAME.
+func (d *SpecDiff) Drift() []FieldChange {
+	out := make([]FieldChange, 0)
+	prefixed := func(prefix string, changes []FieldChange) {
+		for _, change := range changes {
+			change.Field = prefix + change.Field
+			out = append(out, change)
+		}
+	}
+	prefixed("task.", d.TaskDefinition.Task)
+	for _, container := range d.TaskDefinition.Containers {
+		prefixed("container."+container.Name+".", container.Changes)
+	}
+	prefixed("", d.ServiceChanges)
+	for _, rule := range d.Rules {
+		changes := rule.Changes
+		if rule.taskDefinition == nil && d.RegistersTaskDefinition() {
+			// the rule follows the service's task definition, whose drift
+			// is reported above
+			changes = make([]FieldChange, 0, len(rule.Changes))
+			for _, change := range rule.Changes {
+				if !strings.HasSuffix(change.Field, ".taskDefinition") {
+					changes = append(changes, change)
+				}
+			}
+		}
+		prefixed("rule."+rule.Name+".", changes)
+	}
+	return out
+}
+
+// DiffServiceSpec compares a spec to the live service: its deployed task
+// definition, desired count and the EventBridge rules ecsy created for it
+func (c *Client) DiffServiceSpec(spec *ServiceSpec) (*SpecDiff, error) {
+	svc, err := c.FindService(spec.Cluster, spec.Service)
+	if err != nil {
+		return nil, err
+	}
+	current, err := c.GetTaskDefinition(aws.StringValue(svc.TaskDefinition))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find current task definition: %v", err)
+	}
+	input, err := RegisterInputFromTaskDefinition(current)
+	if err != nil {
+		return nil, err
+	}
+	if err = spec.applyTo(input); err != nil {
+		return nil, err
+	}
+	desired, err := taskDefinitionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+	out := &SpecDiff{
+		Spec:           spec,
+		Service:        svc,
+		Current:        current,
+		TaskDefinition: DiffTaskDefinitions(current, desired),
+	}
+	if spec.DesiredCount != nil {
+		from, to := make(map[string]string), make(map[string]string)
+		setField(from, "desiredCount", formatInt64(svc.DesiredCount))
+		setField(to, "desiredCount", formatInt64(spec.DesiredCount))
+		out.ServiceChanges = diffFields(from, to)
+	}
+	// rules run the applied task definition, which has no arn yet when the
+	// spec registers a new revision
+	ruleTask := current
+	if out.RegistersTaskDefinition() {
+		desired.TaskDefinitionArn = aws.String(aws.StringValue(current.Family) + ":(new revision)")
+		ruleTask = desired
+	}
+	if out.Rules, err = c.diffSpecRules(spec, aws.StringValue(svc.ClusterArn), ruleTask); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) diffSpecRules(spec *ServiceSpec, clusterArn string, ruleTask *ecs.TaskDefinition) ([]RuleDiff, error) {
+	out := make([]RuleDiff, 0)
+	declared := make(map[string]bool)
+	for _, schedule := range spec.Schedules {
+		rule := scheduledTaskRule(spec.Cluster, spec.Service, schedule.Name, schedule.Schedule)
+		diff := RuleDiff{Kind: RuleKindSchedule, rule: rule, clusterArn: clusterArn, command: schedule.Command}
+		if err := c.diffRule(&diff, ruleTask); err != nil {
+			return nil, err
+		}
+		declared[diff.Name] = true
+		if len(diff.Changes) > 0 {
+			out = append(out, diff)
+		}
+	}
+	for _, task := range spec.PostDeployment {
+		input := &CreatePostDeploymentTaskInput{
+			SourceCluster: spec.Cluster,
+			SourceService: spec.Service,
+			TargetCluster: task.Cluster,
+			TargetService: task.Service,
+			Command:       task.Command,
+		}
+		if input.TargetCluster == "" {
+			input.TargetCluster = spec.Cluster
+		}
+		rule, err := c.postDeploymentRule(input)
+		if err != nil {
+			return nil, err
+		}
+		if declared[*rule.Name] {
+			return nil, fmt.Errorf("post-deployment task %s/%s is declared twice", input.TargetCluster, input.TargetService)
+		}
+		diff := RuleDiff{Kind: RuleKindPostDeployment, rule: rule, clusterArn: clusterArn, command: task.Command}
+		target := ruleTask
+		if input.TargetCluster != spec.Cluster || input.TargetService != spec.Service {
+			clusters, err := c.clusterMap()
+			if err != nil {
+				return nil, fmt.Errorf("unable create cluster definitions map: %v", err)
+			}
+			diff.clusterArn = clusters[input.TargetCluster]
+			if target, err = c.GetNewestTaskDefinition(input.TargetCluster, input.TargetService); err != nil {
+				return nil, fmt.Errorf("unable to find task definition of %s/%s: %v", input.TargetCluster, input.TargetService, err)
+			}
+			diff.taskDefinition = target
+		}
+		if err = c.diffRule(&diff, target); err != nil {
+			return nil, err
+		}
+		declared[diff.Name] = true
+		if len(diff.Changes) > 0 {
+			out = append(out, diff)
+		}
+	}
+	stale, err := c.undeclaredRules(spec, declared)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, stale...), nil
+}
+
+// diffRule compares a rule and the target running task on it with what
+// exists live
+func (c *Client) diffRule(diff *RuleDiff, task *ecs.TaskDefinition) error {
+	svc := c.CloudWatchEvents
+	diff.Name = aws.StringValue(diff.rule.Name)
+	target, err := c.taskTarget(diff.clusterArn, task, diff.command)
+	if err != nil {
+		return err
+	}
+	from, to := make(map[string]string), make(map[string]string)
+	existing, err := svc.DescribeRule(&cloudwatchevents.DescribeRuleInput{Name: diff.rule.Name})
+	switch {
+	case err == nil:
+		ruleFields(from, existing.ScheduleExpression, existing.EventPattern, existing.Description)
+		targets, err := svc.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{Rule: diff.rule.Name})
+		if err != nil {
+			return fmt.Errorf("unable to list targets of rule %s: %v", diff.Name, err)
+		}
+		for _, t := range targets.Targets {
+			targetFields(from, t)
+			if aws.StringValue(t.Id) != aws.StringValue(target.Id) {
+				diff.staleTargets = append(diff.staleTargets, t.Id)
+			}
+		}
+	case isNotFound(err):
+		diff.Create = true
+	default:
+		return fmt.Errorf("unable to describe rule %s: %v", diff.Name, err)
+	}
+	ruleFields(to, diff.rule.ScheduleExpression, diff.rule.EventPattern, diff.rule.Description)
+	targetFields(to, target)
+	diff.Changes = diffFields(from, to)
+	return nil
+}
+
+// undeclaredRules finds the rules ecsy created for the service that the
+// spec no longer lists. Kinds of rules the spec does not manage are left
+// alone.
+func (c *Client) undeclaredRules(spec *ServiceSpec, declared map[string]bool) ([]RuleDiff, error) {
+	out := make([]RuleDiff, 0)
+	if spec.Schedules == nil && spec.PostDeployment == nil {
+		return out, nil
+	}
+	rules, err := c.serviceRules(spec.Cluster, spec.Service)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		managed := (rule.kind == RuleKindSchedule && spec.Schedules != nil) ||
+			(rule.kind == RuleKindPostDeployment && spec.PostDeployment != nil)
+		if !managed || declared[aws.StringValue(rule.rule.Name)] {
+			continue
+		}
+		from := make(map[string]string)
+		ruleFields(from, rule.rule.ScheduleExpression, rule.rule.EventPattern, rule.rule.Description)
+		for _, t := range rule.targets {
+			targetFields(from, t)
+		}
+		out = append(out, RuleDiff{
+			Name:    aws.StringValue(rule.rule.Name),
+			Kind:    rule.kind,
+			Delete:  true,
+			Changes: diffFields(from, map[string]string{}),
+		})
+	}
+	return out, nil
+}
+
+// serviceRule is a rule ecsy created for a service, with its targets
+type serviceRule struct {
+	kind    string
+	rule    *cloudwatchevents.Rule
+	targets []*cloudwatchevents.Target
+}
+
+// serviceRules lists the scheduled and post-deployment rules ecsy created
+// for a service, recognizing them by their description
+func (c *Client) serviceRules(cluster, service string) ([]serviceRule, error) {
+	kinds := map[string]string{
+		scheduledTaskDescription(cluster, service):  RuleKindSchedule,
+		postDeploymentDescription(cluster, service): RuleKindPostDeployment,
+	}
+	svc := c.CloudWatchEvents
+	input := &cloudwatchevents.ListRulesInput{
+		NamePrefix: aws.String(cluster + "-" + service + "-"),
+		Limit:      aws.Int64(100),
+	}
+	out := make([]serviceRule, 0)
+	for {
+		result, err := svc.ListRules(input)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list event rules: %v", err)
+		}
+		for _, rule := range result.Rules {
+			kind, ok := kinds[aws.StringValue(rule.Description)]
+			if !ok {
+				continue
+			}
+			targets, err := svc.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{Rule: rule.Name})
+			if err != nil {
+				return nil, fmt.Errorf("unable to list targets of rule %s: %v", aws.StringValue(rule.Name), err)
+			}
+			out = append(out, serviceRule{kind: kind, rule: rule, targets: targets.Targets})
+		}
+		if result.NextToken == nil {
+			sort.Slice(out, func(i, j int) bool {
+				return aws.StringValue(out[i].rule.Name) < aws.StringValue(out[j].rule.Name)
+			})
+			return out, nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
+
+// ApplyServiceSpec makes the changes of a diff: it registers a single new
+// revision when the task definition changes, updates the service once,
+// then puts the declared rules and deletes the undeclared ones. It returns
+// the task definition the service runs afterwards.
+func (c *Client) ApplyServiceSpec(diff *SpecDiff) (*ecs.TaskDefinition, error) {
+	spec := diff.Spec
+	task := diff.Current
+	var err error
+	if diff.RegistersTaskDefinition() {
+		task, err = c.CloneTaskDefinition(diff.Current, spec.applyTo)
+		if err != nil {
+			return nil, fmt.Errorf("unable to register task definition: %v", err)
+		}
+	}
+	input := &ecs.UpdateServiceInput{}
+	input.SetCluster(spec.Cluster)
+	input.SetService(spec.Service)
+	if aws.StringValue(task.TaskDefinitionArn) != aws.StringValue(diff.Service.TaskDefinition) {
+		input.SetTaskDefinition(*task.TaskDefinitionArn)
+	}
+	if len(diff.ServiceChanges) > 0 {
+		input.SetDesiredCount(*spec.DesiredCount)
+	}
+	if input.TaskDefinition != nil || input.DesiredCount != nil {
+		if _, err = c.ECS.UpdateService(input); err != nil {
+			return nil, fmt.Errorf("unable to update service: %v", err)
+		}
+	}
+	for _, rule := range diff.Rules {
+		if rule.Delete {
+			err = c.deleteRule(rule.Name)
+		} else {
+			err = c.putRule(rule, task)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+func (c *Client) putRule(diff RuleDiff, serviceTask *ecs.TaskDefinition) error {
+	svc := c.CloudWatchEvents
+	if _, err := svc.PutRule(diff.rule); err != nil {
+		return fmt.Errorf("unable to create or update event rule %s: %v", diff.Name, err)
+	}
+	task := diff.taskDefinition
+	if task == nil {
+		task = serviceTask
+	}
+	if err := c.createTaskTarget(diff.clusterArn, "", task, diff.Name, diff.command); err != nil {
+		return err
+	}
+	if len(diff.staleTargets) > 0 {
+		_, err := svc.RemoveTargets(&cloudwatchevents.RemoveTargetsInput{Rule: diff.rule.Name, Ids: diff.staleTargets})
+		if err != nil {
+			return fmt.Errorf("unable to remove targets of rule %s: %v", diff.Name, err)
+		}
+	}
+	return nil
+}
+
+// deleteRule removes a rule and its targets, which EventBridge requires
+// to be removed first
+func (c *Client) deleteRule(name string) error {
+	svc := c.CloudWatchEvents
+	targets, err := svc.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{Rule: aws.String(name)})
+	if err != nil {
+		return fmt.Errorf("unable to list targets of rule %s: %v", name, err)
+	}
+	if len(targets.Targets) > 0 {
+		ids := make([]*string, len(targets.Targets))
+		for i, t := range targets.Targets {
+			ids[i] = t.Id
+		}
+		_, err = svc.RemoveTargets(&cloudwatchevents.RemoveTargetsInput{Rule: aws.String(name), Ids: ids})
+		if err != nil {
+			return fmt.Errorf("unable to remove targets of rule %s: %v", name, err)
+		}
+	}
+	if _, err = svc.DeleteRule(&cloudwatchevents.DeleteRuleInput{Name: aws.String(name)}); err != nil {
+		return fmt.Errorf("unable to delete event rule %s: %v", name, err)
+	}
+	return nil
+}
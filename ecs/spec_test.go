@@ -0,0 +1,288 @@
+package ecs
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+)
+
+func TestParseServiceSpec(t *testing.T) {
+	yamlSpec := `
+cluster: qa
+service: api
+image: api:2
+memory: 512
+task_cpu: 256
+desired_count: 3
+env:
+  APP_ENV: qa
+  PORT: 8080
+schedules:
+  - name: report
+    schedule: rate(1 day)
+    command: bin/report
+`
+	jsonSpec := `{
+  "cluster": "qa", "service": "api", "image": "api:2", "memory": 512, "task_cpu": "256",
+  "desired_count": 3, "env": {"APP_ENV": "qa", "PORT": "8080"},
+  "schedules": [{"name": "report", "schedule": "rate(1 day)", "command": "bin/report"}]
+}`
+	fromYAML, err := ParseServiceSpec([]byte(yamlSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := ParseServiceSpec([]byte(jsonSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("expected YAML and JSON to parse alike, got %+v and %+v", fromYAML, fromJSON)
+	}
+	if fromYAML.Env["PORT"] != "8080" || fromYAML.TaskCpu != "256" || *fromYAML.Memory != 512 {
+		t.Errorf("unexpected spec %+v", fromYAML)
+	}
+	if fromYAML.Secrets != nil || fromYAML.PostDeployment != nil {
+		t.Errorf("expected settings left out to stay unmanaged, got %+v", fromYAML)
+	}
+
+	invalid := map[string]string{
+		"unknown field":     "cluster: qa\nservice: api\nimgae: api:2\n",
+		"missing service":   "cluster: qa\n",
+		"unnamed schedule":  "cluster: qa\nservice: api\nschedules:\n  - schedule: rate(1 day)\n",
+		"repeated schedule": "cluster: qa\nservice: api\nschedules:\n  - {name: a, schedule: rate(1 day)}\n  - {name: a, schedule: rate(2 days)}\n",
+	}
+	for name, spec := range invalid {
+		if _, err := ParseServiceSpec([]byte(spec)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// specTestClient is a qa/api service with a scheduled task, an old one the
+// spec no longer lists, and a rule of another service sharing the prefix
+func specTestClient() (*Client, *fakeECS, *fakeEvents) {
+	fake := newFakeECS()
+	def := testTaskDef("api", 1, "api:1")
+	def.TaskRoleArn = aws.String("arn:aws:iam::1:role/api")
+	def.ExecutionRoleArn = aws.String("arn:aws:iam::1:role/ecsTaskExecution")
+	fake.addService("qa", "api", def)
+	target := func() []*cloudwatchevents.Target {
+		return []*cloudwatchevents.Target{{
+			Id:            aws.String("1"),
+			Arn:           aws.String("arn:aws:ecs:us-west-2:1:cluster/qa"),
+			RoleArn:       def.TaskRoleArn,
+			EcsParameters: &cloudwatchevents.EcsParameters{TaskDefinitionArn: def.TaskDefinitionArn, TaskCount: aws.Int64(1)},
+		}}
+	}
+	events := &fakeEvents{
+		rules: map[string]*cloudwatchevents.DescribeRuleOutput{
+			"qa-api-report": {
+				Name:               aws.String("qa-api-report"),
+				ScheduleExpression: aws.String("rate(1 hour)"),
+				Description:        aws.String("Schedule Expression for api Service in qa ECS Cluster"),
+			},
+			"qa-api-cleanup": {
+				Name:               aws.String("qa-api-cleanup"),
+				ScheduleExpression: aws.String("rate(1 day)"),
+				Description:        aws.String("Schedule Expression for api Service in qa ECS Cluster"),
+			},
+			"qa-api-gateway-report": {
+				Name:               aws.String("qa-api-gateway-report"),
+				ScheduleExpression: aws.String("rate(1 day)"),
+				Description:        aws.String("Schedule Expression for api-gateway Service in qa ECS Cluster"),
+			},
+		},
+		targets: map[string][]*cloudwatchevents.Target{
+			"qa-api-report":         target(),
+			"qa-api-cleanup":        target(),
+			"qa-api-gateway-report": target(),
+		},
+	}
+	return &Client{ECS: fake, CloudWatchEvents: events}, fake, events
+}
+
+func TestApplyServiceSpec(t *testing.T) {
+	client, fake, events := specTestClient()
+	spec, err := ParseServiceSpec([]byte(`
+cluster: qa
+service: api
+image: api:2
+desired_count: 3
+env:
+  APP_ENV: production
+secrets:
+  DATABASE_URL: /qa/api/DATABASE_URL
+schedules:
+  - name: report
+    schedule: rate(1 day)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := client.DiffServiceSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.RegistersTaskDefinition() {
+		t.Fatalf("expected a new revision")
+	}
+	want := []FieldChange{
+		{Field: "env.APP_ENV", From: "qa", To: "production"},
+		{Field: "image", From: "api:1", To: "api:2"},
+		{Field: "secret.DATABASE_URL", To: "/qa/api/DATABASE_URL", Added: true},
+	}
+	if got := diff.TaskDefinition.Containers[0].Changes; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if want := []FieldChange{{Field: "desiredCount", From: "1", To: "3"}}; !reflect.DeepEqual(diff.ServiceChanges, want) {
+		t.Errorf("expected %v, got %v", want, diff.ServiceChanges)
+	}
+	rules := make(map[string]RuleDiff)
+	for _, rule := range diff.Rules {
+		rules[rule.Name] = rule
+	}
+	if len(rules) != 2 || !rules["qa-api-cleanup"].Delete || rules["qa-api-report"].Create {
+		t.Fatalf("expected to update qa-api-report and delete qa-api-cleanup, got %+v", diff.Rules)
+	}
+	wantRule := []FieldChange{
+		{Field: "scheduleExpression", From: "rate(1 hour)", To: "rate(1 day)"},
+		{Field: "target.1.taskDefinition", From: "api:1", To: "api:(new revision)"},
+	}
+	if got := rules["qa-api-report"].Changes; !reflect.DeepEqual(got, wantRule) {
+		t.Errorf("expected %v, got %v", wantRule, got)
+	}
+
+	task, err := client.ApplyServiceSpec(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.registered) != 1 || len(fake.updates) != 1 {
+		t.Fatalf("expected one registration and one update, got %d and %d", len(fake.registered), len(fake.updates))
+	}
+	update := fake.updates[0]
+	if *update.TaskDefinition != *task.TaskDefinitionArn || *update.DesiredCount != 3 {
+		t.Errorf("expected a single update deploying %s with 3 tasks, got %v", *task.TaskDefinitionArn, update)
+	}
+	if *fake.registered[0].TaskRoleArn != "arn:aws:iam::1:role/api" {
+		t.Errorf("expected the rest of the task definition to be kept, got %v", fake.registered[0])
+	}
+	if target := events.targets["qa-api-report"][0]; *target.EcsParameters.TaskDefinitionArn != *task.TaskDefinitionArn {
+		t.Errorf("expected the schedule to run the new revision, got %s", *target.EcsParameters.TaskDefinitionArn)
+	}
+	if _, ok := events.rules["qa-api-cleanup"]; ok {
+		t.Errorf("expected the undeclared schedule to be deleted")
+	}
+	if _, ok := events.rules["qa-api-gateway-report"]; !ok {
+		t.Errorf("expected another service's schedule to be left alone")
+	}
+
+	again, err := client.DiffServiceSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.IsEmpty() {
+		t.Errorf("expected nothing left to apply, got %+v", again)
+	}
+}
+
+func TestApplyServiceSpecLeavesUnmanagedSettings(t *testing.T) {
+	client, fake, events := specTestClient()
+	spec := &ServiceSpec{Cluster: "qa", Service: "api", DesiredCount: aws.Int64(2)}
+	diff, err := client.DiffServiceSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.RegistersTaskDefinition() || len(diff.Rules) != 0 {
+		t.Fatalf("expected only the desired count to change, got %+v", diff)
+	}
+	if _, err = client.ApplyServiceSpec(diff); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.registered) != 0 || len(fake.updates) != 1 || fake.updates[0].TaskDefinition != nil {
+		t.Errorf("expected a scale only update, got %d registrations and %v", len(fake.registered), fake.updates)
+	}
+	if events.puts != 0 || len(events.rules) != 3 {
+		t.Errorf("expected rules to be left alone, got %d writes", events.puts)
+	}
+}
+
+func TestApplyServiceSpecSecretsNeedExecutionRole(t *testing.T) {
+	client, fake, _ := specTestClient()
+	fake.taskDefs["arn:aws:ecs:us-west-2:1:task-definition/api:1"].ExecutionRoleArn = nil
+	spec := &ServiceSpec{Cluster: "qa", Service: "api", Secrets: map[string]string{"KEY": "/qa/api/KEY"}}
+	if _, err := client.DiffServiceSpec(spec); err == nil || !strings.Contains(err.Error(), "execution role") {
+		t.Errorf("expected an execution role error, got %v", err)
+	}
+}
+
+func TestApplyServiceSpecDryRun(t *testing.T) {
+	client, fake, events := specTestClient()
+	client.Region = "us-west-2"
+	fake.taskDefs["api"] = fake.taskDefs["arn:aws:ecs:us-west-2:1:task-definition/api:1"]
+	client.EnableDryRun(nil)
+	spec := &ServiceSpec{Cluster: "qa", Service: "api", Image: "api:2", Schedules: []ScheduleSpec{}}
+	diff, err := client.DiffServiceSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.ApplyServiceSpec(diff); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.registered) != 0 || len(fake.updates) != 0 || events.puts != 0 {
+		t.Fatalf("expected no writes, got %d registrations, %d updates and %d rule writes", len(fake.registered), len(fake.updates), events.puts)
+	}
+	operations := make([]string, 0)
+	for _, req := range client.Plan() {
+		operations = append(operations, req.Operation+" "+req.Target)
+	}
+	sort.Strings(operations)
+	want := []string{
+		"DeleteRule qa-api-cleanup",
+		"DeleteRule qa-api-report",
+		"RegisterTaskDefinition api",
+		"RemoveTargets qa-api-cleanup",
+		"RemoveTargets qa-api-report",
+		"UpdateService qa/api",
+	}
+	if !reflect.DeepEqual(operations, want) {
+		t.Errorf("expected %v, got %v", want, operations)
+	}
+}
+
+func TestSpecDiffDrift(t *testing.T) {
+	client, _, _ := specTestClient()
+	spec := &ServiceSpec{
+		Cluster:      "qa",
+		Service:      "api",
+		Image:        "api:2",
+		DesiredCount: aws.Int64(1),
+		Env:          map[string]string{"APP_ENV": "qa", "DEBUG": "1"},
+		Schedules: []ScheduleSpec{
+			{Name: "report", Schedule: "rate(2 hours)"},
+			{Name: "cleanup", Schedule: "rate(1 day)"},
+		},
+	}
+	diff, err := client.DiffServiceSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldChange{
+		{Field: "container.api.env.DEBUG", To: "1", Added: true},
+		{Field: "container.api.image", From: "api:1", To: "api:2"},
+		{Field: "rule.qa-api-report.scheduleExpression", From: "rate(1 hour)", To: "rate(2 hours)"},
+	}
+	if got := diff.Drift(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	spec = &ServiceSpec{Cluster: "qa", Service: "api", Image: "api:1", DesiredCount: aws.Int64(1)}
+	if diff, err = client.DiffServiceSpec(spec); err != nil {
+		t.Fatal(err)
+	}
+	if got := diff.Drift(); len(got) != 0 {
+		t.Errorf("expected no drift, got %v", got)
+	}
+}
@@ -0,0 +1,182 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// templateVar matches ${NAME} references, and $${NAME} escapes
+var templateVar = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+// RenderTaskDefinition fills a task definition file with variables, either
+// as a Go template ({{ .image }}) or by ${image} substitution, or both.
+// Referring to a variable that was not given is an error; $${NAME} is kept
+// as a literal ${NAME}, for shell commands that need one. Values are
+// inserted as they are, once: a value containing ${...} or {{ ... }} is not
+// expanded again.
+func RenderTaskDefinition(text string, vars map[string]string) (string, error) {
+	missing := make([]string, 0)
+	substituted := templateVar.ReplaceAllStringFunc(text, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		name := templateVar.FindStringSubmatch(ref)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		// a quoted constant, so the template prints the value untouched
+		return "{{" + strconv.Quote(value) + "}}"
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("no value given for %s", strings.Join(missing, ", "))
+	}
+	tmpl, err := template.New("task definition").Option("missingkey=error").Parse(substituted)
+	if err != nil {
+		return "", err
+	}
+	var rendered bytes.Buffer
+	if err = tmpl.Execute(&rendered, vars); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// readOnlyTaskDefinitionFields are the fields describe-task-definition
+// returns that a RegisterTaskDefinition request does not take
+var readOnlyTaskDefinitionFields = []string{
+	"compatibilities",
+	"deregisteredAt",
+	"registeredAt",
+	"registeredBy",
+	"requiresAttributes",
+	"revision",
+	"status",
+	"taskDefinitionArn",
+}
+
+// ParseTaskDefinitionJSON reads a task definition file: a
+// RegisterTaskDefinition request as the AWS CLI takes it, the output of
+// describe-task-definition, or an ecsy export. Read-only fields are ignored;
+// any other field a request does not have is an error, to catch typos.
+func ParseTaskDefinitionJSON(data []byte) (*ecs.RegisterTaskDefinitionInput, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"taskDefinition", "task_definition"} {
+		if wrapped, ok := fields[key]; ok {
+			input, err := ParseTaskDefinitionJSON(wrapped)
+			if err != nil {
+				return nil, err
+			}
+			if len(input.Tags) == 0 {
+				// tags sit next to the task definition, where a request has them
+				outer := &ecs.RegisterTaskDefinitionInput{}
+				if err = jsonutil.UnmarshalJSON(outer, bytes.NewReader(data)); err != nil {
+					return nil, err
+				}
+				input.Tags = outer.Tags
+			}
+			return input, nil
+		}
+	}
+	input := &ecs.RegisterTaskDefinitionInput{}
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	ignored := make(map[string]bool, len(readOnlyTaskDefinitionFields))
+	for _, key := range readOnlyTaskDefinitionFields {
+		ignored[key] = true
+	}
+	if err := checkShapeFields(reflect.TypeOf(input), document, "", ignored); err != nil {
+		return nil, err
+	}
+	if err := jsonutil.UnmarshalJSON(input, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// ValidateTaskDefinition checks a registration request for mistakes ECS
+// would only report later, if at all: no essential container, containers
+// without memory, and two containers claiming the same port
+func ValidateTaskDefinition(input *ecs.RegisterTaskDefinitionInput) error {
+	problems := make([]string, 0)
+	if aws.StringValue(input.Family) == "" {
+		problems = append(problems, "family is not set")
+	}
+	if len(input.ContainerDefinitions) == 0 {
+		problems = append(problems, "there are no container definitions")
+	}
+	networkMode := aws.StringValue(input.NetworkMode)
+	fargate := false
+	for _, compatibility := range input.RequiresCompatibilities {
+		fargate = fargate || aws.StringValue(compatibility) == ecs.CompatibilityFargate
+	}
+	if fargate && (aws.StringValue(input.Cpu) == "" || aws.StringValue(input.Memory) == "") {
+		problems = append(problems, "Fargate task definitions need task level cpu and memory")
+	}
+	essential := false
+	names := make(map[string]bool)
+	ports := make(map[string]string)
+	for i, container := range input.ContainerDefinitions {
+		name := aws.StringValue(container.Name)
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+			problems = append(problems, fmt.Sprintf("container %s has no name", name))
+		} else if names[name] {
+			problems = append(problems, fmt.Sprintf("container %s is defined twice", name))
+		}
+		names[name] = true
+		if aws.StringValue(container.Image) == "" {
+			problems = append(problems, fmt.Sprintf("container %s has no image", name))
+		}
+		// containers are essential unless marked otherwise
+		if container.Essential == nil || *container.Essential {
+			essential = true
+		}
+		if aws.StringValue(input.Memory) == "" && container.Memory == nil && container.MemoryReservation == nil {
+			problems = append(problems, fmt.Sprintf("container %s has no memory or memoryReservation, and the task has no memory", name))
+		}
+		for _, port := range container.PortMappings {
+			protocol := aws.StringValue(port.Protocol)
+			if protocol == "" {
+				protocol = ecs.TransportProtocolTcp
+			}
+			hostPort := aws.Int64Value(port.HostPort)
+			if networkMode == ecs.NetworkModeAwsvpc || networkMode == ecs.NetworkModeHost {
+				// the host port is the container port in these modes
+				hostPort = aws.Int64Value(port.ContainerPort)
+			}
+			if hostPort == 0 {
+				// a dynamic port is chosen on each host
+				continue
+			}
+			key := fmt.Sprintf("%d/%s", hostPort, protocol)
+			if other, ok := ports[key]; ok {
+				problems = append(problems, fmt.Sprintf("port %s is used by both %s and %s", key, other, name))
+				continue
+			}
+			ports[key] = name
+		}
+	}
+	if len(input.ContainerDefinitions) > 0 && !essential {
+		problems = append(problems, "no container is essential")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid task definition:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
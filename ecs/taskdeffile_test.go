@@ -0,0 +1,188 @@
+package ecs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestRenderTaskDefinition(t *testing.T) {
+	// values are inserted once, never expanded again
+	vars := map[string]string{"image": "api:2", "env": "prod", "cmd": "echo {{ .image }} ${env} $${env}"}
+	tests := []struct {
+		text string
+		want string
+		err  string
+	}{
+		{`{"image": "{{ .image }}"}`, `{"image": "api:2"}`, ""},
+		{`{"image": "${image}", "env": "${env}"}`, `{"image": "api:2", "env": "prod"}`, ""},
+		{`{"family": "api-{{ .env }}", "image": "${image}"}`, `{"family": "api-prod", "image": "api:2"}`, ""},
+		{`{"command": ["sh", "-c", "echo $${HOME} $PATH"]}`, `{"command": ["sh", "-c", "echo ${HOME} $PATH"]}`, ""},
+		{`{"image": "${tag}"}`, "", "no value given for tag"},
+		{`{"image": "{{ .tag }}"}`, "", "tag"},
+		{`{"command": "${cmd}"}`, `{"command": "echo {{ .image }} ${env} $${env}"}`, ""},
+		{`{"command": "{{ .cmd }}"}`, `{"command": "echo {{ .image }} ${env} $${env}"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := RenderTaskDefinition(tt.text, vars)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected an error about %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseTaskDefinitionJSON(t *testing.T) {
+	request := `{
+  "family": "api",
+  "memory": "512",
+  "containerDefinitions": [
+    {"name": "api", "image": "api:2", "essential": true, "portMappings": [{"containerPort": 80}]}
+  ],
+  "tags": [{"key": "team", "value": "core"}]
+}`
+	described := `{
+  "taskDefinition": {
+    "taskDefinitionArn": "arn:aws:ecs:us-west-2:1:task-definition/api:7",
+    "revision": 7,
+    "status": "ACTIVE",
+    "requiresAttributes": [{"name": "com.amazonaws.ecs.capability.docker-remote-api.1.18"}],
+    "registeredAt": "2024-03-01T12:00:00Z",
+    "family": "api",
+    "memory": "512",
+    "containerDefinitions": [
+      {"name": "api", "image": "api:2", "essential": true, "portMappings": [{"containerPort": 80}]}
+    ]
+  },
+  "tags": [{"key": "team", "value": "core"}]
+}`
+	for name, text := range map[string]string{"request": request, "described": described} {
+		t.Run(name, func(t *testing.T) {
+			input, err := ParseTaskDefinitionJSON([]byte(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *input.Family != "api" || *input.Memory != "512" || len(input.ContainerDefinitions) != 1 {
+				t.Fatalf("unexpected task definition %v", input)
+			}
+			if *input.ContainerDefinitions[0].PortMappings[0].ContainerPort != 80 {
+				t.Errorf("expected the port mapping, got %v", input.ContainerDefinitions[0])
+			}
+			if len(input.Tags) != 1 || *input.Tags[0].Key != "team" {
+				t.Errorf("expected the tags, got %v", input.Tags)
+			}
+			if err = input.Validate(); err != nil {
+				t.Errorf("expected a valid request, got %v", err)
+			}
+		})
+	}
+	if _, err := ParseTaskDefinitionJSON([]byte(`{"family": `)); err == nil {
+		t.Errorf("expected invalid JSON to be an error")
+	}
+}
+
+func TestParseTaskDefinitionJSONRejectsUnknownFields(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{`{"family": "api", "memroy": "512"}`, "unknown field memroy"},
+		{`{"family": "api", "containerDefinitions": [{"name": "api", "portMapings": []}]}`, "unknown field containerDefinitions[0].portMapings"},
+		{`{"taskDefinition": {"family": "api", "cpuu": "256"}}`, "unknown field cpuu"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := ParseTaskDefinitionJSON([]byte(tt.text))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected an error about %q, got %v", tt.err, err)
+			}
+		})
+	}
+	input, err := ParseTaskDefinitionJSON([]byte(`{"family": "api", "revision": 3, "registeredAt": 1709294400.5, "compatibilities": ["EC2"]}`))
+	if err != nil {
+		t.Fatalf("expected read-only fields to be ignored, got %v", err)
+	}
+	if *input.Family != "api" {
+		t.Errorf("unexpected task definition %v", input)
+	}
+}
+
+func TestValidateTaskDefinition(t *testing.T) {
+	container := func(name string, hostPort int64) *ecs.ContainerDefinition {
+		return &ecs.ContainerDefinition{
+			Name:         aws.String(name),
+			Image:        aws.String(name + ":1"),
+			Memory:       aws.Int64(128),
+			PortMappings: []*ecs.PortMapping{{ContainerPort: aws.Int64(80), HostPort: aws.Int64(hostPort)}},
+		}
+	}
+	tests := []struct {
+		name  string
+		input *ecs.RegisterTaskDefinitionInput
+		err   string
+	}{
+		{"valid", &ecs.RegisterTaskDefinitionInput{
+			Family:               aws.String("api"),
+			ContainerDefinitions: []*ecs.ContainerDefinition{container("api", 0), container("sidecar", 0)},
+		}, ""},
+		{"no essential container", &ecs.RegisterTaskDefinitionInput{
+			Family: aws.String("api"),
+			ContainerDefinitions: []*ecs.ContainerDefinition{
+				func() *ecs.ContainerDefinition { c := container("api", 0); c.Essential = aws.Bool(false); return c }(),
+			},
+		}, "no container is essential"},
+		{"no memory", &ecs.RegisterTaskDefinitionInput{
+			Family: aws.String("api"),
+			ContainerDefinitions: []*ecs.ContainerDefinition{
+				func() *ecs.ContainerDefinition { c := container("api", 0); c.Memory = nil; return c }(),
+			},
+		}, "container api has no memory"},
+		{"task memory", &ecs.RegisterTaskDefinitionInput{
+			Family: aws.String("api"),
+			Memory: aws.String("512"),
+			ContainerDefinitions: []*ecs.ContainerDefinition{
+				func() *ecs.ContainerDefinition { c := container("api", 0); c.Memory = nil; return c }(),
+			},
+		}, ""},
+		{"host port conflict", &ecs.RegisterTaskDefinitionInput{
+			Family:               aws.String("api"),
+			ContainerDefinitions: []*ecs.ContainerDefinition{container("api", 8080), container("sidecar", 8080)},
+		}, "port 8080/tcp is used by both api and sidecar"},
+		{"awsvpc port conflict", &ecs.RegisterTaskDefinitionInput{
+			Family:               aws.String("api"),
+			NetworkMode:          aws.String(ecs.NetworkModeAwsvpc),
+			ContainerDefinitions: []*ecs.ContainerDefinition{container("api", 0), container("sidecar", 0)},
+		}, "port 80/tcp is used by both api and sidecar"},
+		{"fargate sizing", &ecs.RegisterTaskDefinitionInput{
+			Family:                  aws.String("api"),
+			RequiresCompatibilities: []*string{aws.String(ecs.CompatibilityFargate)},
+			ContainerDefinitions:    []*ecs.ContainerDefinition{container("api", 0)},
+		}, "Fargate task definitions need task level cpu and memory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTaskDefinition(tt.input)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected an error about %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
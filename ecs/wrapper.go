@@ -1,22 +1,19 @@
 package ecs
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 	"path"
-	"sort"
+	"regexp"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -30,75 +27,28 @@ func newServiceKey(cluster, service string) serviceKey {
 	return serviceKey(fmt.Sprintf("%s_%s", cluster, service))
 }
 
-var _ecs *ecs.ECS
-var _ec2 *ec2.EC2
-var _iam *iam.IAM
-var _cloudWatch *cloudwatchlogs.CloudWatchLogs
-var _cloudWatchEvents *cloudwatchevents.CloudWatchEvents
-var _clusterArns map[string]string
-
-func getServiceConfiguration() *aws.Config {
-	region := os.Getenv("AWS_REGION")
-	if region == "" {
-		region = "us-west-2"
-	}
-	return &aws.Config{Region: aws.String(region)}
-}
-
-func assertECS() *ecs.ECS {
-	if _ecs == nil {
-		_ecs = ecs.New(session.New(getServiceConfiguration()))
-	}
-	return _ecs
-}
-
-func assertEC2() *ec2.EC2 {
-	if _ec2 == nil {
-		_ec2 = ec2.New(session.New(getServiceConfiguration()))
-	}
-	return _ec2
-}
-
-func assertCloudWatch(region string) *cloudwatchlogs.CloudWatchLogs {
-	if _cloudWatch == nil {
-		_cloudWatch = cloudwatchlogs.New(session.New(getServiceConfiguration()))
-	}
-	return _cloudWatch
-}
-
-func assertCloudWatchEvents() *cloudwatchevents.CloudWatchEvents {
-	if _cloudWatchEvents == nil {
-		_cloudWatchEvents = cloudwatchevents.New(session.New(getServiceConfiguration()))
-	}
-	return _cloudWatchEvents
-}
-func assertIAM() *iam.IAM {
-	if _iam == nil {
-		_iam = iam.New(session.New(getServiceConfiguration()))
-	}
-	return _iam
-}
-
-func assertClusterMap() (map[string]string, error) {
-	if _clusterArns == nil {
-		svc := assertECS()
+func (c *Client) clusterMap() (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clusterArns == nil {
+		svc := c.ECS
 		clusters, err := svc.ListClusters(&ecs.ListClustersInput{})
 		if err != nil {
 			return nil, err
 		}
-		_clusterArns = make(map[string]string, len(clusters.ClusterArns))
+		c.clusterArns = make(map[string]string, len(clusters.ClusterArns))
 		for _, s := range clusters.ClusterArns {
 			parts := strings.Split(*s, "/")
 			simpleName := parts[len(parts)-1]
-			_clusterArns[simpleName] = *s
+			c.clusterArns[simpleName] = *s
 		}
 	}
-	return _clusterArns, nil
+	return c.clusterArns, nil
 }
 
 // GetClusterNames returns a slice of strings representing cluster names
-func GetClusterNames() ([]string, error) {
-	clusterMap, err := assertClusterMap()
+func (c *Client) GetClusterNames() ([]string, error) {
+	clusterMap, err := c.clusterMap()
 	if err != nil {
 		return nil, err
 	}
@@ -112,8 +62,8 @@ func GetClusterNames() ([]string, error) {
 }
 
 // ValidateCluster returns an error if the cluster is not found
-func ValidateCluster(cluster string) error {
-	names, err := GetClusterNames()
+func (c *Client) ValidateCluster(cluster string) error {
+	names, err := c.GetClusterNames()
 	if err != nil {
 		return err
 	}
@@ -127,12 +77,12 @@ func ValidateCluster(cluster string) error {
 
 // GetDeployedEssentialContainer gets the currently deployed "essential" container
 // definition of a cluster service
-func GetDeployedEssentialContainer(cluster, service string) (*ecs.ContainerDefinition, error) {
-	err := ValidateCluster(cluster)
+func (c *Client) GetDeployedEssentialContainer(cluster, service string) (*ecs.ContainerDefinition, error) {
+	err := c.ValidateCluster(cluster)
 	if err != nil {
 		return nil, err
 	}
-	task, err := GetCurrentTaskDefinition(cluster, service)
+	task, err := c.GetCurrentTaskDefinition(cluster, service)
 	if err != nil {
 		return nil, err
 	}
@@ -143,53 +93,43 @@ func GetDeployedEssentialContainer(cluster, service string) (*ecs.ContainerDefin
 	return container, nil
 }
 
-func FindServicesWithEnvVar(envVar, envVarValue string) ([]ecs.Service, error) {
-	clusters, err := ListClusters()
+// FindServicesWithEnvVar finds the services whose essential container's
+// environment has a variable named envVar, set to envVarValue if not empty.
+//
+// Deprecated: use SearchEnv, which also matches secrets and images and
+// reports the services it could not search.
+func (c *Client) FindServicesWithEnvVar(envVar, envVarValue string) ([]ecs.Service, error) {
+	search := EnvSearch{Name: "^" + regexp.QuoteMeta(envVar) + "$", Regexp: true}
+	if envVarValue != "" {
+		search.Value = "^" + regexp.QuoteMeta(envVarValue) + "$"
+	}
+	result, err := c.SearchEnv(search)
 	if err != nil {
 		return nil, err
 	}
+	if len(result.Errors) > 0 {
+		return nil, result.Errors[0]
+	}
 	found := make([]ecs.Service, 0)
-	for _, cluster := range clusters {
-		svc := assertECS()
-		services := make([]*ecs.Service, 0)
-		err := svc.ListServicesPages(&ecs.ListServicesInput{
-			Cluster: aws.String(cluster),
-		}, func(page *ecs.ListServicesOutput, lastPage bool) bool {
-			service, err := svc.DescribeServices(&ecs.DescribeServicesInput{
-				Cluster:  aws.String(cluster),
-				Services: page.ServiceArns,
-			})
-			if err != nil {
-				log.Fatalln(err)
-			}
-			services = append(services, service.Services...)
-			return !lastPage
-		})
+	seen := make(map[string]bool)
+	for _, match := range result.Matches {
+		key := match.Cluster + "/" + match.Service
+		if match.Field != EnvMatchEnv || seen[key] {
+			continue
+		}
+		seen[key] = true
+		service, err := c.FindService(match.Cluster, match.Service)
 		if err != nil {
 			return nil, err
 		}
-		for _, service := range services {
-			container, err := GetDeployedEssentialContainer(cluster, *service.ServiceName)
-			if err != nil {
-				return nil, err
-			}
-			for _, env := range container.Environment {
-				if envVar != *env.Name {
-					continue
-				}
-				if envVarValue != "" && envVarValue != *env.Value {
-					continue
-				}
-				found = append(found, *service)
-			}
-		}
+		found = append(found, *service)
 	}
 	return found, nil
 }
 
 // GetCurrentTaskDefinition returns a service's current task definition
-func GetCurrentTaskDefinition(cluster, service string) (*ecs.TaskDefinition, error) {
-	svc := assertECS()
+func (c *Client) GetCurrentTaskDefinition(cluster, service string) (*ecs.TaskDefinition, error) {
+	svc := c.ECS
 	input := &ecs.DescribeServicesInput{}
 	input.SetCluster(cluster)
 	input.SetServices([]*string{aws.String(service)})
@@ -210,12 +150,12 @@ func GetCurrentTaskDefinition(cluster, service string) (*ecs.TaskDefinition, err
 	return nil, fmt.Errorf("error finding service with name %s in cluster %s, %d results found", service, cluster, len(result.Services))
 }
 
-func GetNewestTaskDefinition(cluster, service string) (*ecs.TaskDefinition, error) {
-	def, err := GetCurrentTaskDefinition(cluster, service)
+func (c *Client) GetNewestTaskDefinition(cluster, service string) (*ecs.TaskDefinition, error) {
+	def, err := c.GetCurrentTaskDefinition(cluster, service)
 	if err != nil {
 		return nil, err
 	}
-	svc := assertECS()
+	svc := c.ECS
 	input := &ecs.ListTaskDefinitionsInput{
 		FamilyPrefix: def.Family,
 		Sort:         aws.String("DESC"),
@@ -228,12 +168,12 @@ func GetNewestTaskDefinition(cluster, service string) (*ecs.TaskDefinition, erro
 	if len(results.TaskDefinitionArns) == 0 {
 		return nil, fmt.Errorf("task definitions not found for family %s", *def.Family)
 	}
-	return GetTaskDefinition(*results.TaskDefinitionArns[0])
+	return c.GetTaskDefinition(*results.TaskDefinitionArns[0])
 }
 
 // GetTaskDefinition returns a task definition by arn
-func GetTaskDefinition(arn string) (*ecs.TaskDefinition, error) {
-	svc := assertECS()
+func (c *Client) GetTaskDefinition(arn string) (*ecs.TaskDefinition, error) {
+	svc := c.ECS
 	output, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
 		TaskDefinition: aws.String(arn),
 	})
@@ -255,8 +195,8 @@ func GetEssentialContainer(task *ecs.TaskDefinition) (*ecs.ContainerDefinition,
 
 // GetClusterInstances returns the container instances of a cluster
 // by id
-func GetClusterInstances(cluster string) ([]string, error) {
-	svc := assertECS()
+func (c *Client) GetClusterInstances(cluster string) ([]string, error) {
+	svc := c.ECS
 	input := &ecs.ListContainerInstancesInput{
 		Cluster: &cluster,
 	}
@@ -277,8 +217,8 @@ func GetClusterInstances(cluster string) ([]string, error) {
 }
 
 // UpdateAgent will update the agents for all instances in a cluster
-func UpdateAgent(cluster, containerInstanceARN string) error {
-	svc := assertECS()
+func (c *Client) UpdateAgent(cluster, containerInstanceARN string) error {
+	svc := c.ECS
 	input := &ecs.UpdateContainerAgentInput{
 		Cluster:           &cluster,
 		ContainerInstance: &containerInstanceARN,
@@ -291,8 +231,8 @@ func UpdateAgent(cluster, containerInstanceARN string) error {
 }
 
 // GetContainerInstances returns the container instances of a cluster
-func GetContainerInstances(cluster string, service string) ([]string, error) {
-	svc := assertECS()
+func (c *Client) GetContainerInstances(cluster string, service string) ([]string, error) {
+	svc := c.ECS
 	input := &ecs.ListTasksInput{}
 	input.SetCluster(cluster)
 	if service != "" {
@@ -320,7 +260,7 @@ func GetContainerInstances(cluster string, service string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	ec2svc := assertEC2()
+	ec2svc := c.EC2
 	iinput := &ec2.DescribeInstancesInput{}
 	ec2instances := make([]*string, len(result3.ContainerInstances))
 	for i, ci := range result3.ContainerInstances {
@@ -340,16 +280,16 @@ func GetContainerInstances(cluster string, service string) ([]string, error) {
 
 // CreateNewTaskWithEnvironment registers a new task, based on the passed task,
 // but with new environment.
-func CreateNewTaskWithEnvironment(existingTask *ecs.TaskDefinition, env []*ecs.KeyValuePair) (*ecs.TaskDefinition, error) {
-	return updateEssential(existingTask, func(container *ecs.ContainerDefinition) {
+func (c *Client) CreateNewTaskWithEnvironment(existingTask *ecs.TaskDefinition, env []*ecs.KeyValuePair) (*ecs.TaskDefinition, error) {
+	return c.updateEssential(existingTask, func(container *ecs.ContainerDefinition) {
 		container.SetEnvironment(env)
 	})
 }
 
 // CreateNewTaskWithMemory registers a new task definition, overwriting memory
 // params
-func CreateNewTaskWithMemory(existingTask *ecs.TaskDefinition, memory *int64, memoryReservation *int64) (*ecs.TaskDefinition, error) {
-	return updateEssential(existingTask, func(container *ecs.ContainerDefinition) {
+func (c *Client) CreateNewTaskWithMemory(existingTask *ecs.TaskDefinition, memory *int64, memoryReservation *int64) (*ecs.TaskDefinition, error) {
+	return c.updateEssential(existingTask, func(container *ecs.ContainerDefinition) {
 		if memory != nil {
 			container.SetMemory(*memory)
 		}
@@ -361,11 +301,11 @@ func CreateNewTaskWithMemory(existingTask *ecs.TaskDefinition, memory *int64, me
 
 // CreateNewTaskWithImage registers a new task, based on the passed task,
 // but with new image.
-func CreateNewTaskWithImage(existingTask *ecs.TaskDefinition, imageURL string) (*ecs.TaskDefinition, error) {
+func (c *Client) CreateNewTaskWithImage(existingTask *ecs.TaskDefinition, imageURL string) (*ecs.TaskDefinition, error) {
 	if imageURL == "" {
 		return nil, fmt.Errorf("invalid imageUrl: empty")
 	}
-	return updateEssential(existingTask, func(container *ecs.ContainerDefinition) {
+	return c.updateEssential(existingTask, func(container *ecs.ContainerDefinition) {
 		container.SetImage(imageURL)
 	})
 }
@@ -389,63 +329,52 @@ func WithName(name string) func(definition *ecs.ContainerDefinition) {
 	}
 }
 
-// CopyTaskDefinitionWithCommand registers a new task, based on the passed task,
-// but with new command.
-func CopyTaskDefinition(existingTask *ecs.TaskDefinition, toFamilyName string, modifiers ...func(*ecs.ContainerDefinition)) (*ecs.TaskDefinition, error) {
-	svc := assertECS()
-	newTask := &ecs.TaskDefinition{
-		ContainerDefinitions: existingTask.ContainerDefinitions,
-		Family:               aws.String(toFamilyName),
-	}
-	essential := findEssential(existingTask)
-	if essential == nil {
-		return nil, errors.New("essential container not found")
-	}
-	WithName(toFamilyName)(essential)
-	for _, modifier := range modifiers {
-		modifier(essential)
-	}
-	output, err := svc.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
-		Family:               newTask.Family,
-		ContainerDefinitions: newTask.ContainerDefinitions,
+// CopyTaskDefinition registers a new task, based on the passed task,
+// but in a new family, with the essential container renamed to the family
+// and optionally modified (for example WithCommand).
+func (c *Client) CopyTaskDefinition(existingTask *ecs.TaskDefinition, toFamilyName string, modifiers ...func(*ecs.ContainerDefinition)) (*ecs.TaskDefinition, error) {
+	newTask, err := c.CloneTaskDefinition(existingTask, func(input *ecs.RegisterTaskDefinitionInput) error {
+		essential := findEssentialDefinition(input.ContainerDefinitions)
+		if essential == nil {
+			return errors.New("essential container not found")
+		}
+		input.SetFamily(toFamilyName)
+		WithName(toFamilyName)(essential)
+		for _, modifier := range modifiers {
+			modifier(essential)
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "task registration")
 	}
-	return output.TaskDefinition, nil
+	return newTask, nil
 }
 
-func updateEssential(existingTask *ecs.TaskDefinition, configure func(definition *ecs.ContainerDefinition)) (*ecs.TaskDefinition, error) {
-	essential := findEssential(existingTask)
-	if essential == nil {
-		return nil, fmt.Errorf("error finding essential container, does the task %s have a container marked as essential", existingTask.GoString())
-	}
-	configure(essential)
-	return saveTaskDef(existingTask)
+func (c *Client) updateEssential(existingTask *ecs.TaskDefinition, configure func(definition *ecs.ContainerDefinition)) (*ecs.TaskDefinition, error) {
+	return c.CloneTaskDefinition(existingTask, func(input *ecs.RegisterTaskDefinitionInput) error {
+		essential := findEssentialDefinition(input.ContainerDefinitions)
+		if essential == nil {
+			return fmt.Errorf("error finding essential container, does the task %s have a container marked as essential", existingTask.GoString())
+		}
+		configure(essential)
+		return nil
+	})
 }
 
 func findEssential(task *ecs.TaskDefinition) *ecs.ContainerDefinition {
-	for _, def := range task.ContainerDefinitions {
-		if *def.Essential {
+	return findEssentialDefinition(task.ContainerDefinitions)
+}
+
+func findEssentialDefinition(containers []*ecs.ContainerDefinition) *ecs.ContainerDefinition {
+	for _, def := range containers {
+		if aws.BoolValue(def.Essential) {
 			return def
 		}
 	}
 	return nil
 }
 
-func saveTaskDef(existingTask *ecs.TaskDefinition) (*ecs.TaskDefinition, error) {
-	svc := assertECS()
-	input := &ecs.RegisterTaskDefinitionInput{}
-	input.SetContainerDefinitions(existingTask.ContainerDefinitions)
-	input.SetFamily(*existingTask.Family)
-	input.SetVolumes(existingTask.Volumes)
-	newTaskDef, err := svc.RegisterTaskDefinition(input)
-	if err != nil {
-		return nil, err
-	}
-	return newTaskDef.TaskDefinition, nil
-}
-
 // EssentialImage returns the essential image of a task def
 func EssentialImage(task *ecs.TaskDefinition) string {
 	essential := findEssential(task)
@@ -456,8 +385,8 @@ func EssentialImage(task *ecs.TaskDefinition) string {
 }
 
 // FindService finds a service struct by name
-func FindService(cluster, service string) (*ecs.Service, error) {
-	svc := assertECS()
+func (c *Client) FindService(cluster, service string) (*ecs.Service, error) {
+	svc := c.ECS
 	result, err := svc.DescribeServices(&ecs.DescribeServicesInput{
 		Cluster:  aws.String(cluster),
 		Services: []*string{aws.String(service)},
@@ -471,8 +400,8 @@ func FindService(cluster, service string) (*ecs.Service, error) {
 	return nil, fmt.Errorf("did not find one (%d) services matching name %s in %s cluster, unable to continue", len(result.Services), service, cluster)
 }
 
-func ListClusters() ([]string, error) {
-	svc := assertECS()
+func (c *Client) ListClusters() ([]string, error) {
+	svc := c.ECS
 	params := &ecs.ListClustersInput{}
 	result := make([]*string, 0)
 	err := svc.ListClustersPages(params, func(clusters *ecs.ListClustersOutput, lastPage bool) bool {
@@ -490,8 +419,8 @@ func ListClusters() ([]string, error) {
 }
 
 // ListServices lists services for a cluster by plain name
-func ListServices(cluster string) ([]string, error) {
-	svc := assertECS()
+func (c *Client) ListServices(cluster string) ([]string, error) {
+	svc := c.ECS
 	params := &ecs.ListServicesInput{Cluster: aws.String(cluster)}
 	result := make([]*string, 0)
 	err := svc.ListServicesPages(params, func(services *ecs.ListServicesOutput, lastPage bool) bool {
@@ -510,8 +439,8 @@ func ListServices(cluster string) ([]string, error) {
 
 // FindNewestDefinition finds the most recent task definition of the service's
 // task family
-func FindNewestDefinition(familyPrefix string) (*ecs.TaskDefinition, error) {
-	svc := assertECS()
+func (c *Client) FindNewestDefinition(familyPrefix string) (*ecs.TaskDefinition, error) {
+	svc := c.ECS
 	result, err := svc.ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
 		FamilyPrefix: aws.String(familyPrefix),
 		Sort:         aws.String("DESC"),
@@ -530,13 +459,75 @@ func FindNewestDefinition(familyPrefix string) (*ecs.TaskDefinition, error) {
 	return taskResult.TaskDefinition, nil
 }
 
+// ListFamilyRevisions returns up to max task definitions of a family,
+// newest first. Families sharing the prefix are skipped.
+func (c *Client) ListFamilyRevisions(family string, max int) ([]*ecs.TaskDefinition, error) {
+	if max <= 0 {
+		return nil, fmt.Errorf("the number of revisions to list must be positive, got %d", max)
+	}
+	svc := c.ECS
+	arns := make([]*string, 0)
+	err := svc.ListTaskDefinitionsPages(&ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Sort:         aws.String("DESC"),
+	}, func(page *ecs.ListTaskDefinitionsOutput, lastPage bool) bool {
+		for _, arn := range page.TaskDefinitionArns {
+			if taskDefinitionFamily(*arn) == family {
+				arns = append(arns, arn)
+			}
+		}
+		return len(arns) < max && !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(arns) > max {
+		arns = arns[:max]
+	}
+	out := make([]*ecs.TaskDefinition, len(arns))
+	for i, arn := range arns {
+		def, err := c.GetTaskDefinition(*arn)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = def
+	}
+	return out, nil
+}
+
+// ResolveTaskDefinition finds a task definition related to a service by
+// reference: "current", "newest", a revision number of the service's
+// family, or a full family:revision or arn
+func (c *Client) ResolveTaskDefinition(cluster, service, ref string) (*ecs.TaskDefinition, error) {
+	if ref == "current" || ref == "newest" {
+		return c.LocateTaskDef(cluster, service, ref)
+	}
+	if _, err := strconv.Atoi(ref); err == nil {
+		current, err := c.GetCurrentTaskDefinition(cluster, service)
+		if err != nil {
+			return nil, err
+		}
+		return c.GetTaskDefinition(fmt.Sprintf("%s:%s", *current.Family, ref))
+	}
+	return c.GetTaskDefinition(ref)
+}
+
+// taskDefinitionFamily returns the family of a task definition arn
+func taskDefinitionFamily(arn string) string {
+	name := path.Base(arn)
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
 // DeployTaskToService deploys a given task definition to a cluster/service
-func DeployTaskToService(cluster, service string, task *ecs.TaskDefinition) (*ecs.Service, error) {
+func (c *Client) DeployTaskToService(cluster, service string, task *ecs.TaskDefinition) (*ecs.Service, error) {
 	input := &ecs.UpdateServiceInput{}
 	input.SetCluster(cluster)
 	input.SetService(service)
 	input.SetTaskDefinition(*task.TaskDefinitionArn)
-	svc := assertECS()
+	svc := c.ECS
 	output, err := svc.UpdateService(input)
 	if err != nil {
 		return nil, err
@@ -545,12 +536,12 @@ func DeployTaskToService(cluster, service string, task *ecs.TaskDefinition) (*ec
 }
 
 // ScaleService sets the desired count of a service
-func ScaleService(cluster, service string, desiredCount int) (*ecs.Service, error) {
+func (c *Client) ScaleService(cluster, service string, desiredCount int) (*ecs.Service, error) {
 	input := &ecs.UpdateServiceInput{}
 	input.SetCluster(cluster)
 	input.SetService(service)
 	input.SetDesiredCount(int64(desiredCount))
-	svc := assertECS()
+	svc := c.ECS
 	output, err := svc.UpdateService(input)
 	if err != nil {
 		return nil, err
@@ -559,30 +550,42 @@ func ScaleService(cluster, service string, desiredCount int) (*ecs.Service, erro
 }
 
 // KeyPairsToString takes a list of key pairs... and prints them
-// into a multiline block
+// into a multiline block, quoting values that need it
 func KeyPairsToString(kv []*ecs.KeyValuePair) string {
 	out := ""
 	for _, envVar := range kv {
-		out += fmt.Sprintf("%s=%s\n", *envVar.Name, *envVar.Value)
+		out += fmt.Sprintf("%s=%s\n", *envVar.Name, formatEnvValue(*envVar.Value))
 	}
 	return out
 }
 
 // StringToKeyPairs takes a string, and turns it back into
-// an array of key pairs
+// an array of key pairs. It understands .env syntax: comments,
+// export prefixes, quoting and multi-line quoted values.
 func StringToKeyPairs(input string) ([]*ecs.KeyValuePair, error) {
-	scanner := bufio.NewScanner(strings.NewReader(input))
-	output := make([]*ecs.KeyValuePair, 0)
-	i := 0
-	for scanner.Scan() {
-		i++
-		line := strings.TrimSpace(scanner.Text())
-		if len(line) == 0 {
-			continue
-		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("Problem parsing line %d: %s", i, line)
+	pairs, err := parseDotenv(input)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]*ecs.KeyValuePair, 0, len(pairs))
+	for _, pair := range pairs {
+		newPair := &ecs.KeyValuePair{}
+		newPair.SetName(pair.name)
+		newPair.SetValue(pair.value)
+		output = append(output, newPair)
+	}
+	return output, nil
+}
+
+// AssignmentsToKeyPairs turns NAME=value assignments, as given on the
+// command line, into key pairs. Values are taken literally, with no quoting
+// or comments, since the shell has already dealt with those.
+func AssignmentsToKeyPairs(assignments []string) ([]*ecs.KeyValuePair, error) {
+	output := make([]*ecs.KeyValuePair, 0, len(assignments))
+	for _, assignment := range assignments {
+		parts := strings.SplitN(assignment, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("expected NAME=value, got %q", assignment)
 		}
 		newPair := &ecs.KeyValuePair{}
 		newPair.SetName(parts[0])
@@ -593,19 +596,23 @@ func StringToKeyPairs(input string) ([]*ecs.KeyValuePair, error) {
 }
 
 // BuildConsoleURLForService builds the console url for a service
-func BuildConsoleURLForService(cluster, service string) string {
-	region := os.Getenv("AWS_REGION")
-	return fmt.Sprintf("https://%s.console.aws.amazon.com/ecs/home?region=%s#/clusters/%s/services/%s", region, region, cluster, service)
+func (c *Client) BuildConsoleURLForService(cluster, service string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/ecs/home?region=%s#/clusters/%s/services/%s", c.Region, c.Region, cluster, service)
+}
+
+// BuildConsoleURLForTask builds the console url for a task
+func (c *Client) BuildConsoleURLForTask(cluster, taskID string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/ecs/home?region=%s#/clusters/%s/tasks/%s/details", c.Region, c.Region, cluster, taskID)
 }
 
 // GetAllTasksByDefinition gets the tasks that have run recently for a
 // cluster and service
-func GetAllTasksByDefinition(cluster string, def *ecs.TaskDefinition, status string) ([]*ecs.Task, error) {
-	runningTasks, err := GetAllTasksByDefinitionStatus(cluster, def, aws.String("RUNNING"))
+func (c *Client) GetAllTasksByDefinition(cluster string, def *ecs.TaskDefinition, status string) ([]*ecs.Task, error) {
+	runningTasks, err := c.GetAllTasksByDefinitionStatus(cluster, def, aws.String("RUNNING"))
 	if err != nil {
 		return nil, err
 	}
-	stoppedTasks, err := GetAllTasksByDefinitionStatus(cluster, def, aws.String("STOPPED"))
+	stoppedTasks, err := c.GetAllTasksByDefinitionStatus(cluster, def, aws.String("STOPPED"))
 	if err != nil {
 		return nil, err
 	}
@@ -621,8 +628,8 @@ func GetAllTasksByDefinition(cluster string, def *ecs.TaskDefinition, status str
 
 // GetAllTasksByDefinitionStatus gets all of the tasks of a certain status
 // for a task definition
-func GetAllTasksByDefinitionStatus(cluster string, def *ecs.TaskDefinition, status *string) ([]*ecs.Task, error) {
-	svc := assertECS()
+func (c *Client) GetAllTasksByDefinitionStatus(cluster string, def *ecs.TaskDefinition, status *string) ([]*ecs.Task, error) {
+	svc := c.ECS
 	params := &ecs.ListTasksInput{
 		Cluster:       aws.String(cluster),
 		Family:        def.Family,
@@ -650,74 +657,49 @@ func GetAllTasksByDefinitionStatus(cluster string, def *ecs.TaskDefinition, stat
 	return tasks.Tasks, nil
 }
 
-// GetLogs returns cloudwatch logs for a specific set of log streams
-// matching a pattern within a log group and region
-func GetLogs(cluster, service, status string) error {
-	def, err := GetCurrentTaskDefinition(cluster, service)
+// GetLogs prints the cloudwatch logs of a service's tasks, interleaved
+// by event time
+func (c *Client) GetLogs(cluster, service, status string) error {
+	events, err := c.GetServiceLogs(cluster, service, LogsQuery{Status: status})
 	if err != nil {
 		return err
 	}
-	allTasks, err := GetAllTasksByDefinition(cluster, def, status)
-	if err != nil {
-		return fmt.Errorf("Problem getting tasks by definition: %v", err)
-	}
-	logConfig := def.ContainerDefinitions[0].LogConfiguration
-	if *logConfig.LogDriver != "awslogs" {
-		return fmt.Errorf("GetLogs function requires awslogs driver, found %v", def.ContainerDefinitions[0].LogConfiguration)
-	}
-	group := logConfig.Options["awslogs-group"]
-	prefix := logConfig.Options["awslogs-stream-prefix"]
-	region := *logConfig.Options["awslogs-region"]
-	allStreams := make([]*cloudwatchlogs.GetLogEventsInput, len(allTasks))
-	for i, task := range allTasks {
-		taskID := GetTaskIDFromArn(*task.TaskArn)
-		allStreams[i] = &cloudwatchlogs.GetLogEventsInput{
-			LogGroupName:  group,
-			LogStreamName: aws.String(fmt.Sprintf("%s/%s/%s", *prefix, *task.Containers[0].Name, taskID)),
-		}
-	}
-	wg := &sync.WaitGroup{}
-	wg.Add(len(allStreams))
-	allLogsStream := make(chan string)
-	for _, params := range allStreams {
-		go func(params *cloudwatchlogs.GetLogEventsInput) {
-			defer wg.Done()
-			logs, err := GetAllLogs(region, params)
-			if err == nil {
-				for _, log := range logs {
-					allLogsStream <- log
-				}
-			}
-		}(params)
+	for _, event := range events {
+		fmt.Println(event.String())
 	}
-	allLogs := make([]string, 0)
-	go func() {
-		for curr := range allLogsStream {
-			allLogs = append(allLogs, curr)
-		}
-	}()
-	wg.Wait()
-	sort.Strings(allLogs)
-	fmt.Println(strings.Join(allLogs, "\n"))
 	return nil
 }
 
 // GetAllLogs retrieves the entire log history
-func GetAllLogs(region string, params *cloudwatchlogs.GetLogEventsInput) ([]string, error) {
-	svc := assertCloudWatch(region)
+func (c *Client) GetAllLogs(region string, params *cloudwatchlogs.GetLogEventsInput) ([]string, error) {
 	allEvents := make([]string, 0)
+	err := c.EachLogEvent(params, func(event *cloudwatchlogs.OutputLogEvent) error {
+		allEvents = append(allEvents, FormatLogEvent(event))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allEvents, nil
+}
+
+// EachLogEvent pages through the events of a log stream, handing each to
+// fn as it is read. It stops at the first error fn returns.
+func (c *Client) EachLogEvent(params *cloudwatchlogs.GetLogEventsInput, fn func(event *cloudwatchlogs.OutputLogEvent) error) error {
+	svc := c.CloudWatchLogs
+	var fnErr error
 	err := svc.GetLogEventsPages(params, func(output *cloudwatchlogs.GetLogEventsOutput, lastPage bool) bool {
 		for _, event := range output.Events {
-			val := time.Unix(*event.Timestamp/1000, 0)
-			message := fmt.Sprintf("[%v] %v", val.Format("2006-01-02 15:04:05 MST"), *event.Message)
-			allEvents = append(allEvents, message)
+			if fnErr = fn(event); fnErr != nil {
+				return false
+			}
 		}
 		return !lastPage
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return allEvents, nil
+	return fnErr
 }
 
 // GetTaskIDFromArn returns the last part of an arn
@@ -727,51 +709,45 @@ func GetTaskIDFromArn(arn string) string {
 	return parts[len(parts)-1]
 }
 
-// GetTaskLogs prints logs to stdout
-func GetTaskLogs(def *ecs.TaskDefinition, taskID string) error {
-	logConfig := def.ContainerDefinitions[0].LogConfiguration
-	if *logConfig.LogDriver != "awslogs" {
-		return fmt.Errorf("logs command requires awslogs driver, found %v", def.ContainerDefinitions[0].LogConfiguration)
+// GetTaskLogs prints the logs of every container of a task, noting the
+// containers whose logs cannot be retrieved
+func (c *Client) GetTaskLogs(def *ecs.TaskDefinition, taskID string) error {
+	containers, unavailable, err := LogContainers(def, nil)
+	if err != nil {
+		return err
 	}
-	group := logConfig.Options["awslogs-group"]
-	prefix := logConfig.Options["awslogs-stream-prefix"]
-	region := *logConfig.Options["awslogs-region"]
-	svc := assertCloudWatch(region)
-	pageNum := 0
-	params := &cloudwatchlogs.DescribeLogStreamsInput{
-		LogGroupName:        group,
-		LogStreamNamePrefix: prefix,
-	}
-	if taskID != "" {
-		params.SetLogStreamNamePrefix(fmt.Sprintf("%s/%s/%s", *prefix, *def.Family, taskID))
-	}
-	var eventErr error
-	err := svc.DescribeLogStreamsPages(params, func(page *cloudwatchlogs.DescribeLogStreamsOutput, lastPage bool) bool {
-		pageNum++
-		for _, stream := range page.LogStreams {
-			name := stream.LogStreamName
-			if strings.HasPrefix(*name, *prefix) {
-				eventErr := ListLogEvents(*group, *name, region)
-				if eventErr != nil {
-					return false
-				}
-			}
+	if len(containers) == 0 {
+		return noLogsError(def, unavailable)
+	}
+	for _, u := range unavailable {
+		fmt.Printf("=> No logs for container %s: %v\n", u.Container, u.Reason)
+	}
+	streams := make([]*LogStream, 0, len(containers))
+	for _, container := range containers {
+		stream, err := ContainerLogStream(container, taskID)
+		if err != nil {
+			return err
 		}
-		return pageNum <= 3
-	})
+		streams = append(streams, stream)
+	}
+	events, err := c.FilterLogEvents(streams, LogsQuery{})
 	if err != nil {
-		return fmt.Errorf("Problem listing log streams: %v", err)
+		return fmt.Errorf("Problem listing log events: %v", err)
 	}
-	if eventErr != nil {
-		return fmt.Errorf("Problem listing log streams: %v", eventErr)
+	for _, event := range events {
+		if len(streams) > 1 {
+			fmt.Printf("%s %s\n", event.Container, event.String())
+			continue
+		}
+		fmt.Println(event.String())
 	}
 	return nil
 }
 
 // ListLogEvents accepts a single group and stream name
 // and prints the cloudwatch logs to stdout
-func ListLogEvents(group, name, region string) error {
-	svc := assertCloudWatch(region)
+func (c *Client) ListLogEvents(group, name, region string) error {
+	svc := c.CloudWatchLogs
 	startTime := time.Now().Add(-10 * time.Minute)
 	eventParams := &cloudwatchlogs.GetLogEventsInput{
 		LogGroupName:  aws.String(group),
@@ -782,8 +758,7 @@ func ListLogEvents(group, name, region string) error {
 	eventErr := svc.GetLogEventsPages(eventParams, func(events *cloudwatchlogs.GetLogEventsOutput, lastPage bool) bool {
 		eventPage++
 		for _, event := range events.Events {
-			val := time.Unix(*event.Timestamp/1000, 0)
-			fmt.Println(fmt.Sprintf("[%v] %v", val.Format("2006-01-02 15:04:05 MST"), *event.Message))
+			fmt.Println(FormatLogEvent(event))
 		}
 		return eventPage <= 3
 	})
@@ -793,34 +768,17 @@ func ListLogEvents(group, name, region string) error {
 	return nil
 }
 
-func LocateTaskDef(cluster, service, source string) (*ecs.TaskDefinition, error) {
+func (c *Client) LocateTaskDef(cluster, service, source string) (*ecs.TaskDefinition, error) {
 	if source == "newest" {
-		return GetNewestTaskDefinition(cluster, service)
+		return c.GetNewestTaskDefinition(cluster, service)
 	}
-	return GetCurrentTaskDefinition(cluster, service)
+	return c.GetCurrentTaskDefinition(cluster, service)
 }
 
 // RunTaskWithCommand runs a one-off task with a command
 // override.
-func RunTaskWithCommand(cluster string, task *ecs.TaskDefinition, command string) (*ecs.RunTaskOutput, error) {
-	svc := assertECS()
-	commandParts, err := parseCommandOverride(command)
-	if err != nil {
-		return nil, err
-	}
-	commandPointers := mapCommandStrPointer(commandParts)
-	return svc.RunTask(&ecs.RunTaskInput{
-		Cluster:        aws.String(cluster),
-		TaskDefinition: task.TaskDefinitionArn,
-		Overrides: &ecs.TaskOverride{
-			ContainerOverrides: []*ecs.ContainerOverride{
-				{
-					Name:    task.ContainerDefinitions[0].Name,
-					Command: commandPointers,
-				},
-			},
-		},
-	})
+func (c *Client) RunTaskWithCommand(cluster string, task *ecs.TaskDefinition, command string) (*ecs.RunTaskOutput, error) {
+	return c.RunTask(cluster, task, RunTaskOptions{Command: command})
 }
 
 func mapCommandStrPointer(parts []string) []*string {
@@ -833,8 +791,8 @@ func mapCommandStrPointer(parts []string) []*string {
 
 // FindRoleByName paginates through roles and returns
 // any found that match the name string
-func FindRoleByName(name string) (*iam.Role, error) {
-	svc := assertIAM()
+func (c *Client) FindRoleByName(name string) (*iam.Role, error) {
+	svc := c.IAM
 	params := &iam.ListRolesInput{
 		MaxItems: aws.Int64(100),
 	}
@@ -868,19 +826,15 @@ type ecsCommandOverrideJSON struct {
 
 // CreateScheduledTask creates a scheduled task in an ECS cluster
 // with the specified paramters
-func CreateScheduledTask(cluster, service, taskSuffix, scheduleExpression, command string) error {
-	svc := assertCloudWatchEvents()
-	clusters, err := assertClusterMap()
+func (c *Client) CreateScheduledTask(cluster, service, taskSuffix, scheduleExpression, command string) error {
+	svc := c.CloudWatchEvents
+	clusters, err := c.clusterMap()
 	if err != nil {
 		return fmt.Errorf("unable create cluster definitions map: %v", err)
 	}
 	clusterArn := clusters[cluster]
-	ruleName := strings.Join([]string{
-		cluster,
-		service,
-		taskSuffix,
-	}, "-")
-	taskDefinition, err := GetCurrentTaskDefinition(cluster, service)
+	ruleName := *scheduledTaskRule(cluster, service, taskSuffix, scheduleExpression).Name
+	taskDefinition, err := c.GetCurrentTaskDefinition(cluster, service)
 	if err != nil {
 		return fmt.Errorf("unable to find current task definition: %v", err)
 	}
@@ -892,25 +846,28 @@ func CreateScheduledTask(cluster, service, taskSuffix, scheduleExpression, comma
 		return fmt.Errorf("unable to list event rules: %v", err)
 	}
 	if len(result.Rules) == 0 {
-		fmt.Printf("Creating Scheduled Task: %v\n", ruleName)
+		c.PrintAction("Creating Scheduled Task: %v", "Would create Scheduled Task: %v", ruleName)
 	} else {
-		fmt.Printf("Updated Scheduled Task: %v\n", ruleName)
-	}
-	_, err = svc.PutRule(
-		&cloudwatchevents.PutRuleInput{
-			Name:               aws.String(ruleName),
-			ScheduleExpression: aws.String(scheduleExpression),
-			Description: aws.String(fmt.Sprintf(
-				"Schedule Expression for %v Service in %v ECS Cluster",
-				service,
-				cluster,
-			)),
-		},
-	)
+		c.PrintAction("Updated Scheduled Task: %v", "Would update Scheduled Task: %v", ruleName)
+	}
+	_, err = svc.PutRule(scheduledTaskRule(cluster, service, taskSuffix, scheduleExpression))
 	if err != nil {
 		return fmt.Errorf("unable to create or update event rule: %v", err)
 	}
-	return createTaskTarget(clusterArn, service, taskDefinition, ruleName, command)
+	return c.createTaskTarget(clusterArn, service, taskDefinition, ruleName, command)
+}
+
+// scheduledTaskRule is the rule running a service's task on a schedule
+func scheduledTaskRule(cluster, service, taskSuffix, scheduleExpression string) *cloudwatchevents.PutRuleInput {
+	return &cloudwatchevents.PutRuleInput{
+		Name:               aws.String(strings.Join([]string{cluster, service, taskSuffix}, "-")),
+		ScheduleExpression: aws.String(scheduleExpression),
+		Description:        aws.String(scheduledTaskDescription(cluster, service)),
+	}
+}
+
+func scheduledTaskDescription(cluster, service string) string {
+	return fmt.Sprintf("Schedule Expression for %v Service in %v ECS Cluster", service, cluster)
 }
 
 // CreatePostDeploymentTaskInput parameterizes the CreatePostDeploymentTask command
@@ -924,23 +881,14 @@ type CreatePostDeploymentTaskInput struct {
 
 // CreatePostDeploymentTask listens for SERVICE_STEADY_STATE events matching the service
 // and cluster, and runs a custom command when a deployment is complete
-func CreatePostDeploymentTask(input *CreatePostDeploymentTaskInput) error {
-	svc := assertCloudWatchEvents()
-	clusters, err := assertClusterMap()
+func (c *Client) CreatePostDeploymentTask(input *CreatePostDeploymentTaskInput) error {
+	svc := c.CloudWatchEvents
+	clusters, err := c.clusterMap()
 	if err != nil {
 		return fmt.Errorf("unable create cluster definitions map: %v", err)
 	}
-	ruleName := strings.Join([]string{
-		input.SourceCluster,
-		input.SourceService,
-		"stable",
-		input.TargetCluster,
-		input.TargetService,
-	}, "-")
-	if len(ruleName) > 64 {
-		ruleName = ruleName[0:64]
-	}
-	taskDefinition, err := GetNewestTaskDefinition(input.TargetCluster, input.TargetService)
+	ruleName := postDeploymentRuleName(input)
+	taskDefinition, err := c.GetNewestTaskDefinition(input.TargetCluster, input.TargetService)
 	if err != nil {
 		return fmt.Errorf("unable to find current task definition: %v", err)
 	}
@@ -952,33 +900,57 @@ func CreatePostDeploymentTask(input *CreatePostDeploymentTaskInput) error {
 		return fmt.Errorf("unable to list event rules: %v", err)
 	}
 	if len(result.Rules) == 0 {
-		fmt.Printf("Creating Post-Deployment Task: %v\n", ruleName)
+		c.PrintAction("Creating Post-Deployment Task: %v", "Would create Post-Deployment Task: %v", ruleName)
 	} else {
-		fmt.Printf("Updating Post-Deployment Task: %v\n", ruleName)
+		c.PrintAction("Updating Post-Deployment Task: %v", "Would update Post-Deployment Task: %v", ruleName)
 	}
-	eventPattern, err := createPostDeploymentPattern(input.SourceCluster, input.SourceService)
+	rule, err := c.postDeploymentRule(input)
 	if err != nil {
-		return fmt.Errorf("unable to create event pattern: %v", err)
-	}
-	_, err = svc.PutRule(
-		&cloudwatchevents.PutRuleInput{
-			Name:         aws.String(ruleName),
-			EventPattern: aws.String(eventPattern),
-			Description: aws.String(fmt.Sprintf(
-				"Post-Deployment Expression for %v Service in %v ECS Cluster",
-				input.SourceService,
-				input.SourceCluster,
-			)),
-		},
-	)
+		return err
+	}
+	_, err = svc.PutRule(rule)
 	if err != nil {
 		return fmt.Errorf("unable to create or update event rule: %v", err)
 	}
-	return createTaskTarget(clusters[input.TargetCluster], input.TargetService, taskDefinition, ruleName, input.Command)
+	return c.createTaskTarget(clusters[input.TargetCluster], input.TargetService, taskDefinition, ruleName, input.Command)
+}
+
+// postDeploymentRuleName names the rule of a post-deployment task, within
+// the 64 characters EventBridge allows
+func postDeploymentRuleName(input *CreatePostDeploymentTaskInput) string {
+	ruleName := strings.Join([]string{
+		input.SourceCluster,
+		input.SourceService,
+		"stable",
+		input.TargetCluster,
+		input.TargetService,
+	}, "-")
+	if len(ruleName) > 64 {
+		ruleName = ruleName[0:64]
+	}
+	return ruleName
+}
+
+func postDeploymentDescription(cluster, service string) string {
+	return fmt.Sprintf("Post-Deployment Expression for %v Service in %v ECS Cluster", service, cluster)
+}
+
+// postDeploymentRule is the rule matching the source service reaching a
+// steady state
+func (c *Client) postDeploymentRule(input *CreatePostDeploymentTaskInput) (*cloudwatchevents.PutRuleInput, error) {
+	eventPattern, err := c.createPostDeploymentPattern(input.SourceCluster, input.SourceService)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create event pattern: %v", err)
+	}
+	return &cloudwatchevents.PutRuleInput{
+		Name:         aws.String(postDeploymentRuleName(input)),
+		EventPattern: aws.String(eventPattern),
+		Description:  aws.String(postDeploymentDescription(input.SourceCluster, input.SourceService)),
+	}, nil
 }
 
-func createPostDeploymentPattern(cluster, service string) (string, error) {
-	return createEventPattern(cluster, service, "ECS Service Action", "SERVICE_STEADY_STATE")
+func (c *Client) createPostDeploymentPattern(cluster, service string) (string, error) {
+	return c.createEventPattern(cluster, service, "ECS Service Action", "SERVICE_STEADY_STATE")
 }
 
 type eventPattern struct {
@@ -990,8 +962,8 @@ type eventPattern struct {
 	} `json:"detail"`
 }
 
-func createEventPattern(cluster, service, detailType, eventType string) (string, error) {
-	ecsService, err := FindService(cluster, service)
+func (c *Client) createEventPattern(cluster, service, detailType, eventType string) (string, error) {
+	ecsService, err := c.FindService(cluster, service)
 	if err != nil {
 		return "", fmt.Errorf("unable to find service: %v", err)
 	}
@@ -1008,8 +980,25 @@ func createEventPattern(cluster, service, detailType, eventType string) (string,
 	return string(eventJSON), nil
 }
 
-func createTaskTarget(clusterArn string, serviceName string, taskDefinition *ecs.TaskDefinition, ruleName, command string) error {
-	svc := assertCloudWatchEvents()
+func (c *Client) createTaskTarget(clusterArn string, serviceName string, taskDefinition *ecs.TaskDefinition, ruleName, command string) error {
+	svc := c.CloudWatchEvents
+	target, err := c.taskTarget(clusterArn, taskDefinition, command)
+	if err != nil {
+		return err
+	}
+	_, err = svc.PutTargets(&cloudwatchevents.PutTargetsInput{
+		Rule:    aws.String(ruleName),
+		Targets: []*cloudwatchevents.Target{target},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create or update targets %v", err)
+	}
+	return nil
+}
+
+// taskTarget is the rule target running one copy of a task definition,
+// optionally overriding the command of its first container
+func (c *Client) taskTarget(clusterArn string, taskDefinition *ecs.TaskDefinition, command string) (*cloudwatchevents.Target, error) {
 	target := &cloudwatchevents.Target{
 		Id:      aws.String("1"),
 		Arn:     aws.String(clusterArn),
@@ -1020,16 +1009,16 @@ func createTaskTarget(clusterArn string, serviceName string, taskDefinition *ecs
 		},
 	}
 	if target.RoleArn == nil {
-		role, err := FindRoleByName("ecsEventsRole")
+		role, err := c.FindRoleByName("ecsEventsRole")
 		if err != nil {
-			return err
+			return nil, err
 		}
 		target.RoleArn = role.Arn
 	}
 	if command != "" {
 		commands, err := parseCommandOverride(command)
 		if err != nil {
-			return fmt.Errorf("command syntax invalid: %v", err)
+			return nil, fmt.Errorf("command syntax invalid: %v", err)
 		}
 		overrides := ecsCommandOverrideJSON{
 			ContainerOverrides: []containerOverride{
@@ -1042,18 +1031,11 @@ func createTaskTarget(clusterArn string, serviceName string, taskDefinition *ecs
 		inputJSON, _ := json.Marshal(overrides)
 		target.Input = aws.String(string(inputJSON))
 	}
-	_, err := svc.PutTargets(&cloudwatchevents.PutTargetsInput{
-		Rule:    aws.String(ruleName),
-		Targets: []*cloudwatchevents.Target{target},
-	})
-	if err != nil {
-		return fmt.Errorf("unable to create or update targets %v", err)
-	}
-	return nil
+	return target, nil
 }
 
-func GetTask(cluster, arn string) (*ecs.Task, error) {
-	svc := assertECS()
+func (c *Client) GetTask(cluster, arn string) (*ecs.Task, error) {
+	svc := c.ECS
 	result, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
 		Cluster: aws.String(cluster),
 		Tasks:   []*string{aws.String(arn)},
@@ -1068,8 +1050,8 @@ func GetTask(cluster, arn string) (*ecs.Task, error) {
 }
 
 // TaskExitCode  will tell you if the exit code of a task
-func TaskExitCode(task *ecs.Task) (*int64, error) {
-	svc := assertECS()
+func (c *Client) TaskExitCode(task *ecs.Task) (*int64, error) {
+	svc := c.ECS
 	input := &ecs.DescribeTasksInput{
 		Cluster: task.ClusterArn,
 		Tasks:   []*string{task.TaskArn},
@@ -1116,8 +1098,8 @@ func TaskExitCode(task *ecs.Task) (*int64, error) {
 }
 
 // CreateRefreshDeployment forces a new deployment on a service.
-func CreateRefreshDeployment(cluster, service string) error {
-	svc := assertECS()
+func (c *Client) CreateRefreshDeployment(cluster, service string) error {
+	svc := c.ECS
 	_, err := svc.UpdateService(&ecs.UpdateServiceInput{
 		Cluster:            aws.String(cluster),
 		Service:            aws.String(service),
@@ -1126,8 +1108,8 @@ func CreateRefreshDeployment(cluster, service string) error {
 	return err
 }
 
-func PrintTaskURLs(clusterName string, service *ecs.Service) ([]string, error) {
-	svc := assertECS()
+func (c *Client) PrintTaskURLs(clusterName string, service *ecs.Service) ([]string, error) {
+	svc := c.ECS
 	tasks, err := svc.ListTasks(&ecs.ListTasksInput{
 		Cluster:     service.ClusterArn,
 		ServiceName: service.ServiceName,
@@ -28,3 +28,26 @@ func TestCommandParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestCommandFormatting(t *testing.T) {
+	for _, args := range commandOverrideTests {
+		t.Run(args.commandString, func(t *testing.T) {
+			formatted, err := formatCommandOverride(args.expectedOverride)
+			if err != nil {
+				t.Fatal(err)
+			}
+			override, err := parseCommandOverride(formatted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(override) != len(args.expectedOverride) {
+				t.Fatalf("expected %v to parse back, got %v", args.expectedOverride, override)
+			}
+			for i := range override {
+				if override[i] != args.expectedOverride[i] {
+					t.Errorf("expected %v to parse back, got %v", args.expectedOverride, override)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Package picker resolves partial names against a list of candidates, and
+// lets a user choose among them interactively on a terminal.
+package picker
+
+import (
+	"sort"
+	"strings"
+)
+
+// How closely a candidate matches a query, best first
+const (
+	matchExact = iota
+	matchFold
+	matchPrefix
+	matchSubstring
+	matchSubsequence
+	noMatch
+)
+
+// rank scores a candidate against a query. Apart from exact matches, the
+// comparison ignores case.
+func rank(candidate, query string) int {
+	if candidate == query {
+		return matchExact
+	}
+	c, q := strings.ToLower(candidate), strings.ToLower(query)
+	switch {
+	case c == q:
+		return matchFold
+	case strings.HasPrefix(c, q):
+		return matchPrefix
+	case strings.Contains(c, q):
+		return matchSubstring
+	case isSubsequence(c, q):
+		return matchSubsequence
+	}
+	return noMatch
+}
+
+// isSubsequence reports whether the characters of q appear in s in order,
+// so "apigw" matches "api-gateway"
+func isSubsequence(s, q string) bool {
+	i := 0
+	for _, r := range s {
+		if i < len(q) && r == rune(q[i]) {
+			i++
+		}
+	}
+	return i == len(q)
+}
+
+// IsPrefix reports whether a candidate matches a query exactly or starts
+// with it (ignoring case), rather than only containing its characters
+func IsPrefix(candidate, query string) bool {
+	return rank(candidate, query) <= matchPrefix
+}
+
+// Filter returns the candidates matching a query, closest matches first
+// and alphabetically within equally close matches. An empty query matches
+// every candidate.
+func Filter(candidates []string, query string) []string {
+	type ranked struct {
+		name string
+		rank int
+	}
+	matches := make([]ranked, 0, len(candidates))
+	for _, c := range candidates {
+		if r := rank(c, query); r != noMatch {
+			matches = append(matches, ranked{c, r})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].name < matches[j].name
+	})
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.name
+	}
+	return out
+}
+
+// Match returns the candidates a partial name most likely refers to: an
+// exact match, otherwise every candidate it matches equally well (ignoring
+// case, as a prefix, a substring, then as a subsequence of characters). A
+// single result means the name resolved unambiguously.
+func Match(candidates []string, query string) []string {
+	best := noMatch
+	out := make([]string, 0)
+	for _, c := range candidates {
+		r := rank(c, query)
+		switch {
+		case r < best:
+			best = r
+			out = append(out[:0], c)
+		case r == best && r != noMatch:
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
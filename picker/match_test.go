@@ -0,0 +1,67 @@
+package picker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	clusters := []string{"prod-us", "prod-eu", "qa", "qa-legacy", "staging"}
+	services := []string{"api-gateway", "api-worker", "web", "Webhooks"}
+	tests := []struct {
+		candidates []string
+		query      string
+		want       []string
+	}{
+		{clusters, "qa", []string{"qa"}},
+		{clusters, "QA", []string{"qa"}},
+		{clusters, "prod", []string{"prod-eu", "prod-us"}},
+		{clusters, "prod-u", []string{"prod-us"}},
+		{clusters, "legacy", []string{"qa-legacy"}},
+		{clusters, "stg", []string{"staging"}},
+		{clusters, "prd", []string{"prod-eu", "prod-us"}},
+		{clusters, "dev", []string{}},
+		{services, "api", []string{"api-gateway", "api-worker"}},
+		{services, "gateway", []string{"api-gateway"}},
+		{services, "apigw", []string{"api-gateway"}},
+		{services, "web", []string{"web"}},
+		{services, "webh", []string{"Webhooks"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := Match(tt.candidates, tt.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPrefix(t *testing.T) {
+	tests := []struct {
+		candidate, query string
+		want             bool
+	}{
+		{"api-gateway", "api-gateway", true},
+		{"api-gateway", "API", true},
+		{"api-gateway", "gateway", false},
+		{"api-gateway", "apigw", false},
+		{"api-gateway", "web", false},
+	}
+	for _, tt := range tests {
+		if got := IsPrefix(tt.candidate, tt.query); got != tt.want {
+			t.Errorf("IsPrefix(%q, %q) = %v, want %v", tt.candidate, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFilterOrdersClosestFirst(t *testing.T) {
+	got := Filter([]string{"search-api", "api-worker", "rapid", "api", "a-p-i"}, "api")
+	want := []string{"api", "api-worker", "rapid", "search-api", "a-p-i"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := Filter([]string{"b", "a"}, ""); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("expected an empty query to list everything, got %v", got)
+	}
+}
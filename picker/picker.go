@@ -0,0 +1,172 @@
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrCancelled is returned when the user leaves the picker without choosing
+var ErrCancelled = errors.New("cancelled")
+
+// maxVisible is the number of options shown at once
+const maxVisible = 10
+
+const (
+	keyCtrlC     = 3
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyBackspace = 127
+	keyCtrlH     = 8
+	keyEscape    = 27
+)
+
+// IsTerminal reports whether a file is an interactive terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Choose lets the user pick one of the options on a terminal: typing
+// filters them, the arrow keys (or ctrl-p / ctrl-n) move the selection and
+// enter chooses. query pre-fills the filter. The picker is drawn on out, so
+// stdout stays free for the command's own output.
+func Choose(in, out *os.File, title string, options []string, query string) (string, error) {
+	if !IsTerminal(in) {
+		return "", fmt.Errorf("%s is not a terminal", in.Name())
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(int(in.Fd()), state)
+	return run(in, out, title, options, query)
+}
+
+// picker is the state of an interactive choice
+type picker struct {
+	title   string
+	options []string
+	query   string
+	matches []string
+	cursor  int
+	// drawn is the number of lines drawn by the last render
+	drawn int
+}
+
+func run(r io.Reader, w io.Writer, title string, options []string, query string) (string, error) {
+	p := &picker{title: title, options: options}
+	p.setQuery(query)
+	p.render(w)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if n == 0 && err != nil {
+			p.clear(w)
+			if err == io.EOF {
+				return "", ErrCancelled
+			}
+			return "", err
+		}
+		for i := 0; i < n; i++ {
+			b := buf[i]
+			switch {
+			case b == '\r' || b == '\n':
+				p.clear(w)
+				if len(p.matches) == 0 {
+					return "", fmt.Errorf("nothing matches %q", p.query)
+				}
+				return p.matches[p.cursor], nil
+			case b == keyCtrlC:
+				p.clear(w)
+				return "", ErrCancelled
+			case b == keyEscape:
+				// arrow keys arrive as ESC [ A / ESC [ B (or ESC O A),
+				// a lone escape cancels
+				if i+2 >= n || (buf[i+1] != '[' && buf[i+1] != 'O') {
+					p.clear(w)
+					return "", ErrCancelled
+				}
+				switch buf[i+2] {
+				case 'A':
+					p.move(-1)
+				case 'B':
+					p.move(1)
+				}
+				i += 2
+			case b == keyCtrlP:
+				p.move(-1)
+			case b == keyCtrlN:
+				p.move(1)
+			case b == keyBackspace || b == keyCtrlH:
+				if p.query != "" {
+					runes := []rune(p.query)
+					p.setQuery(string(runes[:len(runes)-1]))
+				}
+			case b == keyCtrlU:
+				p.setQuery("")
+			case b >= ' ':
+				p.setQuery(p.query + string(b))
+			}
+		}
+		p.render(w)
+	}
+}
+
+func (p *picker) setQuery(query string) {
+	p.query = query
+	p.matches = Filter(p.options, query)
+	p.cursor = 0
+}
+
+func (p *picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = (p.cursor + delta + len(p.matches)) % len(p.matches)
+}
+
+// render redraws the picker in place, over what the last render drew
+func (p *picker) render(w io.Writer) {
+	var b strings.Builder
+	p.erase(&b)
+	lines := []string{
+		fmt.Sprintf("%s (%d/%d)", p.title, len(p.matches), len(p.options)),
+		"> " + p.query,
+	}
+	// keep the cursor in a window of maxVisible options
+	start := 0
+	if p.cursor >= maxVisible {
+		start = p.cursor - maxVisible + 1
+	}
+	for i := start; i < len(p.matches) && i < start+maxVisible; i++ {
+		marker := "  "
+		if i == p.cursor {
+			marker = "> "
+		}
+		lines = append(lines, marker+p.matches[i])
+	}
+	// raw mode needs explicit carriage returns
+	b.WriteString(strings.Join(lines, "\r\n"))
+	p.drawn = len(lines)
+	io.WriteString(w, b.String())
+}
+
+// clear erases the picker, leaving the cursor where it started
+func (p *picker) clear(w io.Writer) {
+	var b strings.Builder
+	p.erase(&b)
+	p.drawn = 0
+	io.WriteString(w, b.String())
+}
+
+func (p *picker) erase(b *strings.Builder) {
+	if p.drawn > 1 {
+		fmt.Fprintf(b, "\033[%dA", p.drawn-1)
+	}
+	b.WriteString("\r\033[J")
+}
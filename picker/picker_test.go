@@ -0,0 +1,80 @@
+package picker
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// keys feeds the picker one read per key press, like a terminal does
+type keys []string
+
+func (k *keys) Read(p []byte) (int, error) {
+	if len(*k) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, (*k)[0])
+	*k = (*k)[1:]
+	return n, nil
+}
+
+func TestRun(t *testing.T) {
+	options := []string{"api-gateway", "api-worker", "web", "webhooks"}
+	tests := []struct {
+		name  string
+		query string
+		keys  keys
+		want  string
+		err   error
+	}{
+		{"enter picks the first", "", keys{"\r"}, "api-gateway", nil},
+		{"typing filters", "", keys{"w", "o", "r", "\r"}, "api-worker", nil},
+		{"arrow keys move", "", keys{"\x1b[B", "\x1b[B", "\x1b[A", "\r"}, "api-worker", nil},
+		{"up wraps around", "web", keys{"\x1b[A", "\r"}, "webhooks", nil},
+		{"ctrl-n and ctrl-p", "api", keys{"\x0e", "\x0e", "\x10", "\r"}, "api-worker", nil},
+		{"backspace widens", "webx", keys{"\x7f", "\x1b[B", "\r"}, "webhooks", nil},
+		{"ctrl-u clears", "web", keys{"\x15", "\r"}, "api-gateway", nil},
+		{"escape cancels", "", keys{"\x1b"}, "", ErrCancelled},
+		{"ctrl-c cancels", "", keys{"\x03"}, "", ErrCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			got, err := run(&tt.keys, out, "Choose", options, tt.query)
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if !strings.HasSuffix(out.String(), "\r\033[J") {
+				t.Errorf("expected the picker to be erased, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestRunNothingMatches(t *testing.T) {
+	k := keys{"\r"}
+	if _, err := run(&k, &bytes.Buffer{}, "Choose", []string{"a"}, "zzz"); err == nil {
+		t.Errorf("expected an error when nothing matches")
+	}
+}
+
+func TestRender(t *testing.T) {
+	p := &picker{title: "Please choose a service", options: []string{"api", "web", "worker"}}
+	p.setQuery("w")
+	p.move(1)
+	out := &bytes.Buffer{}
+	p.render(out)
+	want := "\r\033[JPlease choose a service (2/3)\r\n> w\r\n  web\r\n> worker"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+	out.Reset()
+	p.render(out)
+	if !strings.HasPrefix(out.String(), "\033[3A\r\033[J") {
+		t.Errorf("expected the previous render to be erased, got %q", out.String())
+	}
+}